@@ -3,41 +3,141 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/internal/infrastructure/providers"
+	"yuno_assesment/internal/infrastructure/store"
 	"yuno_assesment/internal/usecase"
+	"yuno_assesment/pkg/httpclient"
 	"yuno_assesment/pkg/logger"
+	"yuno_assesment/pkg/metrics"
 )
 
-func main() {
-	// Create mock servers for demonstration
-	serverA := createMockProviderAServer()
-	defer serverA.Close()
+// cliOptions holds the command-line flags. An empty format writes the text report
+// to the output path and JSON and CSV copies next to it.
+type cliOptions struct {
+	input      string
+	output     string
+	format     string
+	configPath string
+	mock       bool
+}
 
-	serverB := createMockProviderBServer()
-	defer serverB.Close()
+// parseFlags parses the command-line arguments, excluding the program name
+func parseFlags(args []string) (*cliOptions, error) {
+	opts := &cliOptions{}
+	flags := flag.NewFlagSet("payments", flag.ContinueOnError)
+	flags.StringVar(&opts.input, "input", "test_data/payment_requests.csv", "CSV, or JSON Lines with a .jsonl extension, file with the payment requests")
+	flags.StringVar(&opts.output, "output", "", "results file (default from the config or RESULTS_PATH)")
+	flags.StringVar(&opts.format, "format", "", "results format: text, json or csv (default all three)")
+	flags.StringVar(&opts.configPath, "config", "", "JSON configuration file (default built-in configuration)")
+	flags.BoolVar(&opts.mock, "mock", true, "send payments to built-in mock provider servers instead of the configured endpoints")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
 
-	// Initialize configuration
+	switch opts.format {
+	case "", usecase.FormatText, usecase.FormatJSON, usecase.FormatCSV:
+	default:
+		return nil, fmt.Errorf("unsupported format %q", opts.format)
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	return opts, nil
+}
+
+// loadConfig returns the configuration from path, or the defaults when path is empty,
+// with environment overrides applied
+func loadConfig(path string) (*config.Config, error) {
 	cfg := config.DefaultConfig()
+	if path != "" {
+		var err error
+		if cfg, err = config.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg.LoadEnvironment()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+func main() {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	// Map mock servers to providers
-	mockServers := map[string]*httptest.Server{
-		"ProviderA": serverA,
-		"ProviderB": serverB,
+	// Initialize configuration
+	cfg, err := loadConfig(opts.configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if opts.output != "" {
+		cfg.Global.ResultsPath = opts.output
 	}
+	logger.SetLevel(cfg.Global.Logging.Level)
+	logger.SetFormat(cfg.Global.Logging.Format)
+
+	// Exit with exitCode once every deferred cleanup below, such as writing the HAR
+	// file and closing the mock servers, has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Stop sending payments on SIGINT or SIGTERM and write the results completed so
+	// far. A second signal terminates immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	// Update provider endpoints in config
-	for providerName, server := range mockServers {
-		if providerConfig, exists := cfg.Providers[providerName]; exists {
-			providerConfig.Endpoint = server.URL
-			cfg.Providers[providerName] = providerConfig
+	if opts.mock {
+		// Create mock servers for demonstration
+		serverA := createMockProviderAServer()
+		defer serverA.Close()
+
+		serverB := createMockProviderBServer()
+		defer serverB.Close()
+
+		// Map mock servers to providers
+		mockServers := map[string]*httptest.Server{
+			"ProviderA": serverA,
+			"ProviderB": serverB,
+		}
+
+		// Update provider endpoints in config
+		for providerName, server := range mockServers {
+			if providerConfig, exists := cfg.Providers[providerName]; exists {
+				providerConfig.Endpoint = server.URL
+				cfg.Providers[providerName] = providerConfig
+			}
 		}
 	}
 
@@ -46,12 +146,57 @@ func main() {
 		Timeout: 60 * time.Second,
 	}
 
-	// Create provider factory which implements PaymentRepository
-	paymentRepo := providers.NewFactory(cfg, client)
+	// Optionally log provider traffic while debugging an integration
+	if cfg.Global.Diagnostics.LogHTTP {
+		client.Transport = httpclient.NewLoggingTransport(client.Transport, cfg.Global.Diagnostics.RedactFields...)
+	}
+
+	// Optionally record provider traffic for support escalations
+	if cfg.Global.Diagnostics.RecordHTTP {
+		recorder := httpclient.NewHARRecorder(client.Transport)
+		client.Transport = recorder
+		defer func() {
+			if err := recorder.WriteFile(cfg.Global.Diagnostics.HARPath); err != nil {
+				logger.Error("Failed to write HAR file: %v", err)
+				return
+			}
+			logger.Info("Provider traffic written to %s", cfg.Global.Diagnostics.HARPath)
+		}()
+	}
+
+	// Create provider factory which implements PaymentRepository, recording its
+	// metrics in registry
+	registry := metrics.NewRegistry()
+	paymentRepo := providers.NewFactory(cfg, client, providers.WithMetricsRegistry(registry))
+	defer func() {
+		if err := paymentRepo.Close(); err != nil {
+			logger.Error("Failed to close provider factory: %v", err)
+		}
+	}()
 	logger.Info("Initializing payment processing system")
 
+	if _, err := paymentRepo.StartMetricsServer(); err != nil {
+		logger.Error("Failed to start metrics server: %v", err)
+	}
+
 	// Create payment use case with the payment repository
-	paymentUseCase := usecase.NewPaymentUseCase(paymentRepo)
+	useCaseOpts := []usecase.Option{
+		usecase.WithMaxConcurrentBatches(cfg.Global.MaxConcurrentBatches),
+		usecase.WithDryRun(cfg.Global.DryRun),
+		usecase.WithOnThresholdExceeded(func(summary usecase.FailureSummary) {
+			logger.Error("ALERT: %d of %d payments failed in this run", summary.Failed, summary.Total)
+		}),
+	}
+
+	// Optionally post each result to a webhook, finishing pending deliveries before exit
+	if cfg.Global.Webhook.URL != "" {
+		notifier := usecase.NewWebhookNotifier(cfg.Global.Webhook, nil)
+		defer notifier.Wait()
+		useCaseOpts = append(useCaseOpts, usecase.WithWebhookNotifier(notifier))
+	}
+	// Payments sharing an idempotency key are charged at most once per run
+	useCaseOpts = append(useCaseOpts, usecase.WithTransactionStore(store.NewMemoryTransactionStore()))
+	paymentUseCase := usecase.NewPaymentUseCase(paymentRepo, cfg, useCaseOpts...)
 
 	// Process payments from CSV file
 	// for debugging purposes, replace the following line with:
@@ -64,23 +209,80 @@ func main() {
 	// exeDir := filepath.Dir(exePath)
 	// filePath := filepath.Join(, "..", "test_data", "payment_requests.csv")
 
-	results, err := paymentUseCase.ProcessPaymentRequestsFromCSV(context.Background(), "test_data/payment_requests.csv")
-	if err != nil {
-		logger.Error("Failed to process CSV file: %v", err)
-		os.Exit(1)
+	if err := processPayments(ctx, paymentUseCase, opts.input, cfg.Global.ResultsPath, opts.format); err != nil {
+		logger.Error("%v", err)
+		exitCode = 1
+		return
 	}
 
-	// Create results directory if it doesn't exist
-	err = os.MkdirAll("test_data", 0755)
+	logger.Info("Payment processing completed. Results written to %s", cfg.Global.ResultsPath)
+}
+
+// processPayments processes the payment requests in the input file and writes the
+// results to resultsPath. When ctx is cancelled part way, requests not yet sent are
+// reported as CANCELLED, the results completed so far are still written, and an error
+// wrapping ctx.Err() is returned.
+func processPayments(ctx context.Context, paymentUseCase *usecase.PaymentUseCase, input, resultsPath, format string) error {
+	results, rowErrors, err := readAndProcess(ctx, paymentUseCase, input)
 	if err != nil {
-		logger.Error("Failed to create test_data directory: %v", err)
-		os.Exit(1)
+		if results == nil {
+			return fmt.Errorf("failed to process %s: %w", input, err)
+		}
+		logger.Error("%s processed with errors: %v", input, err)
+	}
+	for _, rowErr := range rowErrors {
+		logger.Error("Skipped invalid input row: %v", rowErr)
+	}
+
+	summary := usecase.SummarizeResults(results)
+	logger.Info("Processed %d payments: %d succeeded, %d failed, %.2f approved in total",
+		summary.Total, summary.Succeeded, summary.Failed, summary.TotalAmountApproved)
+	codes := make([]string, 0, len(summary.ByErrorCode))
+	for code := range summary.ByErrorCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		logger.Info("  %s: %d", code, summary.ByErrorCode[code])
+	}
+
+	// Write the selected format to the output path, or by default the text report
+	// to the output path and the JSON and CSV forms next to it
+	if format != "" {
+		if err := usecase.WriteResultsFile(resultsPath, results, format); err != nil {
+			return fmt.Errorf("failed to write %s results: %w", format, err)
+		}
+	} else {
+		for _, format := range []string{usecase.FormatText, usecase.FormatJSON, usecase.FormatCSV} {
+			path := resultsPathFor(resultsPath, format)
+			if err := usecase.WriteResultsFile(path, results, format); err != nil {
+				if format == usecase.FormatText {
+					return fmt.Errorf("failed to write %s results: %w", format, err)
+				}
+				logger.Error("Failed to write %s results: %v", format, err)
+			}
+		}
 	}
 
-	// Write results to output file
-	makeResultOutPutFile(results)
+	if ctx.Err() != nil {
+		return fmt.Errorf("payment processing interrupted, partial results written to %s: %w", resultsPath, ctx.Err())
+	}
+	return nil
+}
+
+// readAndProcess processes the payment requests in the file at input, read as JSON
+// Lines when it has a .jsonl extension and as CSV otherwise
+func readAndProcess(ctx context.Context, paymentUseCase *usecase.PaymentUseCase, input string) ([]repository.PaymentResult, []usecase.CSVRowError, error) {
+	if !strings.EqualFold(filepath.Ext(input), ".jsonl") {
+		return paymentUseCase.ProcessPaymentRequestsFromCSV(ctx, input)
+	}
 
-	logger.Info("Payment processing completed. Results written to test_data/payment_results.txt")
+	file, err := os.Open(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open JSON Lines file: %w", err)
+	}
+	defer file.Close()
+	return paymentUseCase.ProcessPaymentRequestsFromJSONL(ctx, file)
 }
 
 // createMockProviderAServer creates a test server that simulates Provider A's API
@@ -112,13 +314,22 @@ func createMockProviderAServer() *httptest.Server {
 // createMockProviderBServer creates a test server that simulates Provider B's API
 func createMockProviderBServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var requestBody map[string]interface{}
+		var requestBody struct {
+			PaymentValue struct {
+				Amount       string `json:"amount"`
+				CurrencyCode string `json:"currencyCode"`
+			} `json:"paymentValue"`
+		}
 		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
-		amount := requestBody["amount"].(float64)
+		amount, err := strconv.ParseFloat(requestBody.PaymentValue.Amount, 64)
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
 		state := "SUCCESS"
 		if amount == 999 {
 			state = "FAILED"
@@ -129,58 +340,19 @@ func createMockProviderBServer() *httptest.Server {
 			"paymentId": fmt.Sprintf("PAY-DEMO-%d", int(amount)),
 			"state":     state,
 			"value": map[string]interface{}{
-				"amount":       fmt.Sprintf("%.2f", amount),
-				"currencyCode": requestBody["currency"],
+				"amount":       requestBody.PaymentValue.Amount,
+				"currencyCode": requestBody.PaymentValue.CurrencyCode,
 			},
 			"processedAt": time.Now().UnixMilli(),
 		})
 	}))
 }
 
-func makeResultOutPutFile(results []repository.PaymentResult) {
-	// Write results to output file
-	// for debugging purposes, replace the following line with:
-	// logger.Info("Starting batch payment processing from CSV file")
-	// exePath, err := os.Executable()
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	// exeDir := filepath.Dir(exePath)
-	// filePath := filepath.Join(, "..", "test_data", "payment_results.txt")
-	outputFile, err := os.Create("test_data/payment_results.txt")
-	if err != nil {
-		logger.Error("Failed to create output file: %v", err)
-		os.Exit(1)
-	}
-	defer outputFile.Close()
-
-	// Write results header
-	fmt.Fprintln(outputFile, "Payment Processing Results")
-	fmt.Fprintln(outputFile, "------------------------")
-	fmt.Fprintln(outputFile)
-
-	// Process and write each result
-	for i, result := range results {
-		fmt.Fprintf(outputFile, "Payment Request #%d:\n", i+1)
-
-		if result.Request.Amount != 0 {
-			fmt.Fprintf(outputFile, "  Amount: %.2f %s\n", result.Request.Amount, result.Request.Currency)
-			fmt.Fprintf(outputFile, "  Provider: %s\n", result.Request.Provider)
-
-			if result.Error != nil {
-				fmt.Fprintf(outputFile, "  Status: Failed\n")
-				fmt.Fprintf(outputFile, "  Error: %s (%s)\n", result.Error.Message, result.Error.Code)
-			} else if result.Payment != nil {
-				fmt.Fprintf(outputFile, "  Status: Success\n")
-				fmt.Fprintf(outputFile, "  Payment ID: %s\n", result.Payment.ID)
-				fmt.Fprintf(outputFile, "  Payment Status: %s\n", result.Payment.Status)
-			} else {
-				fmt.Fprintf(outputFile, "  Status: Unknown\n")
-			}
-		} else {
-			fmt.Fprintf(outputFile, "  Status: Invalid Request\n")
-		}
-		fmt.Fprintln(outputFile)
+// resultsPathFor returns the file the results in format are written to. The text
+// report goes to base and the other formats replace its extension.
+func resultsPathFor(base, format string) string {
+	if format == usecase.FormatText {
+		return base
 	}
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "." + format
 }
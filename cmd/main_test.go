@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
-	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/internal/infrastructure/providers"
 	"yuno_assesment/internal/usecase"
+	"yuno_assesment/pkg/httpclient"
 )
 
 func TestMain(t *testing.T) {
@@ -100,8 +106,10 @@ func TestCreateMockProviderBServer(t *testing.T) {
 
 	t.Run("Successful Payment", func(t *testing.T) {
 		payload := map[string]interface{}{
-			"amount":   100.00,
-			"currency": "USD",
+			"paymentValue": map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "USD",
+			},
 		}
 		jsonPayload, _ := json.Marshal(payload)
 		resp, err := http.Post(server.URL, "application/json", bytes.NewBuffer(jsonPayload))
@@ -122,8 +130,10 @@ func TestCreateMockProviderBServer(t *testing.T) {
 
 	t.Run("Failed Payment", func(t *testing.T) {
 		payload := map[string]interface{}{
-			"amount":   999.00,
-			"currency": "USD",
+			"paymentValue": map[string]interface{}{
+				"amount":       "999.00",
+				"currencyCode": "USD",
+			},
 		}
 		jsonPayload, _ := json.Marshal(payload)
 		resp, err := http.Post(server.URL, "application/json", bytes.NewBuffer(jsonPayload))
@@ -143,49 +153,25 @@ func TestCreateMockProviderBServer(t *testing.T) {
 	})
 }
 
-func TestMakeResultOutputFile(t *testing.T) {
-	// Create a temporary directory for test output
-	tempDir, err := os.MkdirTemp("", "test")
-	if err != nil {
-		t.Fatalf("Failed to create temp directory: %v", err)
-	}
-	defer os.RemoveAll(tempDir)
-
-	// Change working directory to temp directory
-	oldWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get current working directory: %v", err)
-	}
-	defer os.Chdir(oldWd)
-	os.Chdir(tempDir)
-
-	// Create test_data directory
-	err = os.Mkdir("test_data", 0755)
-	if err != nil {
-		t.Fatalf("Failed to create test_data directory: %v", err)
-	}
-
-	// Create test data
-	results := []repository.PaymentResult{
-		{
-			Request: repository.PaymentRequest{Amount: 100.00, Currency: "USD", Provider: "ProviderA"},
-			Payment: &domain.Payment{ID: "PAY-001", Status: domain.StatusApproved},
-		},
-		{
-			Request: repository.PaymentRequest{Amount: 999.00, Currency: "USD", Provider: "ProviderB"},
-			Error:   &domain.PaymentError{Code: "DECLINED", Message: "Payment declined"},
-		},
+func TestResultsPathFor(t *testing.T) {
+	tests := []struct {
+		base     string
+		format   string
+		expected string
+	}{
+		{base: "out/results.txt", format: usecase.FormatText, expected: "out/results.txt"},
+		{base: "out/results.txt", format: usecase.FormatJSON, expected: "out/results.json"},
+		{base: "out/results.txt", format: usecase.FormatCSV, expected: "out/results.csv"},
+		{base: "results", format: usecase.FormatCSV, expected: "results.csv"},
 	}
 
-	// Call the function
-	makeResultOutPutFile(results)
-
-	// Check if the file was created
-	if _, err := os.Stat("test_data/payment_results.txt"); os.IsNotExist(err) {
-		t.Error("Expected payment_results.txt to be created, but it doesn't exist")
+	for _, tt := range tests {
+		t.Run(tt.base+"/"+tt.format, func(t *testing.T) {
+			if got := resultsPathFor(tt.base, tt.format); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
 	}
-
-	// TODO: Add more detailed checks on the content of the file
 }
 
 func TestIntegration(t *testing.T) {
@@ -216,7 +202,7 @@ func TestIntegration(t *testing.T) {
 	paymentRepo := providers.NewFactory(cfg, client)
 
 	// Create payment use case
-	paymentUseCase := usecase.NewPaymentUseCase(paymentRepo)
+	paymentUseCase := usecase.NewPaymentUseCase(paymentRepo, cfg)
 
 	// Create a temporary CSV file for testing
 	tempFile, err := os.CreateTemp("", "test_payments_*.csv")
@@ -233,10 +219,13 @@ func TestIntegration(t *testing.T) {
 	tempFile.Close()
 
 	// Process payments from CSV file
-	results, err := paymentUseCase.ProcessPaymentRequestsFromCSV(context.Background(), tempFile.Name())
+	results, rowErrors, err := paymentUseCase.ProcessPaymentRequestsFromCSV(context.Background(), tempFile.Name())
 	if err != nil {
 		t.Fatalf("Failed to process CSV file: %v", err)
 	}
+	if len(rowErrors) != 0 {
+		t.Errorf("Expected no row errors, got %v", rowErrors)
+	}
 
 	// Add detailed logging
 	t.Logf("Results: %+v", results)
@@ -258,3 +247,288 @@ func TestIntegration(t *testing.T) {
 		t.Errorf("Expected second payment error code to be %s, got %s", domain.ErrCardDeclined, results[1].Error.Code)
 	}
 }
+
+// notifyingBody is a response body that closes done when the client closes it
+type notifyingBody struct {
+	io.Reader
+	done chan struct{}
+}
+
+func (b *notifyingBody) Close() error {
+	close(b.done)
+	return nil
+}
+
+func TestProcessPayments_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The 100.00 payment completes. The 200.00 payment is in flight when the run is
+	// interrupted, which happens only once the first response has been read.
+	firstDone := make(chan struct{})
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		if body["amount"].(float64) != 100 {
+			<-firstDone
+			cancel()
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-DONE",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		resp := httpclient.NewMockResponse(http.StatusOK, data)
+		resp.Body = &notifyingBody{Reader: bytes.NewReader(data), done: firstDone}
+		return resp, nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Providers["ProviderA"] = config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://provider-a.test",
+		MaxAmount: 1000.00,
+		Timeout:   time.Minute,
+	}
+	paymentUseCase := usecase.NewPaymentUseCase(providers.NewFactory(cfg, client), cfg)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "payments.csv")
+	if err := os.WriteFile(input, []byte("amount,currency,provider\n100.00,USD,ProviderA\n200.00,USD,ProviderA\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CSV file: %v", err)
+	}
+	output := filepath.Join(dir, "results.json")
+
+	err := processPayments(ctx, paymentUseCase, input, output, usecase.FormatJSON)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected an interrupted run, got %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("expected the partial results to be written: %v", err)
+	}
+	var written []struct {
+		Status  string `json:"status"`
+		Payment *struct {
+			ID string `json:"id"`
+		} `json:"payment"`
+		Error *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("results file is not valid JSON: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(written))
+	}
+	if written[0].Payment == nil || written[0].Payment.ID != "TXN-DONE" {
+		t.Errorf("expected the completed payment to be written, got %+v", written[0])
+	}
+	if written[1].Payment != nil || written[1].Error == nil {
+		t.Errorf("expected the interrupted payment to be written as failed, got %+v", written[1])
+	} else {
+		t.Logf("interrupted payment failed with %s", written[1].Error.Code)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expected    cliOptions
+		expectedErr bool
+	}{
+		{
+			name:     "defaults",
+			expected: cliOptions{input: "test_data/payment_requests.csv", mock: true},
+		},
+		{
+			name: "all flags",
+			args: []string{"-input", "in.csv", "-output", "out/results.json", "-format", "json", "-config", "cfg.json", "-mock=false"},
+			expected: cliOptions{
+				input:      "in.csv",
+				output:     "out/results.json",
+				format:     usecase.FormatJSON,
+				configPath: "cfg.json",
+				mock:       false,
+			},
+		},
+		{name: "unsupported format", args: []string{"-format", "xml"}, expectedErr: true},
+		{name: "unknown flag", args: []string{"-verbose"}, expectedErr: true},
+		{name: "positional argument", args: []string{"in.csv"}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseFlags(tt.args)
+			if tt.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got options %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *opts != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *opts)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return path
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := loadConfig("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Global.ResultsPath != "test_data/payment_results.txt" {
+			t.Errorf("expected default results path, got %s", cfg.Global.ResultsPath)
+		}
+	})
+
+	t.Run("file overrides defaults", func(t *testing.T) {
+		path := write("override.json", `{"global": {"default_currency": "EUR", "results_path": "out/results.txt"}}`)
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Global.DefaultCurrency != "EUR" || cfg.Global.ResultsPath != "out/results.txt" {
+			t.Errorf("expected file settings to apply, got currency %s and path %s", cfg.Global.DefaultCurrency, cfg.Global.ResultsPath)
+		}
+		if _, exists := cfg.Providers["ProviderA"]; !exists {
+			t.Error("expected default providers to be kept")
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		path := write("invalid.json", `{"global": {"default_currency": "JPY"}}`)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("expected a validation error")
+		}
+	})
+
+	t.Run("max request size", func(t *testing.T) {
+		tests := []struct {
+			size        string
+			expectedErr bool
+		}{
+			{size: "2048"},
+			{size: "512KB"},
+			{size: "1mb"},
+			{size: "2 GB"},
+			{size: "1TB", expectedErr: true},
+			{size: "-1MB", expectedErr: true},
+			{size: "lots", expectedErr: true},
+		}
+
+		for i, tt := range tests {
+			t.Run(tt.size, func(t *testing.T) {
+				path := write(fmt.Sprintf("max_request_size_%d.json", i), `{"global": {"max_request_size": "`+tt.size+`"}}`)
+				_, err := loadConfig(path)
+				if tt.expectedErr && err == nil {
+					t.Error("expected a validation error")
+				}
+				if !tt.expectedErr && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("unknown default provider", func(t *testing.T) {
+		path := write("default_provider.json", `{"global": {"default_provider": "ProviderC"}}`)
+		_, err := loadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), "default provider") {
+			t.Errorf("expected a default provider error, got %v", err)
+		}
+	})
+
+	t.Run("provider operations", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			operations  string
+			expectedErr bool
+		}{
+			{name: "paths and methods", operations: `{"process": {"path": "/v2/charges"}, "cancel": {"method": "delete", "path": "/v2/charges/{id}"}}`},
+			{name: "unknown method", operations: `{"refund": {"method": "FETCH"}}`, expectedErr: true},
+		}
+
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := write(fmt.Sprintf("operations_%d.json", i), `{"providers": {"ProviderA": {"name": "ProviderA", "endpoint": "http://provider-a.test", "timeout": 1000000000, "max_amount": 100, "operations": `+tt.operations+`}}}`)
+				cfg, err := loadConfig(path)
+				if tt.expectedErr {
+					if err == nil || !strings.Contains(err.Error(), "HTTP method") {
+						t.Errorf("expected an HTTP method error, got %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := cfg.Providers["ProviderA"].Operations.Process.Path; got != "/v2/charges" {
+					t.Errorf("expected process path /v2/charges, got %q", got)
+				}
+			})
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			webhook     string
+			expectedErr bool
+		}{
+			{name: "disabled", webhook: `{}`},
+			{name: "valid", webhook: `{"url": "https://hooks.example.com/payments", "timeout": 2000000000, "max_retries": 3, "secret": "s3cret"}`},
+			{name: "relative url", webhook: `{"url": "/payments"}`, expectedErr: true},
+			{name: "unsupported scheme", webhook: `{"url": "ftp://hooks.example.com"}`, expectedErr: true},
+			{name: "negative retries", webhook: `{"url": "https://hooks.example.com", "max_retries": -1}`, expectedErr: true},
+		}
+
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := write(fmt.Sprintf("webhook_%d.json", i), `{"global": {"webhook": `+tt.webhook+`}}`)
+				_, err := loadConfig(path)
+				if tt.expectedErr && err == nil {
+					t.Error("expected a validation error")
+				}
+				if !tt.expectedErr && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		path := write("malformed.json", `{"global": `)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("expected a parse error")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := loadConfig(filepath.Join(dir, "missing.json")); err == nil {
+			t.Error("expected an error for a missing file")
+		}
+	})
+}
@@ -1,47 +1,356 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"yuno_assesment/pkg/logger"
 )
 
 // PaymentProviderConfig represents the configuration for a payment provider
 type PaymentProviderConfig struct {
-	Name        string        `json:"name"`
-	Endpoint    string        `json:"endpoint"`
-	Timeout     time.Duration `json:"timeout"`
-	RetryCount  int           `json:"retry_count"`
-	MaxAmount   float64       `json:"max_amount"`
-	Description string        `json:"description"`
-	RetryPolicy RetryPolicy   `json:"retry_policy"`
-	RateLimit   RateLimit     `json:"rate_limit"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	// Sandbox marks an endpoint that does not move real money
+	Sandbox bool          `json:"sandbox"`
+	Timeout time.Duration `json:"timeout"`
+	// RetryCount is kept for backwards compatibility only; retries are governed
+	// solely by RetryPolicy
+	RetryCount int     `json:"retry_count"`
+	MaxAmount  float64 `json:"max_amount"`
+	// MinAmount rejects payments below it
+	MinAmount float64 `json:"min_amount"`
+	// SupportedCurrencies rejects payments in other currencies; when empty it falls
+	// back to Global.SupportedCurrencies
+	SupportedCurrencies []string    `json:"supported_currencies"`
+	Description         string      `json:"description"`
+	RetryPolicy         RetryPolicy `json:"retry_policy"`
+	RateLimit           RateLimit   `json:"rate_limit"`
+	// StrictResponse rejects provider responses containing unknown fields
+	StrictResponse bool `json:"strict_response"`
+	// MaintenanceWindows are the times during which payments are not sent to the provider
+	MaintenanceWindows []TimeWindow           `json:"maintenance_windows"`
+	LatencyStability   LatencyStabilityConfig `json:"latency_stability"`
+	// Mock configures the in-process "Mock" provider and is ignored by real providers
+	Mock MockConfig `json:"mock"`
+	// SettlementFields names the optional fee and net amount fields in responses
+	SettlementFields SettlementFields `json:"settlement_fields"`
+	// OmitRawData leaves Payment.ProviderRawData empty, keeping provider responses
+	// out of results written in production
+	OmitRawData bool `json:"omit_raw_data"`
+	// Auth holds the credentials sent with every request to the provider
+	Auth       AuthConfig       `json:"auth"`
+	Operations OperationsConfig `json:"operations"`
+	// MaxAmountByCurrency overrides MaxAmount for the currencies it lists; see MaxAmountFor
+	MaxAmountByCurrency map[string]float64 `json:"-"`
+	// AmountDecimals sets the decimal places of amounts in a currency, for providers
+	// sending amounts as strings
+	AmountDecimals map[string]int `json:"amount_decimals"`
+	// MaxResponseSize caps the bytes read from a provider response body; the factory
+	// sets it from Global.MaxRequestSize, and zero means no limit
+	MaxResponseSize int64 `json:"-"`
+	// AllowCurrencyMismatch accepts payments the provider reports in another currency
+	// than requested, for providers known to normalize currencies
+	AllowCurrencyMismatch bool `json:"allow_currency_mismatch"`
+}
+
+// DefaultAmountDecimals is the number of decimal places of amounts in currencies
+// without an AmountDecimals entry
+const DefaultAmountDecimals = 2
+
+// DecimalsFor returns the number of decimal places of amounts sent in currency
+func (p PaymentProviderConfig) DecimalsFor(currency string) int {
+	if decimals, ok := p.AmountDecimals[currency]; ok {
+		return decimals
+	}
+	return DefaultAmountDecimals
+}
+
+// maxAmountDefaultKey is the key of the fallback limit in a per-currency max_amount object
+const maxAmountDefaultKey = "default"
+
+// UnmarshalJSON decodes a provider configuration. max_amount is either a single limit
+// for every currency or an object of limits keyed by currency, such as
+// {"USD": 10000, "JPY": 1000000, "default": 5000}, whose "default" entry sets MaxAmount.
+// timeout is a duration such as "30s".
+func (p *PaymentProviderConfig) UnmarshalJSON(data []byte) error {
+	type plain PaymentProviderConfig
+	aux := struct {
+		*plain
+		MaxAmount json.RawMessage `json:"max_amount"`
+		Timeout   *duration       `json:"timeout"`
+	}{plain: (*plain)(p), Timeout: (*duration)(&p.Timeout)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	raw := bytes.TrimSpace(aux.MaxAmount)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+		return nil
+	case raw[0] == '{':
+		var limits map[string]float64
+		if err := json.Unmarshal(raw, &limits); err != nil {
+			return fmt.Errorf("invalid max_amount: %w", err)
+		}
+		if limit, ok := limits[maxAmountDefaultKey]; ok {
+			p.MaxAmount = limit
+			delete(limits, maxAmountDefaultKey)
+		}
+		p.MaxAmountByCurrency = limits
+	default:
+		if err := json.Unmarshal(raw, &p.MaxAmount); err != nil {
+			return fmt.Errorf("invalid max_amount: %w", err)
+		}
+	}
+	return nil
+}
+
+// MaxAmountFor returns the largest amount accepted in currency: its entry in
+// MaxAmountByCurrency, or MaxAmount when the currency has none
+func (p PaymentProviderConfig) MaxAmountFor(currency string) float64 {
+	if limit, ok := p.MaxAmountByCurrency[currency]; ok {
+		return limit
+	}
+	return p.MaxAmount
+}
+
+// Authentication schemes for provider requests
+const (
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+)
+
+// AuthConfig holds the credentials sent to a provider in the Authorization header:
+// Token for AuthBearer, Username and Password for AuthBasic. An empty Type sends no
+// credentials.
+type AuthConfig struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// OperationConfig is the HTTP request of one provider operation. Path is appended to the
+// provider endpoint, with {id} replaced by the transaction ID, and Method overrides the
+// operation's HTTP method. Empty fields fall back to the provider's defaults.
+type OperationConfig struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// OperationsConfig holds the HTTP request of each provider operation
+type OperationsConfig struct {
+	Process OperationConfig `json:"process"`
+	Status  OperationConfig `json:"status"`
+	Refund  OperationConfig `json:"refund"`
+	Cancel  OperationConfig `json:"cancel"`
+}
+
+// operationMethods are the HTTP methods a provider operation may be configured with
+var operationMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// validate checks that every operation uses a known HTTP method
+func (o OperationsConfig) validate() error {
+	names := []string{"process", "status", "refund", "cancel"}
+	for i, op := range []OperationConfig{o.Process, o.Status, o.Refund, o.Cancel} {
+		if op.Method != "" && !contains(operationMethods, strings.ToUpper(op.Method)) {
+			return fmt.Errorf("unknown HTTP method %q for the %s operation", op.Method, names[i])
+		}
+	}
+	return nil
+}
+
+// SettlementFields names the response fields holding the provider's fee and the net
+// settlement amount, as dot-separated paths such as "value.fee". Empty names fall
+// back to the provider's defaults.
+type SettlementFields struct {
+	Fee       string `json:"fee"`
+	NetAmount string `json:"net_amount"`
+}
+
+// Outcomes a MockRule can produce
+const (
+	MockApprove = "approve"
+	MockDecline = "decline"
+	MockError   = "error"
+)
+
+// MockConfig configures the in-process mock provider. The first rule whose amount
+// range matches decides the outcome; payments matching no rule are approved.
+// Every call is delayed by Latency, and a fraction ErrorRate (0-1) of payments fail
+// with a retryable PROVIDER_UNAVAILABLE error before the rules are applied.
+type MockConfig struct {
+	Rules     []MockRule    `json:"rules"`
+	Latency   time.Duration `json:"latency"`
+	ErrorRate float64       `json:"error_rate"`
+}
+
+// MockRule maps payments with MinAmount <= amount <= MaxAmount to an Outcome.
+// A zero MaxAmount leaves the range unbounded. ErrorCode is used by the
+// MockError outcome and defaults to PROVIDER_UNAVAILABLE.
+type MockRule struct {
+	MinAmount float64 `json:"min_amount"`
+	MaxAmount float64 `json:"max_amount"`
+	Outcome   string  `json:"outcome"`
+	ErrorCode string  `json:"error_code,omitempty"`
+}
+
+// Matches reports whether amount falls within the rule's range
+func (r MockRule) Matches(amount float64) bool {
+	return amount >= r.MinAmount && (r.MaxAmount == 0 || amount <= r.MaxAmount)
+}
+
+// LatencyStabilityConfig flags a provider as unstable when the standard deviation of
+// its last WindowSize response times exceeds MaxStdDev (i.e. the variance exceeds
+// MaxStdDev squared). Zero values disable the check.
+type LatencyStabilityConfig struct {
+	WindowSize int           `json:"window_size"`
+	MaxStdDev  time.Duration `json:"max_std_dev"`
 }
 
-// RetryPolicy defines retry behavior configuration
+// TimeWindow is a period of time starting at Start (inclusive) and ending at End (exclusive)
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Contains reports whether t falls inside the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// RetryPolicy defines retry behavior configuration. With JitterEnabled each backoff
+// delay is drawn uniformly between zero and the exponential delay ("full jitter"),
+// so that payments failing together do not retry in lockstep. RetryBudget caps the
+// retries made to the provider across all payments at that many per minute, refilled
+// continuously; zero leaves retries uncapped.
 type RetryPolicy struct {
 	InitialDelay    time.Duration `json:"initial_delay"`
 	MaxDelay        time.Duration `json:"max_delay"`
 	MaxAttempts     int           `json:"max_attempts"`
 	RetryableErrors []string      `json:"retryable_errors"`
 	RetryableCodes  []int         `json:"retryable_codes"`
+	JitterEnabled   bool          `json:"jitter_enabled"`
+	RetryBudget     int           `json:"retry_budget"`
+}
+
+// enabled reports whether the policy configures retries at all
+func (r RetryPolicy) enabled() bool {
+	return r.InitialDelay > 0 || r.MaxDelay > 0 || len(r.RetryableErrors) > 0 || len(r.RetryableCodes) > 0 || r.JitterEnabled
 }
 
-// RateLimit defines rate limiting configuration
+// RateLimit defines rate limiting configuration. A RequestsPerSecond of zero
+// disables rate limiting. When FailFast is set, requests exceeding the limit fail
+// immediately instead of waiting for capacity.
 type RateLimit struct {
-	RequestsPerSecond int `json:"requests_per_second"`
-	BurstSize         int `json:"burst_size"`
+	RequestsPerSecond int  `json:"requests_per_second"`
+	BurstSize         int  `json:"burst_size"`
+	FailFast          bool `json:"fail_fast"`
 }
 
-// GlobalConfig defines global application settings
+// GlobalConfig defines global application settings. MaxConcurrentBatches limits
+// how many payment batches may run at once; zero means unlimited.
+// MaxCurrenciesPerBatch rejects batches mixing more distinct currencies, which
+// usually means columns shifted while parsing; zero means unlimited.
+// DryRun validates payments without sending them to any provider.
+// MaxRequestSize, such as "1MB", caps the size of provider response bodies read.
+// ResultsPath is where the processed results are written. DefaultProvider is
+// used for CSV rows that leave the provider empty or have no provider column.
 type GlobalConfig struct {
-	DefaultCurrency     string               `json:"default_currency"`
-	SupportedCurrencies []string             `json:"supported_currencies"`
-	DefaultTimeout      time.Duration        `json:"default_timeout"`
-	MaxRequestSize      string               `json:"max_request_size"`
-	Metrics             MetricsConfig        `json:"metrics"`
-	Logging             LoggingConfig        `json:"logging"`
-	CircuitBreaker      CircuitBreakerConfig `json:"circuit_breaker"`
+	DefaultCurrency       string               `json:"default_currency"`
+	DefaultProvider       string               `json:"default_provider"`
+	SupportedCurrencies   []string             `json:"supported_currencies"`
+	DefaultTimeout        time.Duration        `json:"default_timeout"`
+	MaxRequestSize        string               `json:"max_request_size"`
+	MaxConcurrentBatches  int                  `json:"max_concurrent_batches"`
+	MaxCurrenciesPerBatch int                  `json:"max_currencies_per_batch"`
+	Metrics               MetricsConfig        `json:"metrics"`
+	Logging               LoggingConfig        `json:"logging"`
+	CircuitBreaker        CircuitBreakerConfig `json:"circuit_breaker"`
+	Simulation            SimulationConfig     `json:"simulation"`
+	Diagnostics           DiagnosticsConfig    `json:"diagnostics"`
+	FailureAlert          FailureAlertConfig   `json:"failure_alert"`
+	CSV                   CSVConfig            `json:"csv"`
+	Routing               RoutingConfig        `json:"routing"`
+	Webhook               WebhookConfig        `json:"webhook"`
+	DryRun                bool                 `json:"dry_run"`
+	ResultsPath           string               `json:"results_path"`
+}
+
+// Provider selection strategies
+const (
+	// RoutingFirstAvailable picks the first available provider in the given order
+	RoutingFirstAvailable = "first_available"
+	// RoutingLeastLoad picks the available provider with the fewest in-flight requests
+	RoutingLeastLoad = "least_load"
+	// RoutingRoundRobin rotates through the available providers
+	RoutingRoundRobin = "round_robin"
+	// RoutingLowestLatency picks the available provider with the lowest average latency
+	RoutingLowestLatency = "lowest_latency"
+)
+
+// RoutingConfig defines how a provider is chosen among equivalent candidates.
+// An empty Strategy means RoutingFirstAvailable. Routes maps a route name to the
+// providers to try, in order, when failing over.
+type RoutingConfig struct {
+	Strategy string              `json:"strategy"`
+	Routes   map[string][]string `json:"routes"`
+}
+
+// WebhookConfig defines where payment results are posted as they complete. An empty
+// URL disables notifications. A delivery failing with a network error or a 5xx/429
+// response is retried up to MaxRetries times. When Secret is set each body is signed
+// with HMAC-SHA256 in the X-Webhook-Signature header.
+type WebhookConfig struct {
+	URL        string        `json:"url"`
+	Timeout    time.Duration `json:"timeout"`
+	MaxRetries int           `json:"max_retries"`
+	Secret     string        `json:"secret"`
+}
+
+// CSVConfig defines how payment request CSV files are parsed. Amounts may use
+// GroupingSeparator between groups of three digits, as in "1,000.00"; an empty
+// separator rejects grouped amounts. LazyQuotes tolerates stray quotes in fields.
+type CSVConfig struct {
+	GroupingSeparator string `json:"grouping_separator"`
+	LazyQuotes        bool   `json:"lazy_quotes"`
+}
+
+// FailureAlertConfig defines when a processed batch has failed badly enough to alert
+// operators. The alert fires when more than MaxFailures payments fail or when the
+// failed fraction (0-1) exceeds MaxFailureRate; a zero value disables that limit.
+// With FailBatch set the batch also returns an ErrFailureThreshold error.
+type FailureAlertConfig struct {
+	MaxFailures    int     `json:"max_failures"`
+	MaxFailureRate float64 `json:"max_failure_rate"`
+	FailBatch      bool    `json:"fail_batch"`
+}
+
+// DiagnosticsConfig defines support tooling. When RecordHTTP is set, every provider
+// request/response pair is written, redacted, to a HAR-like file at HARPath. When
+// LogHTTP is set they are logged at debug level, with RedactFields redacted from
+// the bodies in addition to the built-in sensitive fields.
+type DiagnosticsConfig struct {
+	RecordHTTP   bool     `json:"record_http"`
+	HARPath      string   `json:"har_path"`
+	LogHTTP      bool     `json:"log_http"`
+	RedactFields []string `json:"redact_fields"`
+}
+
+// SimulationConfig defines fault injection used to exercise downstream handling in
+// non-production environments. Nothing is injected unless Staging is set explicitly.
+// DeclineInjectionRate is the fraction (0-1) of payments declined without calling a provider.
+type SimulationConfig struct {
+	Staging              bool    `json:"staging"`
+	DeclineInjectionRate float64 `json:"decline_injection_rate"`
 }
 
 // MetricsConfig defines metrics collection settings
@@ -134,6 +443,7 @@ func DefaultConfig() *Config {
 			"ProviderA": {
 				Name:        "ProviderA",
 				Endpoint:    endpoints.ProviderA,
+				Sandbox:     true,
 				Timeout:     30 * time.Second,
 				RetryCount:  3,
 				MaxAmount:   10000.0,
@@ -144,11 +454,14 @@ func DefaultConfig() *Config {
 			"ProviderB": {
 				Name:        "ProviderB",
 				Endpoint:    endpoints.ProviderB,
+				Sandbox:     true,
 				Timeout:     30 * time.Second,
 				RetryCount:  3,
 				MaxAmount:   10000.0,
 				Description: "Payment Provider B",
 				RetryPolicy: defaultRetryPolicy,
+				// ProviderB expects yen amounts without decimals
+				AmountDecimals: map[string]int{"JPY": 0},
 				RateLimit: RateLimit{
 					RequestsPerSecond: 50,
 					BurstSize:         5,
@@ -162,8 +475,16 @@ func DefaultConfig() *Config {
 				"EUR",
 				"GBP",
 			},
-			DefaultTimeout: 30 * time.Second,
-			MaxRequestSize: "1MB",
+			DefaultTimeout:       30 * time.Second,
+			MaxRequestSize:       "1MB",
+			MaxConcurrentBatches: 4,
+			ResultsPath:          "test_data/payment_results.txt",
+			Diagnostics: DiagnosticsConfig{
+				HARPath: "test_data/provider_traffic.har",
+			},
+			CSV: CSVConfig{
+				GroupingSeparator: ",",
+			},
 			Metrics: MetricsConfig{
 				Enabled:           true,
 				ReportingInterval: time.Minute,
@@ -216,6 +537,13 @@ func (c *Config) LoadEnvironment() {
 		}
 	}
 
+	if sandbox := os.Getenv("PROVIDER_A_SANDBOX"); sandbox != "" {
+		if provider, ok := c.Providers["ProviderA"]; ok {
+			provider.Sandbox = sandbox == "true"
+			c.Providers["ProviderA"] = provider
+		}
+	}
+
 	if endpoint := os.Getenv("PROVIDER_B_ENDPOINT"); endpoint != "" {
 		if provider, ok := c.Providers["ProviderB"]; ok {
 			provider.Endpoint = endpoint
@@ -223,23 +551,162 @@ func (c *Config) LoadEnvironment() {
 		}
 	}
 
+	if sandbox := os.Getenv("PROVIDER_B_SANDBOX"); sandbox != "" {
+		if provider, ok := c.Providers["ProviderB"]; ok {
+			provider.Sandbox = sandbox == "true"
+			c.Providers["ProviderB"] = provider
+		}
+	}
+
+	c.loadAuthEnvironment("ProviderA", "PROVIDER_A")
+	c.loadAuthEnvironment("ProviderB", "PROVIDER_B")
+
 	if timeout := os.Getenv("DEFAULT_TIMEOUT"); timeout != "" {
 		if duration, err := time.ParseDuration(timeout); err == nil {
 			c.Global.DefaultTimeout = duration
 		}
 	}
 
+	if dryRun := os.Getenv("DRY_RUN"); dryRun != "" {
+		c.Global.DryRun = dryRun == "true"
+	}
+
 	if currency := os.Getenv("DEFAULT_CURRENCY"); currency != "" {
 		c.Global.DefaultCurrency = currency
 	}
+	if provider := os.Getenv("DEFAULT_PROVIDER"); provider != "" {
+		c.Global.DefaultProvider = provider
+	}
 
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
 		c.Global.Logging.Level = level
 	}
 
+	if recordHTTP := os.Getenv("RECORD_HTTP"); recordHTTP != "" {
+		c.Global.Diagnostics.RecordHTTP = recordHTTP == "true"
+	}
+
+	if harPath := os.Getenv("HAR_PATH"); harPath != "" {
+		c.Global.Diagnostics.HARPath = harPath
+	}
+
+	if logHTTP := os.Getenv("LOG_HTTP"); logHTTP != "" {
+		c.Global.Diagnostics.LogHTTP = logHTTP == "true"
+	}
+
+	if resultsPath := os.Getenv("RESULTS_PATH"); resultsPath != "" {
+		c.Global.ResultsPath = resultsPath
+	}
+
 	if metricsEnabled := os.Getenv("METRICS_ENABLED"); metricsEnabled != "" {
 		c.Global.Metrics.Enabled = metricsEnabled == "true"
 	}
+
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		c.Global.Webhook.URL = webhookURL
+	}
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		c.Global.Webhook.Secret = secret
+	}
+
+	// Applies to every provider, so raw responses can be dropped in one place
+	if omitRawData := os.Getenv("OMIT_PROVIDER_RAW_DATA"); omitRawData != "" {
+		for name, provider := range c.Providers {
+			provider.OmitRawData = omitRawData == "true"
+			c.Providers[name] = provider
+		}
+	}
+}
+
+// loadAuthEnvironment reads a provider's credentials from <prefix>_TOKEN, or from
+// <prefix>_USERNAME and <prefix>_PASSWORD, selecting the matching auth type
+func (c *Config) loadAuthEnvironment(name, prefix string) {
+	provider, ok := c.Providers[name]
+	if !ok {
+		return
+	}
+
+	if token := os.Getenv(prefix + "_TOKEN"); token != "" {
+		provider.Auth = AuthConfig{Type: AuthBearer, Token: token}
+	}
+	if username := os.Getenv(prefix + "_USERNAME"); username != "" {
+		provider.Auth = AuthConfig{Type: AuthBasic, Username: username, Password: os.Getenv(prefix + "_PASSWORD")}
+	}
+	c.Providers[name] = provider
+}
+
+// LoadFile reads a JSON configuration file on top of the defaults. Settings missing
+// from the file keep their default values, except that a provider listed in the file
+// replaces the default configuration of the same name entirely.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	for _, provider := range cfg.Providers {
+		if err := provider.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Validate checks that the provider configuration is complete and consistent
+func (p PaymentProviderConfig) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("provider name is required")
+	}
+	if p.Endpoint == "" {
+		return fmt.Errorf("endpoint is required for provider %s", p.Name)
+	}
+	if p.MaxAmount <= 0 {
+		return fmt.Errorf("max amount %v for provider %s must be greater than 0", p.MaxAmount, p.Name)
+	}
+	if p.MinAmount < 0 || p.MinAmount > p.MaxAmount {
+		return fmt.Errorf("min amount %v for provider %s must be between 0 and the max amount", p.MinAmount, p.Name)
+	}
+	for currency, decimals := range p.AmountDecimals {
+		if decimals < 0 || decimals > 4 {
+			return fmt.Errorf("amount decimals %d in %s for provider %s must be between 0 and 4", decimals, currency, p.Name)
+		}
+	}
+	for currency, limit := range p.MaxAmountByCurrency {
+		if limit <= 0 || limit < p.MinAmount {
+			return fmt.Errorf("max amount %v in %s for provider %s must be greater than 0 and the min amount", limit, currency, p.Name)
+		}
+	}
+	if p.Timeout <= 0 {
+		return fmt.Errorf("timeout %v for provider %s must be greater than 0", p.Timeout, p.Name)
+	}
+	if p.RetryCount < 0 {
+		return fmt.Errorf("retry count %d for provider %s must not be negative", p.RetryCount, p.Name)
+	}
+	if p.RetryPolicy.enabled() && p.RetryPolicy.MaxAttempts < 1 {
+		return fmt.Errorf("retry max attempts %d for provider %s must be at least 1 when retries are configured", p.RetryPolicy.MaxAttempts, p.Name)
+	}
+	if p.RetryPolicy.RetryBudget < 0 {
+		return fmt.Errorf("retry budget %d for provider %s must not be negative", p.RetryPolicy.RetryBudget, p.Name)
+	}
+	if p.RateLimit.RequestsPerSecond < 0 {
+		return fmt.Errorf("rate limit %d for provider %s must not be negative", p.RateLimit.RequestsPerSecond, p.Name)
+	}
+	if rate := p.Mock.ErrorRate; rate < 0 || rate > 1 {
+		return fmt.Errorf("mock error rate %v for provider %s must be between 0 and 1", rate, p.Name)
+	}
+	switch p.Auth.Type {
+	case "", AuthBearer, AuthBasic:
+	default:
+		return fmt.Errorf("unknown auth type %q for provider %s", p.Auth.Type, p.Name)
+	}
+	if err := p.Operations.validate(); err != nil {
+		return fmt.Errorf("provider %s: %w", p.Name, err)
+	}
+	return nil
 }
 
 // Validate checks if the configuration is valid
@@ -248,9 +715,111 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("default currency %s is not in supported currencies", c.Global.DefaultCurrency)
 	}
 
+	if rate := c.Global.Simulation.DeclineInjectionRate; rate < 0 || rate > 1 {
+		return fmt.Errorf("decline injection rate %v must be between 0 and 1", rate)
+	}
+
+	if _, err := ParseByteSize(c.Global.MaxRequestSize); err != nil {
+		return fmt.Errorf("invalid max request size: %w", err)
+	}
+
+	if provider := c.Global.DefaultProvider; provider != "" {
+		if _, exists := c.Providers[provider]; !exists {
+			return fmt.Errorf("default provider %s is not configured", provider)
+		}
+	}
+
+	switch c.Global.Routing.Strategy {
+	case "", RoutingFirstAvailable, RoutingLeastLoad, RoutingRoundRobin, RoutingLowestLatency:
+	default:
+		return fmt.Errorf("unknown routing strategy %q", c.Global.Routing.Strategy)
+	}
+
+	for route, names := range c.Global.Routing.Routes {
+		for _, name := range names {
+			if _, exists := c.Providers[name]; !exists {
+				return fmt.Errorf("route %s references unknown provider %s", route, name)
+			}
+		}
+	}
+
+	if rate := c.Global.FailureAlert.MaxFailureRate; rate < 0 || rate > 1 {
+		return fmt.Errorf("failure alert rate %v must be between 0 and 1", rate)
+	}
+
+	if err := c.Global.Webhook.Validate(); err != nil {
+		return err
+	}
+
+	for name, provider := range c.Providers {
+		if err := provider.Validate(); err != nil {
+			return err
+		}
+		for _, currency := range provider.SupportedCurrencies {
+			if !contains(c.Global.SupportedCurrencies, currency) {
+				return fmt.Errorf("provider %s supports currency %s which is not in the global supported currencies", name, currency)
+			}
+		}
+		if provider.RetryCount != 0 && provider.RetryCount != provider.RetryPolicy.MaxAttempts {
+			logger.Warn("Provider %s sets legacy retry_count=%d which is ignored; retry_policy.max_attempts=%d is used instead",
+				name, provider.RetryCount, provider.RetryPolicy.MaxAttempts)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that a configured webhook URL is an absolute http(s) URL and that
+// the timeout and retry count are not negative
+func (w WebhookConfig) Validate() error {
+	if w.URL != "" {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook url %q must be an absolute http or https URL", w.URL)
+		}
+	}
+	if w.Timeout < 0 {
+		return fmt.Errorf("webhook timeout %v must not be negative", w.Timeout)
+	}
+	if w.MaxRetries < 0 {
+		return fmt.Errorf("webhook max retries %d must not be negative", w.MaxRetries)
+	}
 	return nil
 }
 
+// byteSizeUnits are the units accepted by ParseByteSize, longest suffix first
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size such as "1MB", "512KB" or "2048" into bytes. The units
+// B, KB, MB and GB are case-insensitive and multiples of 1024. An empty size is zero.
+func ParseByteSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * multiplier, nil
+}
+
 // helper function to check if a slice contains a string
 func contains(slice []string, str string) bool {
 	for _, s := range slice {
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a file named name in dir and returns its path
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestPaymentProviderConfig_Validate(t *testing.T) {
+	valid := PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://provider-a.test",
+		MaxAmount: 100,
+		Timeout:   time.Second,
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(p *PaymentProviderConfig)
+		expectedErr string
+	}{
+		{name: "valid", modify: func(p *PaymentProviderConfig) {}},
+		{name: "missing name", modify: func(p *PaymentProviderConfig) { p.Name = "" }, expectedErr: "name is required"},
+		{name: "missing endpoint", modify: func(p *PaymentProviderConfig) { p.Endpoint = "" }, expectedErr: "endpoint is required"},
+		{name: "zero max amount", modify: func(p *PaymentProviderConfig) { p.MaxAmount = 0 }, expectedErr: "max amount"},
+		{name: "zero timeout", modify: func(p *PaymentProviderConfig) { p.Timeout = 0 }, expectedErr: "timeout"},
+		{name: "negative retry count", modify: func(p *PaymentProviderConfig) { p.RetryCount = -1 }, expectedErr: "retry count"},
+		{
+			name:        "retries without attempts",
+			modify:      func(p *PaymentProviderConfig) { p.RetryPolicy = RetryPolicy{InitialDelay: time.Millisecond} },
+			expectedErr: "retry max attempts",
+		},
+		{name: "negative rate limit", modify: func(p *PaymentProviderConfig) { p.RateLimit.RequestsPerSecond = -1 }, expectedErr: "rate limit"},
+		{
+			name:        "non-positive currency limit",
+			modify:      func(p *PaymentProviderConfig) { p.MaxAmountByCurrency = map[string]float64{"JPY": 0} },
+			expectedErr: "max amount 0 in JPY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := valid
+			tt.modify(&provider)
+			err := provider.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFile_InvalidProvider(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "provider.json", `{"providers": {"ProviderC": {"name": "ProviderC", "endpoint": "http://provider-c.test", "max_amount": 100}}}`)
+	_, err := LoadFile(path)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected a provider timeout error, got %v", err)
+	}
+}
+
+func TestLoadFile_MaxAmount(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAmount   string
+		expectedUSD float64
+		expectedJPY float64
+		expectedErr bool
+	}{
+		{name: "single limit", maxAmount: `5000`, expectedUSD: 5000, expectedJPY: 5000},
+		{name: "per currency", maxAmount: `{"JPY": 1000000, "default": 5000}`, expectedUSD: 5000, expectedJPY: 1000000},
+		{name: "per currency without default", maxAmount: `{"JPY": 1000000}`, expectedErr: true},
+		{name: "invalid limit", maxAmount: `"5000"`, expectedErr: true},
+	}
+
+	dir := t.TempDir()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, dir, fmt.Sprintf("max_amount_%d.json", i), `{"providers": {"ProviderA": {"name": "ProviderA", "endpoint": "http://provider-a.test", "timeout": 1000000000, "max_amount": `+tt.maxAmount+`}}}`)
+			cfg, err := LoadFile(path)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			provider := cfg.Providers["ProviderA"]
+			if got := provider.MaxAmountFor("USD"); got != tt.expectedUSD {
+				t.Errorf("expected USD limit %v, got %v", tt.expectedUSD, got)
+			}
+			if got := provider.MaxAmountFor("JPY"); got != tt.expectedJPY {
+				t.Errorf("expected JPY limit %v, got %v", tt.expectedJPY, got)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Durations(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{name: "seconds", timeout: `"30s"`, expected: 30 * time.Second},
+		{name: "milliseconds", timeout: `"500ms"`, expected: 500 * time.Millisecond},
+		{name: "nanoseconds", timeout: `1000000000`, expected: time.Second},
+		{name: "invalid", timeout: `"soon"`, expectedErr: true},
+	}
+
+	dir := t.TempDir()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, dir, fmt.Sprintf("durations_%d.json", i), `{
+				"providers": {"ProviderA": {"name": "ProviderA", "endpoint": "http://provider-a.test", "timeout": `+tt.timeout+`, "max_amount": 100,
+					"retry_policy": {"max_attempts": 2, "initial_delay": `+tt.timeout+`, "max_delay": `+tt.timeout+`}}},
+				"global": {"default_timeout": `+tt.timeout+`, "circuit_breaker": {"reset_timeout": `+tt.timeout+`}}
+			}`)
+			cfg, err := LoadFile(path)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected a parse error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			provider := cfg.Providers["ProviderA"]
+			for field, got := range map[string]time.Duration{
+				"timeout":         provider.Timeout,
+				"initial_delay":   provider.RetryPolicy.InitialDelay,
+				"max_delay":       provider.RetryPolicy.MaxDelay,
+				"default_timeout": cfg.Global.DefaultTimeout,
+				"reset_timeout":   cfg.Global.CircuitBreaker.ResetTimeout,
+			} {
+				if got != tt.expected {
+					t.Errorf("%s: expected %v, got %v", field, tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_RoundTrip(t *testing.T) {
+	original := DefaultConfig()
+	providerA := original.Providers["ProviderA"]
+	providerA.MaxAmount = 50
+	providerA.MaxAmountByCurrency = map[string]float64{"USD": 100, "JPY": 5000}
+	original.Providers["ProviderA"] = providerA
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := fmt.Sprintf(`"default_timeout":%q`, original.Global.DefaultTimeout.String())
+	if !strings.Contains(string(data), expected) {
+		t.Errorf("expected durations written as strings such as %s, got %s", expected, data)
+	}
+
+	path := writeConfigFile(t, t.TempDir(), "round_trip.json", string(data))
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Global.DefaultTimeout != original.Global.DefaultTimeout ||
+		cfg.Global.CircuitBreaker.ResetTimeout != original.Global.CircuitBreaker.ResetTimeout ||
+		cfg.Global.Metrics.ReportingInterval != original.Global.Metrics.ReportingInterval {
+		t.Errorf("expected global durations to survive a round trip, got %+v", cfg.Global)
+	}
+	for name, provider := range original.Providers {
+		got := cfg.Providers[name]
+		if got.Timeout != provider.Timeout || got.RetryPolicy.InitialDelay != provider.RetryPolicy.InitialDelay ||
+			got.RetryPolicy.MaxDelay != provider.RetryPolicy.MaxDelay || got.Mock.Latency != provider.Mock.Latency {
+			t.Errorf("%s: expected durations to survive a round trip, got %+v", name, got)
+		}
+		if got.MaxAmount != provider.MaxAmount || !reflect.DeepEqual(got.MaxAmountByCurrency, provider.MaxAmountByCurrency) {
+			t.Errorf("%s: expected max amounts %v and %v to survive a round trip, got %v and %v",
+				name, provider.MaxAmount, provider.MaxAmountByCurrency, got.MaxAmount, got.MaxAmountByCurrency)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// duration is a time.Duration as written in config files: a string parsed by
+// time.ParseDuration, such as "30s" or "500ms", or a number of nanoseconds for older
+// files. It is always written back as a string.
+type duration time.Duration
+
+// MarshalJSON writes the duration as a string such as "1m30s"
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads the duration from a string or a number of nanoseconds
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float64:
+		*d = duration(v)
+		return nil
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = duration(parsed)
+		return nil
+	default:
+		return fmt.Errorf("invalid duration %s: expected a string such as \"30s\" or a number of nanoseconds", data)
+	}
+}
+
+// MarshalJSON writes the provider configuration with its timeout as a string, and
+// max_amount as an object of limits keyed by currency when MaxAmountByCurrency is set,
+// so it reads back as it was
+func (p PaymentProviderConfig) MarshalJSON() ([]byte, error) {
+	type plain PaymentProviderConfig
+	var maxAmount interface{} = p.MaxAmount
+	if len(p.MaxAmountByCurrency) > 0 {
+		limits := make(map[string]float64, len(p.MaxAmountByCurrency)+1)
+		for currency, limit := range p.MaxAmountByCurrency {
+			limits[currency] = limit
+		}
+		limits[maxAmountDefaultKey] = p.MaxAmount
+		maxAmount = limits
+	}
+	return json.Marshal(struct {
+		plain
+		MaxAmount interface{} `json:"max_amount"`
+		Timeout   duration    `json:"timeout"`
+	}{plain: plain(p), MaxAmount: maxAmount, Timeout: duration(p.Timeout)})
+}
+
+// MarshalJSON writes the mock configuration with its latency as a string
+func (m MockConfig) MarshalJSON() ([]byte, error) {
+	type plain MockConfig
+	return json.Marshal(struct {
+		plain
+		Latency duration `json:"latency"`
+	}{plain: plain(m), Latency: duration(m.Latency)})
+}
+
+// UnmarshalJSON reads the mock configuration, accepting a latency such as "200ms"
+func (m *MockConfig) UnmarshalJSON(data []byte) error {
+	type plain MockConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		Latency *duration `json:"latency"`
+	}{plain: (*plain)(m), Latency: (*duration)(&m.Latency)})
+}
+
+// MarshalJSON writes the latency stability check with its deviation as a string
+func (l LatencyStabilityConfig) MarshalJSON() ([]byte, error) {
+	type plain LatencyStabilityConfig
+	return json.Marshal(struct {
+		plain
+		MaxStdDev duration `json:"max_std_dev"`
+	}{plain: plain(l), MaxStdDev: duration(l.MaxStdDev)})
+}
+
+// UnmarshalJSON reads the latency stability check, accepting a deviation such as "50ms"
+func (l *LatencyStabilityConfig) UnmarshalJSON(data []byte) error {
+	type plain LatencyStabilityConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		MaxStdDev *duration `json:"max_std_dev"`
+	}{plain: (*plain)(l), MaxStdDev: (*duration)(&l.MaxStdDev)})
+}
+
+// MarshalJSON writes the retry policy with its delays as strings
+func (r RetryPolicy) MarshalJSON() ([]byte, error) {
+	type plain RetryPolicy
+	return json.Marshal(struct {
+		plain
+		InitialDelay duration `json:"initial_delay"`
+		MaxDelay     duration `json:"max_delay"`
+	}{plain: plain(r), InitialDelay: duration(r.InitialDelay), MaxDelay: duration(r.MaxDelay)})
+}
+
+// UnmarshalJSON reads the retry policy, accepting delays such as "100ms"
+func (r *RetryPolicy) UnmarshalJSON(data []byte) error {
+	type plain RetryPolicy
+	return json.Unmarshal(data, &struct {
+		*plain
+		InitialDelay *duration `json:"initial_delay"`
+		MaxDelay     *duration `json:"max_delay"`
+	}{plain: (*plain)(r), InitialDelay: (*duration)(&r.InitialDelay), MaxDelay: (*duration)(&r.MaxDelay)})
+}
+
+// MarshalJSON writes the global configuration with its default timeout as a string
+func (g GlobalConfig) MarshalJSON() ([]byte, error) {
+	type plain GlobalConfig
+	return json.Marshal(struct {
+		plain
+		DefaultTimeout duration `json:"default_timeout"`
+	}{plain: plain(g), DefaultTimeout: duration(g.DefaultTimeout)})
+}
+
+// UnmarshalJSON reads the global configuration, accepting a default timeout such as "30s"
+func (g *GlobalConfig) UnmarshalJSON(data []byte) error {
+	type plain GlobalConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		DefaultTimeout *duration `json:"default_timeout"`
+	}{plain: (*plain)(g), DefaultTimeout: (*duration)(&g.DefaultTimeout)})
+}
+
+// MarshalJSON writes the webhook configuration with its timeout as a string
+func (w WebhookConfig) MarshalJSON() ([]byte, error) {
+	type plain WebhookConfig
+	return json.Marshal(struct {
+		plain
+		Timeout duration `json:"timeout"`
+	}{plain: plain(w), Timeout: duration(w.Timeout)})
+}
+
+// UnmarshalJSON reads the webhook configuration, accepting a timeout such as "5s"
+func (w *WebhookConfig) UnmarshalJSON(data []byte) error {
+	type plain WebhookConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		Timeout *duration `json:"timeout"`
+	}{plain: (*plain)(w), Timeout: (*duration)(&w.Timeout)})
+}
+
+// MarshalJSON writes the metrics configuration with its reporting interval as a string
+func (m MetricsConfig) MarshalJSON() ([]byte, error) {
+	type plain MetricsConfig
+	return json.Marshal(struct {
+		plain
+		ReportingInterval duration `json:"reporting_interval"`
+	}{plain: plain(m), ReportingInterval: duration(m.ReportingInterval)})
+}
+
+// UnmarshalJSON reads the metrics configuration, accepting an interval such as "10s"
+func (m *MetricsConfig) UnmarshalJSON(data []byte) error {
+	type plain MetricsConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		ReportingInterval *duration `json:"reporting_interval"`
+	}{plain: (*plain)(m), ReportingInterval: (*duration)(&m.ReportingInterval)})
+}
+
+// MarshalJSON writes the circuit breaker configuration with its reset timeout as a string
+func (c CircuitBreakerConfig) MarshalJSON() ([]byte, error) {
+	type plain CircuitBreakerConfig
+	return json.Marshal(struct {
+		plain
+		ResetTimeout duration `json:"reset_timeout"`
+	}{plain: plain(c), ResetTimeout: duration(c.ResetTimeout)})
+}
+
+// UnmarshalJSON reads the circuit breaker configuration, accepting a reset timeout
+// such as "1m"
+func (c *CircuitBreakerConfig) UnmarshalJSON(data []byte) error {
+	type plain CircuitBreakerConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		ResetTimeout *duration `json:"reset_timeout"`
+	}{plain: (*plain)(c), ResetTimeout: (*duration)(&c.ResetTimeout)})
+}
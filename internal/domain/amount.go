@@ -0,0 +1,30 @@
+package domain
+
+import "math"
+
+// minorUnitsPerMajor is the number of minor units (cents) in one unit of every
+// supported currency
+const minorUnitsPerMajor = 100
+
+// ToMinorUnits converts an amount such as 100.10 to integer minor units (10010),
+// rounding to the nearest cent so float representation errors do not leak through
+func ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * minorUnitsPerMajor))
+}
+
+// FromMinorUnits converts integer minor units back to an amount
+func FromMinorUnits(units int64) float64 {
+	return float64(units) / minorUnitsPerMajor
+}
+
+// RoundAmount rounds an amount to whole cents, e.g. 0.1+0.2 becomes 0.3
+func RoundAmount(amount float64) float64 {
+	return FromMinorUnits(ToMinorUnits(amount))
+}
+
+// AmountsMatch reports whether two amounts differ by at most one cent once rounded
+// to whole cents
+func AmountsMatch(a, b float64) bool {
+	diff := ToMinorUnits(a) - ToMinorUnits(b)
+	return diff >= -1 && diff <= 1
+}
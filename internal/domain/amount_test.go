@@ -0,0 +1,54 @@
+package domain
+
+import "testing"
+
+func TestToMinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		expected int64
+	}{
+		{name: "0.1+0.2", amount: 0.1 + 0.2, expected: 30},
+		{name: "100.10", amount: 100.10, expected: 10010},
+		{name: "sub-cent noise", amount: 19.999999, expected: 2000},
+		{name: "whole amount", amount: 250, expected: 25000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMinorUnits(tt.amount); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	if got := RoundAmount(0.1 + 0.2); got != 0.3 {
+		t.Errorf("expected 0.3, got %v", got)
+	}
+	if got := RoundAmount(100.09999999); got != 100.10 {
+		t.Errorf("expected 100.10, got %v", got)
+	}
+}
+
+func TestAmountsMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     float64
+		expected bool
+	}{
+		{name: "float noise", a: 0.1 + 0.2, b: 0.3, expected: true},
+		{name: "one cent apart", a: 100.10, b: 100.11, expected: true},
+		{name: "two cents apart", a: 100.10, b: 100.12, expected: false},
+		{name: "different amounts", a: 100, b: 10, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AmountsMatch(tt.a, tt.b); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
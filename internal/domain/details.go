@@ -0,0 +1,31 @@
+package domain
+
+import "context"
+
+// PaymentDetails is caller-supplied information forwarded to the provider along
+// with a payment. ReferenceID is the caller's own identifier for the payment.
+type PaymentDetails struct {
+	ReferenceID string
+	Metadata    map[string]string
+}
+
+// IsEmpty reports whether there is nothing to forward
+func (d PaymentDetails) IsEmpty() bool {
+	return d.ReferenceID == "" && len(d.Metadata) == 0
+}
+
+type paymentDetailsContextKey struct{}
+
+// WithPaymentDetails returns a context carrying the details of a payment request
+func WithPaymentDetails(ctx context.Context, details PaymentDetails) context.Context {
+	if details.IsEmpty() {
+		return ctx
+	}
+	return context.WithValue(ctx, paymentDetailsContextKey{}, details)
+}
+
+// PaymentDetailsFromContext returns the payment details carried by ctx, if any
+func PaymentDetailsFromContext(ctx context.Context) PaymentDetails {
+	details, _ := ctx.Value(paymentDetailsContextKey{}).(PaymentDetails)
+	return details
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey returns a context carrying the idempotency key of a payment request
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by ctx, if any
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
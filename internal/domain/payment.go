@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -21,9 +23,46 @@ const (
 	StatusCancelled PaymentStatus = "CANCELLED"
 	// StatusRefunded represents a payment that was refunded
 	StatusRefunded PaymentStatus = "REFUNDED"
+	// StatusWouldProcess represents a payment that passed validation in dry-run mode
+	// and was not sent to the provider
+	StatusWouldProcess PaymentStatus = "WOULD_PROCESS"
 )
 
-// Currency represents a supported currency code
+// knownStatuses are the payment statuses accepted by ParsePaymentStatus
+var knownStatuses = []PaymentStatus{
+	StatusPending, StatusApproved, StatusDeclined, StatusError,
+	StatusCancelled, StatusRefunded, StatusWouldProcess,
+}
+
+// ParsePaymentStatus parses a payment status case-insensitively, ignoring surrounding
+// whitespace. Unknown statuses are rejected.
+func ParsePaymentStatus(s string) (PaymentStatus, error) {
+	status := PaymentStatus(strings.ToUpper(strings.TrimSpace(s)))
+	for _, known := range knownStatuses {
+		if status == known {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown payment status %q", s)
+}
+
+// String returns the status code, e.g. "APPROVED"
+func (s PaymentStatus) String() string {
+	return string(s)
+}
+
+// MarshalJSON writes the status as its canonical code, e.g. "APPROVED"
+func (s PaymentStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToUpper(string(s)))
+}
+
+// IsTerminal reports whether the status is final and will not change anymore
+func (s PaymentStatus) IsTerminal() bool {
+	return s != StatusPending
+}
+
+// Currency represents a currency code. The named constants are the currencies
+// supported out of the box; which currencies are accepted is configured.
 type Currency string
 
 const (
@@ -35,6 +74,43 @@ const (
 	GBP Currency = "GBP"
 )
 
+// knownCurrencies are the currencies accepted by ParseCurrency when no other set is given
+var knownCurrencies = []string{string(USD), string(EUR), string(GBP)}
+
+// ParseCurrency parses a currency code case-insensitively, ignoring surrounding
+// whitespace. Codes outside allowed, or outside the currencies declared above when
+// allowed is empty, are rejected.
+func ParseCurrency(s string, allowed []string) (Currency, error) {
+	if len(allowed) == 0 {
+		allowed = knownCurrencies
+	}
+	code := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	if !IsSupportedCurrency(code, allowed) {
+		return "", fmt.Errorf("unknown currency code %q", s)
+	}
+	return code, nil
+}
+
+// String returns the currency code, e.g. "USD"
+func (c Currency) String() string {
+	return string(c)
+}
+
+// MarshalJSON writes the currency as its canonical code, e.g. "USD"
+func (c Currency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToUpper(string(c)))
+}
+
+// IsSupportedCurrency reports whether c is one of the allowed currency codes
+func IsSupportedCurrency(c Currency, allowed []string) bool {
+	for _, code := range allowed {
+		if Currency(code) == c {
+			return true
+		}
+	}
+	return false
+}
+
 // Payment represents a payment entity in our domain
 type Payment struct {
 	ID              string        `json:"id"`
@@ -45,12 +121,17 @@ type Payment struct {
 	Timestamp       time.Time     `json:"timestamp"`
 	TransactionID   string        `json:"transaction_id,omitempty"`
 	ReferenceID     string        `json:"reference_id,omitempty"`
+	IdempotencyKey  string        `json:"idempotency_key,omitempty"`
 	ErrorCode       string        `json:"error_code,omitempty"`
 	ErrorMessage    string        `json:"error_message,omitempty"`
 	Metadata        interface{}   `json:"metadata,omitempty"`
 	RetryCount      int           `json:"retry_count,omitempty"`
 	LastRetryTime   *time.Time    `json:"last_retry_time,omitempty"`
 	ProviderRawData interface{}   `json:"provider_raw_data,omitempty"`
+	PollURL         string        `json:"poll_url,omitempty"`
+	Fee             float64       `json:"fee,omitempty"`
+	NetAmount       float64       `json:"net_amount,omitempty"`
+	DryRun          bool          `json:"dry_run,omitempty"`
 }
 
 // Validate checks if the payment data is valid
@@ -70,7 +151,8 @@ func (p *Payment) Validate() error {
 	return nil
 }
 
-// PaymentError represents a domain error
+// PaymentError represents a domain error. The underlying error that caused it, if
+// any, is available through errors.Unwrap.
 type PaymentError struct {
 	Code       string      `json:"code"`
 	Message    string      `json:"message"`
@@ -78,6 +160,8 @@ type PaymentError struct {
 	Details    interface{} `json:"details,omitempty"`
 	Retryable  bool        `json:"retryable"`
 	HTTPStatus int         `json:"http_status,omitempty"`
+
+	err error
 }
 
 // Error implements the error interface for PaymentError
@@ -88,13 +172,51 @@ func (e *PaymentError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// WithCause records err as the underlying cause of e and returns e
+func (e *PaymentError) WithCause(err error) *PaymentError {
+	e.err = err
+	return e
+}
+
+// Unwrap returns the underlying cause of the error, if any
+func (e *PaymentError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is a PaymentError with the same code, so that
+// errors.Is(err, ErrCardDeclinedSentinel) matches any declined payment
+func (e *PaymentError) Is(target error) bool {
+	t, ok := target.(*PaymentError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Sentinel errors for use with errors.Is. They match any PaymentError with the same code.
+var (
+	ErrInsufficientFundsSentinel    = &PaymentError{Code: ErrInsufficientFunds}
+	ErrCardDeclinedSentinel         = &PaymentError{Code: ErrCardDeclined}
+	ErrCardExpiredSentinel          = &PaymentError{Code: ErrCardExpired}
+	ErrInvalidAmountSentinel        = &PaymentError{Code: ErrInvalidAmount}
+	ErrInvalidCurrencySentinel      = &PaymentError{Code: ErrInvalidCurrency}
+	ErrProviderNotFoundSentinel     = &PaymentError{Code: ErrProviderNotFound}
+	ErrProviderUnavailableSentinel  = &PaymentError{Code: ErrProviderUnavailable}
+	ErrProviderTimeoutSentinel      = &PaymentError{Code: ErrProviderTimeout}
+	ErrAuthenticationFailedSentinel = &PaymentError{Code: ErrAuthenticationFailed}
+	ErrNetworkErrorSentinel         = &PaymentError{Code: ErrNetworkError}
+	ErrCancelledSentinel            = &PaymentError{Code: ErrCancelled}
+	ErrRateLimitExceededSentinel    = &PaymentError{Code: ErrRateLimitExceeded}
+	ErrDuplicateTransactionSentinel = &PaymentError{Code: ErrDuplicateTransaction}
+	ErrTransactionNotFoundSentinel  = &PaymentError{Code: ErrTransactionNotFound}
+)
+
 // Common error codes
 const (
 	// Payment validation errors
 	ErrInsufficientFunds = "INSUFFICIENT_FUNDS"
 	ErrCardDeclined      = "CARD_DECLINED"
+	ErrCardExpired       = "CARD_EXPIRED"
 	ErrInvalidAmount     = "INVALID_AMOUNT"
 	ErrInvalidCurrency   = "INVALID_CURRENCY"
+	ErrInvalidRequest    = "INVALID_REQUEST"
 
 	// Provider errors
 	ErrProviderNotFound     = "PROVIDER_NOT_FOUND"
@@ -102,11 +224,15 @@ const (
 	ErrProviderTimeout      = "PROVIDER_TIMEOUT"
 	ErrProviderInvalidResp  = "PROVIDER_INVALID_RESPONSE"
 	ErrInvalidConfiguration = "INVALID_CONFIGURATION"
+	ErrAuthenticationFailed = "AUTHENTICATION_FAILED"
 
 	// System errors
 	ErrInvalidTimestamp = "INVALID_TIMESTAMP"
 	ErrNetworkError     = "NETWORK_ERROR"
 	ErrInternalError    = "INTERNAL_ERROR"
+	ErrCancelled        = "CANCELLED"
+	ErrServiceBusy      = "SERVICE_BUSY"
+	ErrFailureThreshold = "FAILURE_THRESHOLD_EXCEEDED"
 
 	// Rate limiting errors
 	ErrRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPaymentError_Is(t *testing.T) {
+	declined := &PaymentError{Code: ErrCardDeclined, Message: "Payment was declined", Provider: "ProviderA"}
+
+	tests := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{name: "matching sentinel", err: declined, target: ErrCardDeclinedSentinel, expected: true},
+		{name: "different code", err: declined, target: ErrInsufficientFundsSentinel, expected: false},
+		{name: "wrapped by fmt", err: fmt.Errorf("batch row 3: %w", declined), target: ErrCardDeclinedSentinel, expected: true},
+		{name: "empty code never matches", err: &PaymentError{}, target: &PaymentError{}, expected: false},
+		{name: "other error type", err: declined, target: context.Canceled, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPaymentError_Unwrap(t *testing.T) {
+	cause := fmt.Errorf("dial tcp: %w", context.DeadlineExceeded)
+	err := (&PaymentError{Code: ErrProviderTimeout, Message: "Failed to send request"}).WithCause(cause)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected the cause to be reachable through errors.Is")
+	}
+	if !errors.Is(err, ErrProviderTimeoutSentinel) {
+		t.Error("expected the error to match its sentinel")
+	}
+	if errors.Unwrap(err) != cause {
+		t.Errorf("expected Unwrap to return the cause, got %v", errors.Unwrap(err))
+	}
+
+	var perr *PaymentError
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &perr) || perr.Code != ErrProviderTimeout {
+		t.Errorf("expected errors.As to find the payment error, got %v", perr)
+	}
+
+	if (&PaymentError{Code: ErrInvalidAmount}).Unwrap() != nil {
+		t.Error("expected no cause for an error created without one")
+	}
+}
+
+func TestIsSupportedCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency Currency
+		allowed  []string
+		expected bool
+	}{
+		{name: "named constant", currency: USD, allowed: []string{"USD", "EUR"}, expected: true},
+		{name: "configured currency", currency: Currency("JPY"), allowed: []string{"USD", "JPY"}, expected: true},
+		{name: "not allowed", currency: GBP, allowed: []string{"USD", "EUR"}, expected: false},
+		{name: "empty list", currency: USD, expected: false},
+		{name: "case sensitive", currency: Currency("usd"), allowed: []string{"USD"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSupportedCurrency(tt.currency, tt.allowed); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParsePaymentStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    PaymentStatus
+		expectedErr bool
+	}{
+		{name: "canonical", input: "APPROVED", expected: StatusApproved},
+		{name: "lowercase", input: "declined", expected: StatusDeclined},
+		{name: "mixed case with spaces", input: " Refunded ", expected: StatusRefunded},
+		{name: "unknown", input: "SETTLED", expectedErr: true},
+		{name: "empty", input: "", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePaymentStatus(tt.input)
+			if tt.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		allowed     []string
+		expected    Currency
+		expectedErr bool
+	}{
+		{name: "canonical", input: "USD", expected: USD},
+		{name: "lowercase", input: "eur", expected: EUR},
+		{name: "configured currency", input: " jpy ", allowed: []string{"USD", "JPY"}, expected: Currency("JPY")},
+		{name: "unknown code", input: "XYZ", expectedErr: true},
+		{name: "not configured", input: "GBP", allowed: []string{"USD", "JPY"}, expectedErr: true},
+		{name: "too long", input: "USDT", expectedErr: true},
+		{name: "not letters", input: "U$D", expectedErr: true},
+		{name: "empty", input: "", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrency(tt.input, tt.allowed)
+			if tt.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPayment_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Payment{Status: PaymentStatus("approved"), Currency: Currency("usd")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded["status"] != "APPROVED" || decoded["currency"] != "USD" {
+		t.Errorf("expected canonical status and currency codes, got %s", data)
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+type pollURLContextKey struct{}
+
+// WithPollURL returns a context carrying the poll URL of a payment accepted for
+// asynchronous processing, so its provider queries that URL for the status
+func WithPollURL(ctx context.Context, pollURL string) context.Context {
+	if pollURL == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, pollURLContextKey{}, pollURL)
+}
+
+// PollURLFromContext returns the poll URL carried by ctx, if any
+func PollURLFromContext(ctx context.Context) string {
+	pollURL, _ := ctx.Value(pollURLContextKey{}).(string)
+	return pollURL
+}
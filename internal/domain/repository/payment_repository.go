@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 
 	"yuno_assesment/internal/domain"
 )
@@ -10,22 +13,61 @@ import (
 type PaymentProvider interface {
 	Name() string
 	ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError)
+	RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError)
+	GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError)
+	CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError)
 	GetMetadata() map[string]interface{}
 }
 
 // PaymentRepository defines the interface for payment processing
 type PaymentRepository interface {
 	ProcessPayment(ctx context.Context, provider string, amount float64, currency string) (*domain.Payment, *domain.PaymentError)
+	ProcessPaymentRequest(ctx context.Context, req PaymentRequest) (*domain.Payment, *domain.PaymentError)
 	BatchProcessPayments(ctx context.Context, requests []PaymentRequest) []PaymentResult
+	RefundPayment(ctx context.Context, provider string, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError)
+	BatchProcessRefunds(ctx context.Context, requests []RefundRequest) []RefundResult
+	GetPaymentStatus(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError)
+	CancelPayment(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError)
 	GetProviderMetadata(providerName string) map[string]interface{}
 	ListProviders() []string
+	ListProvidersDetailed() []ProviderInfo
+	IsProviderAvailable(name string) bool
+	CanProcess(req PaymentRequest) *domain.PaymentError
 }
 
-// PaymentRequest represents a single payment request for batch processing
+// PaymentRequest represents a single payment request for batch processing.
+// Requests sharing a non-empty IdempotencyKey are charged at most once.
+// ReferenceID is the caller's own identifier for the payment and Line the
+// line of the input file the request was read from, if any. ReferenceID and
+// Metadata are forwarded to the provider with the payment. RequestID tags the
+// log lines written while processing the request.
 type PaymentRequest struct {
-	Amount   float64
-	Currency string
-	Provider string
+	Amount         float64
+	Currency       string
+	Provider       string
+	IdempotencyKey string
+	ReferenceID    string
+	Metadata       map[string]string
+	Line           int
+	RequestID      string
+}
+
+// Details returns the information forwarded to the provider with the payment
+func (r PaymentRequest) Details() domain.PaymentDetails {
+	return domain.PaymentDetails{ReferenceID: r.ReferenceID, Metadata: r.Metadata}
+}
+
+// IdempotencyKeyOrDerive returns IdempotencyKey if set. Otherwise it derives a key
+// from the amount, currency, provider, reference and line, so the same logical
+// request always yields the same key across runs.
+func (r PaymentRequest) IdempotencyKeyOrDerive() string {
+	if r.IdempotencyKey != "" {
+		return r.IdempotencyKey
+	}
+	// Amounts are hashed in cents so float noise does not change the key
+	fields := fmt.Sprintf("%d|%s|%s|%s|%d", domain.ToMinorUnits(r.Amount), r.Currency, r.Provider, r.ReferenceID, r.Line)
+	sum := sha256.Sum256([]byte(fields))
+	return hex.EncodeToString(sum[:])
 }
 
 // PaymentResult represents the result of a batch payment request
@@ -34,3 +76,33 @@ type PaymentResult struct {
 	Payment *domain.Payment
 	Error   *domain.PaymentError
 }
+
+// IndexedPaymentResult is a PaymentResult tagged with the position of its request in the batch
+type IndexedPaymentResult struct {
+	Index int
+	PaymentResult
+}
+
+// ProviderInfo describes a configured provider: where payments are sent, the limits
+// they must meet and whether payments may currently be sent to it
+type ProviderInfo struct {
+	Name                string   `json:"name"`
+	Endpoint            string   `json:"endpoint"`
+	MaxAmount           float64  `json:"max_amount"`
+	SupportedCurrencies []string `json:"supported_currencies"`
+	Available           bool     `json:"available"`
+}
+
+// RefundRequest represents a single refund request for batch processing
+type RefundRequest struct {
+	Provider      string
+	TransactionID string
+	Amount        float64
+}
+
+// RefundResult represents the result of a batch refund request
+type RefundResult struct {
+	Request RefundRequest
+	Refund  *domain.Payment
+	Error   *domain.PaymentError
+}
@@ -0,0 +1,53 @@
+package repository
+
+import "testing"
+
+func TestPaymentRequest_IdempotencyKeyOrDerive(t *testing.T) {
+	base := PaymentRequest{
+		Amount:      100.10,
+		Currency:    "USD",
+		Provider:    "ProviderA",
+		ReferenceID: "ORDER-1",
+		Line:        2,
+	}
+
+	t.Run("explicit key wins", func(t *testing.T) {
+		req := base
+		req.IdempotencyKey = "key-1"
+		if got := req.IdempotencyKeyOrDerive(); got != "key-1" {
+			t.Errorf("expected explicit key, got %q", got)
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		same := base
+		same.Amount = 100.1 + 1e-9 // float noise below a cent
+		if base.IdempotencyKeyOrDerive() != same.IdempotencyKeyOrDerive() {
+			t.Error("expected the same key for the same logical request")
+		}
+		if base.IdempotencyKeyOrDerive() == "" {
+			t.Error("expected a non-empty derived key")
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(*PaymentRequest)
+	}{
+		{name: "amount", modify: func(r *PaymentRequest) { r.Amount = 100.20 }},
+		{name: "currency", modify: func(r *PaymentRequest) { r.Currency = "EUR" }},
+		{name: "provider", modify: func(r *PaymentRequest) { r.Provider = "ProviderB" }},
+		{name: "reference", modify: func(r *PaymentRequest) { r.ReferenceID = "ORDER-2" }},
+		{name: "line", modify: func(r *PaymentRequest) { r.Line = 3 }},
+	}
+
+	for _, tt := range tests {
+		t.Run("changing "+tt.name+" changes the key", func(t *testing.T) {
+			changed := base
+			tt.modify(&changed)
+			if base.IdempotencyKeyOrDerive() == changed.IdempotencyKeyOrDerive() {
+				t.Errorf("expected a different key after changing %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"yuno_assesment/internal/domain"
+)
+
+// TransactionStore records completed payments by idempotency key so duplicates can be
+// detected across restarts. Save stores a payment under its IdempotencyKey, and Exists
+// returns the payment stored under a key, if any.
+type TransactionStore interface {
+	Save(ctx context.Context, payment *domain.Payment) error
+	Exists(ctx context.Context, idempotencyKey string) (bool, *domain.Payment, error)
+}
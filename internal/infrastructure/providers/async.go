@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/logger"
+)
+
+// acceptedPayment builds a pending payment from a 202 Accepted response.
+// The Location header is resolved against the request URL and stored as the
+// poll URL so the final result can be fetched once the provider settles it:
+// status queries with the poll URL carried by their context (see
+// domain.WithPollURL) request it instead of the status operation.
+func acceptedPayment(providerName string, req *http.Request, resp *http.Response, amount float64, currency string, now time.Time) (*domain.Payment, *domain.PaymentError) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		logger.Error("[%s] Accepted response without Location header", providerName)
+		return nil, &domain.PaymentError{
+			Code:       domain.ErrProviderInvalidResp,
+			Message:    "Missing Location header in accepted response",
+			Provider:   providerName,
+			Retryable:  false,
+			HTTPStatus: resp.StatusCode,
+		}
+	}
+
+	pollURL, err := req.URL.Parse(location)
+	if err != nil {
+		logger.Error("[%s] Invalid Location header %q: %v", providerName, location, err)
+		return nil, (&domain.PaymentError{
+			Code:       domain.ErrProviderInvalidResp,
+			Message:    "Invalid Location header: " + err.Error(),
+			Provider:   providerName,
+			Retryable:  false,
+			HTTPStatus: resp.StatusCode,
+			Details:    location,
+		}).WithCause(err)
+	}
+
+	logger.Info("[%s] Payment accepted for asynchronous processing, poll URL: %s", providerName, pollURL)
+	return &domain.Payment{
+		Amount:    amount,
+		Currency:  domain.Currency(currency),
+		Status:    domain.StatusPending,
+		Provider:  providerName,
+		Timestamp: now,
+		PollURL:   pollURL.String(),
+	}, nil
+}
+
+// keepPollURL carries the poll URL a status query was sent to over to a payment that
+// is still pending, so the caller can keep polling it
+func keepPollURL(ctx context.Context, payment *domain.Payment) *domain.Payment {
+	if payment.Status == domain.StatusPending && payment.PollURL == "" {
+		payment.PollURL = domain.PollURLFromContext(ctx)
+	}
+	return payment
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. The factory and its providers read the time through a
+// Clock so tests can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function returning the current time to a Clock
+type ClockFunc func() time.Time
+
+// Now returns f()
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// clockSetter is implemented by providers that read the time through a Clock. The
+// factory hands them its own clock when it creates them.
+type clockSetter interface {
+	setClock(clock Clock)
+}
+
+// realClock reads the system time
+type realClock struct{}
+
+// Now returns time.Now()
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock for tests whose time only changes when it is advanced or set
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a FakeClock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to now
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"strings"
+
+	"yuno_assesment/internal/domain"
+)
+
+// declineCodes maps the decline codes providers report to more specific payment
+// error codes. Other decline codes are reported as ErrCardDeclined.
+var declineCodes = map[string]string{
+	"insufficient_funds": domain.ErrInsufficientFunds,
+	"expired_card":       domain.ErrCardExpired,
+	"card_expired":       domain.ErrCardExpired,
+}
+
+// declineError builds the payment error for a declined payment. The provider's decline
+// code, or failing that its reason, selects the error code, and both are kept in Details
+// and appended to message so the cause of the decline reaches the user.
+func declineError(providerName, message, declineCode, reason string) *domain.PaymentError {
+	code := domain.ErrCardDeclined
+	key := strings.ToLower(strings.TrimSpace(declineCode))
+	if key == "" {
+		key = strings.ToLower(strings.TrimSpace(reason))
+	}
+	if mapped, ok := declineCodes[key]; ok {
+		code = mapped
+	}
+
+	var details []string
+	for _, detail := range []string{declineCode, reason} {
+		if detail = strings.TrimSpace(detail); detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) > 0 {
+		message += ": " + strings.Join(details, ": ")
+	}
+
+	return &domain.PaymentError{
+		Code:      code,
+		Message:   message,
+		Provider:  providerName,
+		Retryable: false,
+		Details:   strings.Join(details, ": "),
+	}
+}
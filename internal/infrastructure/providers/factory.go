@@ -2,26 +2,125 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
 	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/pkg/logger"
+	"yuno_assesment/pkg/metrics"
+)
+
+// UnavailableReason explains why a provider was marked unavailable
+type UnavailableReason string
+
+const (
+	// ReasonNone is used while the provider is available
+	ReasonNone UnavailableReason = ""
+	// ReasonConsecutiveErrors is set when too many requests failed in a row, opening
+	// the circuit breaker
+	ReasonConsecutiveErrors UnavailableReason = "consecutive_errors"
+	// ReasonManual is set when the provider was disabled explicitly
+	ReasonManual UnavailableReason = "manual"
+	// ReasonMaintenance is set while the provider is in a scheduled maintenance window
+	ReasonMaintenance UnavailableReason = "maintenance"
 )
 
 // ProviderState tracks the health and status of a provider
 type ProviderState struct {
-	IsAvailable     bool
-	LastChecked     time.Time
-	ConsecutiveErrs int
-	ErrorCount      int64
-	SuccessCount    int64
-	LastError       error
-	mutex           sync.RWMutex
+	IsAvailable       bool
+	UnavailableReason UnavailableReason
+	LastChecked       time.Time
+	ConsecutiveErrs   int
+	ErrorCount        int64 // accessed atomically
+	SuccessCount      int64 // accessed atomically
+	LastError         error
+	Unstable          bool
+	AvgLatency        time.Duration
+	latencies         *latencyWindow
+	openedAt          time.Time // when an error last kept it unavailable for consecutive errors
+	inFlight          int64     // accessed atomically
+	mutex             sync.RWMutex
+}
+
+// ProviderStateSnapshot is a consistent, point-in-time copy of a ProviderState
+type ProviderStateSnapshot struct {
+	IsAvailable       bool
+	UnavailableReason UnavailableReason
+	LastChecked       time.Time
+	ConsecutiveErrs   int
+	ErrorCount        int64
+	SuccessCount      int64
+	LastError         error
+	Unstable          bool
+	AvgLatency        time.Duration
+	InFlight          int64
+	openedAt          time.Time
+}
+
+// snapshot copies the state under its read lock
+func (s *ProviderState) snapshot() ProviderStateSnapshot {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return ProviderStateSnapshot{
+		IsAvailable:       s.IsAvailable,
+		UnavailableReason: s.UnavailableReason,
+		LastChecked:       s.LastChecked,
+		ConsecutiveErrs:   s.ConsecutiveErrs,
+		ErrorCount:        atomic.LoadInt64(&s.ErrorCount),
+		SuccessCount:      atomic.LoadInt64(&s.SuccessCount),
+		LastError:         s.LastError,
+		Unstable:          s.Unstable,
+		AvgLatency:        s.AvgLatency,
+		InFlight:          atomic.LoadInt64(&s.inFlight),
+		openedAt:          s.openedAt,
+	}
+}
+
+// TotalRequests returns the number of requests whose outcome was recorded
+func (s *ProviderState) TotalRequests() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return atomic.LoadInt64(&s.SuccessCount) + atomic.LoadInt64(&s.ErrorCount)
+}
+
+// SuccessRate returns the fraction of recorded requests that succeeded, or 0 when
+// none were recorded yet
+func (s *ProviderState) SuccessRate() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	successes := atomic.LoadInt64(&s.SuccessCount)
+	total := successes + atomic.LoadInt64(&s.ErrorCount)
+	if total == 0 {
+		return 0
+	}
+	return float64(successes) / float64(total)
+}
+
+// markUnavailable flips the provider to unavailable and records why.
+// Callers must hold the state mutex.
+func (s *ProviderState) markUnavailable(reason UnavailableReason) {
+	s.IsAvailable = false
+	s.UnavailableReason = reason
+}
+
+// markAvailable flips the provider back to available unless it was disabled manually.
+// Callers must hold the state mutex.
+func (s *ProviderState) markAvailable() {
+	if s.UnavailableReason == ReasonManual {
+		return
+	}
+	s.IsAvailable = true
+	s.UnavailableReason = ReasonNone
 }
 
 // Factory is responsible for creating and managing payment providers
@@ -30,57 +129,252 @@ type Factory struct {
 	httpClient     *http.Client
 	providers      map[string]repository.PaymentProvider
 	providerStates map[string]*ProviderState
+	limiters       map[string]*tokenBucket
+	retryBudgets   map[string]*tokenBucket
+	metrics        *paymentMetrics
+	tracer         trace.Tracer
+	middleware     []Middleware
 	mutex          sync.RWMutex
+	roundRobin     uint64 // accessed atomically
+
+	random      *rand.Rand
+	randomMutex sync.Mutex
+	clock       Clock
+
+	idempotency      map[string]*idempotentResult
+	idempotencyMutex sync.Mutex
+
+	// The metrics server started by StartMetricsServer and closed when it stops
+	// serving, guarded by mutex
+	metricsServer *http.Server
+	metricsDone   chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
-// BatchProcessPayments processes multiple payment requests in parallel
+// BatchProcessPayments processes multiple payment requests in parallel. Once ctx is
+// done, requests not yet picked up are marked CANCELLED without calling a provider.
+// Requests picked up when the time left before ctx's deadline is shorter than their
+// provider's timeout are marked PROVIDER_TIMEOUT without calling the provider either.
 func (f *Factory) BatchProcessPayments(ctx context.Context, requests []repository.PaymentRequest) []repository.PaymentResult {
+	ctx, span := f.tracer.Start(ctx, "Factory.BatchProcessPayments", trace.WithAttributes(attrBatchSize.Int(len(requests))))
+	defer span.End()
+
 	results := make([]repository.PaymentResult, len(requests))
+	forEachConcurrently(len(requests), func(idx int) {
+		req := requests[idx]
+		if ctx.Err() != nil {
+			results[idx] = repository.PaymentResult{
+				Request: req,
+				Error:   cancelledError(req.Provider, ctx.Err()),
+			}
+			return
+		}
+		if err := f.deadlineError(ctx, req.Provider); err != nil {
+			results[idx] = repository.PaymentResult{
+				Request: req,
+				Error:   err,
+			}
+			return
+		}
+		payment, err := f.ProcessPaymentRequest(ctx, req)
+		results[idx] = repository.PaymentResult{
+			Request: req,
+			Payment: payment,
+			Error:   err,
+		}
+	})
+	return results
+}
+
+// ErrNilRequests is returned by ProcessPayments when it is given no request slice at all
+var ErrNilRequests = errors.New("payment requests are nil")
+
+// ProcessPayments processes a batch of payments like BatchProcessPayments, and also
+// reports failures of the batch as a whole, which callers should abort on. The error is
+// ErrNilRequests for nil requests, wraps ctx's error without results when ctx was done
+// before the batch started, and wraps it alongside the results when ctx ended during
+// the batch and requests were left CANCELLED. Failed payments are only reported in
+// their results and do not make the error non-nil.
+func (f *Factory) ProcessPayments(ctx context.Context, requests []repository.PaymentRequest) ([]repository.PaymentResult, error) {
+	if requests == nil {
+		return nil, ErrNilRequests
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("batch not started: %w", err)
+	}
+
+	results := f.BatchProcessPayments(ctx, requests)
+	if err := ctx.Err(); err != nil {
+		for _, result := range results {
+			if result.Error != nil && result.Error.Code == domain.ErrCancelled {
+				return results, fmt.Errorf("batch interrupted: %w", err)
+			}
+		}
+	}
+	return results, nil
+}
+
+// deadlineError returns a PROVIDER_TIMEOUT error when the time left before ctx's
+// deadline is shorter than the provider's timeout, since a call started now would most
+// likely time out. It returns nil without a deadline or a configured timeout.
+func (f *Factory) deadlineError(ctx context.Context, providerName string) *domain.PaymentError {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	timeout := f.config.Providers[providerName].Timeout
+	// The deadline is on the real clock, whatever clock the factory uses
+	remaining := time.Until(deadline)
+	if timeout <= 0 || remaining >= timeout {
+		return nil
+	}
+
+	logger.WithContext(ctx).Info("Skipping payment with provider %s: %v left before the deadline, timeout is %v", providerName, remaining, timeout)
+	return &domain.PaymentError{
+		Code:      domain.ErrProviderTimeout,
+		Message:   fmt.Sprintf("Not enough time left before the deadline (%v) for the provider timeout (%v)", remaining.Round(time.Millisecond), timeout),
+		Provider:  providerName,
+		Retryable: true,
+	}
+}
+
+// BatchProcessRefunds processes multiple refund requests in parallel. Once ctx is
+// done, requests not yet picked up are marked CANCELLED without calling a provider.
+func (f *Factory) BatchProcessRefunds(ctx context.Context, requests []repository.RefundRequest) []repository.RefundResult {
+	results := make([]repository.RefundResult, len(requests))
+	forEachConcurrently(len(requests), func(idx int) {
+		req := requests[idx]
+		if ctx.Err() != nil {
+			results[idx] = repository.RefundResult{
+				Request: req,
+				Error:   cancelledError("", ctx.Err()),
+			}
+			return
+		}
+		refund, err := f.RefundPayment(ctx, req.Provider, req.TransactionID, req.Amount)
+		results[idx] = repository.RefundResult{
+			Request: req,
+			Refund:  refund,
+			Error:   err,
+		}
+	})
+	return results
+}
+
+// batchWorkers is the number of requests of a batch processed concurrently
+const batchWorkers = 5
+
+// forEachConcurrently calls fn for every index in [0, n) using a fixed pool of workers.
+// Each index is handled exactly once, so callers can write results by index.
+func forEachConcurrently(n int, fn func(idx int)) {
 	var wg sync.WaitGroup
 
-	// Process payments in parallel with a worker pool
-	workerCount := 5 // Number of concurrent workers
-	requestCh := make(chan int, len(requests))
+	// Process items in parallel with a worker pool
+	indexCh := make(chan int, n)
 
 	// Start workers
-	for i := 0; i < workerCount; i++ {
+	for i := 0; i < batchWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for idx := range requestCh {
-				req := requests[idx]
-				payment, err := f.ProcessPayment(ctx, req.Provider, req.Amount, req.Currency)
-				results[idx] = repository.PaymentResult{
-					Request: req,
-					Payment: payment,
-					Error:   err,
-				}
+			for idx := range indexCh {
+				fn(idx)
 			}
 		}()
 	}
 
-	// Send requests to workers
-	for i := range requests {
-		requestCh <- i
+	// Send work to workers
+	for i := 0; i < n; i++ {
+		indexCh <- i
 	}
-	close(requestCh)
+	close(indexCh)
 
-	// Wait for all requests to complete
+	// Wait for all items to complete
 	wg.Wait()
-	return results
+}
+
+// FactoryOption configures optional behaviour of a Factory
+type FactoryOption func(*Factory)
+
+// WithMetricsRegistry records the factory's metrics in registry instead of a private one
+func WithMetricsRegistry(registry *metrics.Registry) FactoryOption {
+	return func(f *Factory) {
+		f.metrics = newPaymentMetrics(registry)
+	}
+}
+
+// WithRandSource sets the source of randomness used for simulations and retry jitter,
+// so tests can seed it
+func WithRandSource(source rand.Source) FactoryOption {
+	return func(f *Factory) {
+		f.random = rand.New(source)
+	}
+}
+
+// WithClock sets the clock the factory, and the providers it creates, read the current
+// time from, so tests can control it
+func WithClock(clock Clock) FactoryOption {
+	return func(f *Factory) {
+		f.clock = clock
+	}
 }
 
 // NewFactory creates a new provider factory
-func NewFactory(cfg *config.Config, client *http.Client) *Factory {
-	return &Factory{
+func NewFactory(cfg *config.Config, client *http.Client, opts ...FactoryOption) *Factory {
+	f := &Factory{
 		config:         cfg,
 		httpClient:     client,
 		providers:      make(map[string]repository.PaymentProvider),
 		providerStates: make(map[string]*ProviderState),
+		limiters:       make(map[string]*tokenBucket),
+		retryBudgets:   make(map[string]*tokenBucket),
+		idempotency:    make(map[string]*idempotentResult),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.metrics == nil {
+		f.metrics = newPaymentMetrics(metrics.NewRegistry())
+	}
+	if f.tracer == nil {
+		f.tracer = newTracerProvider(cfg).Tracer(tracerName)
 	}
+	if f.clock == nil {
+		f.clock = realClock{}
+	}
+	if f.random == nil {
+		f.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return f
+}
+
+// Close releases the background resources of the factory: it stops the metrics server,
+// if one was started, and closes the idle connections of the HTTP client. Calls after
+// the first do nothing and return the first call's error.
+func (f *Factory) Close() error {
+	f.closeOnce.Do(func() {
+		f.mutex.Lock()
+		server, done := f.metricsServer, f.metricsDone
+		f.metricsServer, f.metricsDone = nil, nil
+		f.mutex.Unlock()
+
+		if server != nil {
+			if err := server.Close(); err != nil {
+				f.closeErr = fmt.Errorf("failed to stop metrics server: %w", err)
+			}
+			<-done
+		}
+		if f.httpClient != nil {
+			f.httpClient.CloseIdleConnections()
+		}
+	})
+	return f.closeErr
 }
 
-// GetProviderMetadata returns metadata for a specific provider
+// GetProviderMetadata returns the static metadata of a specific provider merged with
+// its live state: availability, request counters, average latency and the last error
 func (f *Factory) GetProviderMetadata(providerName string) map[string]interface{} {
 	provider, err := f.getOrCreateProvider(providerName)
 	if err != nil {
@@ -88,7 +382,20 @@ func (f *Factory) GetProviderMetadata(providerName string) map[string]interface{
 			"error": err.Error(),
 		}
 	}
-	return provider.GetMetadata()
+
+	metadata := provider.GetMetadata()
+	if snapshot, exists := f.GetProviderStateSnapshot(providerName); exists {
+		metadata["isAvailable"] = snapshot.IsAvailable
+		metadata["successCount"] = snapshot.SuccessCount
+		metadata["errorCount"] = snapshot.ErrorCount
+		metadata["consecutiveErrors"] = snapshot.ConsecutiveErrs
+		metadata["avgLatency"] = snapshot.AvgLatency
+		metadata["lastError"] = ""
+		if snapshot.LastError != nil {
+			metadata["lastError"] = snapshot.LastError.Error()
+		}
+	}
+	return metadata
 }
 
 // ListProviders returns a list of all available providers
@@ -100,206 +407,494 @@ func (f *Factory) ListProviders() []string {
 	return providers
 }
 
-// getOrCreateProvider gets an existing provider or creates a new one
+// ListProvidersDetailed returns the configuration and availability of every provider,
+// sorted by name. Supported currencies fall back to the global ones like payments do.
+func (f *Factory) ListProvidersDetailed() []repository.ProviderInfo {
+	names := f.ListProviders()
+	sort.Strings(names)
+
+	providers := make([]repository.ProviderInfo, 0, len(names))
+	for _, name := range names {
+		cfg := f.withGlobalCurrencies(f.config.Providers[name])
+		currencies := cfg.SupportedCurrencies
+		if len(currencies) == 0 {
+			currencies = defaultCurrencies
+		}
+		providers = append(providers, repository.ProviderInfo{
+			Name:                name,
+			Endpoint:            cfg.Endpoint,
+			MaxAmount:           cfg.MaxAmount,
+			SupportedCurrencies: append([]string(nil), currencies...),
+			Available:           f.IsProviderAvailable(name),
+		})
+	}
+	return providers
+}
+
+// getOrCreateProvider returns the provider named providerName, creating it on first use
 func (f *Factory) getOrCreateProvider(providerName string) (repository.PaymentProvider, error) {
+	return f.loadProvider(providerName, false)
+}
+
+// loadProvider returns the provider named providerName, creating it unless it exists,
+// after validating its configuration when validate is set. It is the only place
+// providers are created: lookups share the read lock, and a provider missing under it
+// is created under the write lock after checking again, so concurrent first uses
+// create exactly one instance per name.
+func (f *Factory) loadProvider(providerName string, validate bool) (repository.PaymentProvider, error) {
+	f.mutex.RLock()
+	provider, exists := f.providers[providerName]
+	f.mutex.RUnlock()
+	if exists {
+		return provider, nil
+	}
+
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
-	// Check if provider already exists
+	// Another caller may have created it while the lock was released
 	if provider, exists := f.providers[providerName]; exists {
 		return provider, nil
 	}
 
-	// Get provider config
 	providerConfig, exists := f.config.Providers[providerName]
 	if !exists {
+		logger.Error("No configuration found for provider: %s", providerName)
 		return nil, &domain.PaymentError{
 			Code:    domain.ErrProviderNotFound,
 			Message: fmt.Sprintf("Provider %s not found", providerName),
 		}
 	}
 
-	// Create new provider
-	var provider repository.PaymentProvider
-	switch providerName {
-	case "ProviderA":
-		provider = NewProviderA(providerConfig, f.httpClient)
-	case "ProviderB":
-		provider = NewProviderB(providerConfig, f.httpClient)
-	default:
-		return nil, &domain.PaymentError{
-			Code:    domain.ErrProviderNotFound,
-			Message: fmt.Sprintf("Provider %s not supported", providerName),
+	if validate {
+		if err := providerConfig.Validate(); err != nil {
+			logger.Error("Invalid configuration for provider %s: %v", providerName, err)
+			return nil, (&domain.PaymentError{
+				Code:    domain.ErrInvalidConfiguration,
+				Message: "Invalid provider configuration: " + err.Error(),
+			}).WithCause(err)
 		}
 	}
 
-	// Initialize provider state
-	f.providerStates[providerName] = &ProviderState{
-		IsAvailable: true,
-		LastChecked: time.Now(),
+	logger.Info("Creating new instance of provider: %s", providerName)
+	providerConfig = f.withGlobalCurrencies(providerConfig)
+	// Config.Validate rejects sizes that do not parse, leaving those unlimited here
+	providerConfig.MaxResponseSize, _ = config.ParseByteSize(f.config.Global.MaxRequestSize)
+	provider, err := newRegisteredProvider(providerName, providerConfig, f.httpClient)
+	if err != nil {
+		logger.Error("No constructor registered for provider: %s", providerName)
+		return nil, err
+	}
+	if setter, ok := provider.(clockSetter); ok {
+		setter.setClock(f.clock)
+	}
+	provider = Chain(provider, f.middleware...)
+	if setter, ok := provider.(clockSetter); ok {
+		setter.setClock(f.clock)
+	}
+
+	// Initialize provider state unless it was already tracked (e.g. disabled before first use)
+	if _, exists := f.providerStates[providerName]; !exists {
+		f.providerStates[providerName] = &ProviderState{
+			IsAvailable: true,
+			LastChecked: f.clock.Now(),
+		}
 	}
 
 	f.providers[providerName] = provider
 	return provider, nil
 }
 
-// updateProviderState updates the state of a provider
-func (f *Factory) updateProviderState(providerName string, success bool, err error) {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+// ProcessPayment processes a payment through the specified provider. It is a
+// shorthand for ProcessPaymentRequest with only the provider, amount and currency set.
+func (f *Factory) ProcessPayment(ctx context.Context, providerName string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	return f.ProcessPaymentRequest(ctx, repository.PaymentRequest{
+		Provider: providerName,
+		Amount:   amount,
+		Currency: currency,
+	})
+}
 
-	state, exists := f.providerStates[providerName]
-	if !exists {
-		state = &ProviderState{}
-		f.providerStates[providerName] = state
+// ProcessPaymentRequest processes a payment through the requested provider, retrying
+// transient failures as allowed by the provider's RetryPolicy. With an idempotency key,
+// from req or carried by ctx (see domain.WithIdempotencyKey), the payment is made at
+// most once per key. The request's reference and metadata are forwarded to the provider.
+func (f *Factory) ProcessPaymentRequest(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	ctx, span := startPaymentSpan(ctx, f.tracer, "Factory.ProcessPayment", req.Provider, req.Amount, req.Currency)
+	payment, err := f.processPaymentRequest(ctx, req)
+	endSpan(span, err)
+	return payment, err
+}
+
+// processPaymentRequest dispatches the request to the idempotent or plain payment path
+func (f *Factory) processPaymentRequest(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	// A caller that has already given up must not count against the provider's health
+	if err := ctx.Err(); err != nil {
+		return nil, cancelledError(req.Provider, err)
 	}
+	ctx = logger.ContextWithRequestID(ctx, req.RequestID)
+	ctx = domain.WithIdempotencyKey(ctx, req.IdempotencyKey)
+	ctx = domain.WithPaymentDetails(ctx, req.Details())
+	if key := domain.IdempotencyKeyFromContext(ctx); key != "" {
+		return f.processIdempotent(ctx, key, req.Provider, req.Amount, req.Currency)
+	}
+	return f.processPayment(ctx, req.Provider, req.Amount, req.Currency)
+}
 
-	state.mutex.Lock()
-	defer state.mutex.Unlock()
+// processPayment resolves the provider and processes the payment, recording metrics
+func (f *Factory) processPayment(ctx context.Context, providerName string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	provider, err := f.getOrCreateProvider(providerName)
+	if err != nil {
+		return nil, err.(*domain.PaymentError)
+	}
 
-	state.LastChecked = time.Now()
+	done := f.trackInFlight(providerName)
+	payment, paymentErr := f.processWithRetries(ctx, provider, providerName, amount, currency)
+	done()
+	f.metrics.recordResult(providerName, paymentErr)
+	return payment, paymentErr
+}
 
-	if success {
-		state.IsAvailable = true
-		state.ConsecutiveErrs = 0
-		state.SuccessCount++
-	} else {
-		state.ConsecutiveErrs++
-		state.ErrorCount++
-		state.LastError = err
-		if state.ConsecutiveErrs >= 3 {
-			state.IsAvailable = false
+// processWithRetries runs the payment attempts against an already resolved provider
+func (f *Factory) processWithRetries(ctx context.Context, provider repository.PaymentProvider, providerName string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	if f.inMaintenance(providerName) {
+		logger.WithContext(ctx).Info("Provider %s is in a maintenance window, skipping payment", providerName)
+		return nil, maintenanceError(providerName)
+	}
+
+	if f.injectDecline() {
+		logger.WithContext(ctx).Info("Simulated decline for provider %s", providerName)
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrCardDeclined,
+			Message:   "Payment was declined (simulated)",
+			Provider:  providerName,
+			Retryable: false,
+		}
+	}
+
+	if unavailableErr := f.unavailableError(providerName); unavailableErr != nil {
+		logger.WithContext(ctx).Info("Provider %s is unavailable, skipping payment: %s", providerName, unavailableErr.Message)
+		return nil, unavailableErr
+	}
+
+	policy := f.config.Providers[providerName].RetryPolicy
+	attempts := maxAttempts(policy)
+
+	var paymentErr *domain.PaymentError
+	var lastRetry time.Time
+	for attempt := 1; attempt <= attempts; attempt++ {
+		// Throttling is on our side, so it does not count against the provider's health
+		if limitErr := f.acquireRateLimit(ctx, providerName); limitErr != nil {
+			return nil, limitErr
+		}
+
+		var payment *domain.Payment
+		start := f.clock.Now()
+		payment, paymentErr = provider.ProcessPayment(ctx, amount, currency)
+		latency := f.clock.Now().Sub(start)
+		f.metrics.observeLatency(providerName, latency)
+		f.recordLatency(providerName, latency)
+		if paymentErr == nil {
+			f.UpdateProviderState(providerName, nil)
+			// RetryCount reports the retries actually performed for this payment
+			payment.RetryCount = attempt - 1
+			if attempt > 1 {
+				payment.LastRetryTime = &lastRetry
+			}
+			return payment, nil
+		}
+
+		if attempt == attempts || !isRetryable(policy, paymentErr) {
+			break
+		}
+		if !f.takeRetry(providerName) {
+			logger.WithContext(ctx).Error("Retry budget of provider %s exhausted, not retrying: %v", providerName, paymentErr)
+			f.UpdateProviderState(providerName, paymentErr)
+			return nil, retryBudgetError(providerName, paymentErr)
+		}
+
+		delay := f.retryDelay(policy, attempt)
+		logger.WithContext(ctx).Info("Retrying payment with provider %s after %v (attempt %d/%d): %v", providerName, delay, attempt+1, attempts, paymentErr)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			f.UpdateProviderState(providerName, paymentErr)
+			return nil, cancelledError(providerName, sleepErr)
 		}
+		lastRetry = f.clock.Now()
 	}
+
+	f.UpdateProviderState(providerName, paymentErr)
+	return nil, paymentErr
 }
 
-// ProcessPayment processes a payment through the specified provider
-func (f *Factory) ProcessPayment(ctx context.Context, providerName string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+// inMaintenance reports whether the provider is inside one of its maintenance windows
+func (f *Factory) inMaintenance(providerName string) bool {
+	now := f.clock.Now()
+	for _, window := range f.config.Providers[providerName].MaintenanceWindows {
+		if window.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenanceError is returned for payments to a provider under maintenance
+func maintenanceError(providerName string) *domain.PaymentError {
+	return &domain.PaymentError{
+		Code:      domain.ErrProviderUnavailable,
+		Message:   fmt.Sprintf("Provider %s is unavailable: %s", providerName, ReasonMaintenance),
+		Provider:  providerName,
+		Retryable: false,
+	}
+}
+
+// injectDecline reports whether this payment should be declined by the staging
+// decline simulation
+func (f *Factory) injectDecline() bool {
+	simulation := f.config.Global.Simulation
+	if !simulation.Staging || simulation.DeclineInjectionRate <= 0 {
+		return false
+	}
+
+	f.randomMutex.Lock()
+	defer f.randomMutex.Unlock()
+	return f.random.Float64() < simulation.DeclineInjectionRate
+}
+
+// GetPaymentStatus queries the specified provider for the current status of a payment
+func (f *Factory) GetPaymentStatus(ctx context.Context, providerName string, transactionID string) (*domain.Payment, *domain.PaymentError) {
 	provider, err := f.getOrCreateProvider(providerName)
 	if err != nil {
 		return nil, err.(*domain.PaymentError)
 	}
 
-	payment, paymentErr := provider.ProcessPayment(ctx, amount, currency)
-
-	if paymentErr != nil {
-		f.updateProviderState(providerName, false, paymentErr)
-		return nil, paymentErr
+	if limitErr := f.acquireRateLimit(ctx, providerName); limitErr != nil {
+		return nil, limitErr
 	}
 
-	f.updateProviderState(providerName, true, nil)
-	return payment, nil
+	return provider.GetPaymentStatus(ctx, transactionID)
 }
 
-// validateProviderConfig checks if the provider configuration is valid
-func (f *Factory) validateProviderConfig(cfg config.PaymentProviderConfig) error {
-	if cfg.Name == "" {
-		return &domain.PaymentError{
-			Code:    domain.ErrInvalidConfiguration,
-			Message: "Provider name is required",
-		}
+// acquireRateLimit takes a token from the provider's rate limiter, either waiting for
+// one or failing fast with ErrRateLimitExceeded depending on the provider config.
+// A context that ends while waiting yields ErrCancelled.
+func (f *Factory) acquireRateLimit(ctx context.Context, providerName string) *domain.PaymentError {
+	limit := f.config.Providers[providerName].RateLimit
+	if limit.RequestsPerSecond <= 0 {
+		return nil
 	}
 
-	if cfg.Endpoint == "" {
-		return &domain.PaymentError{
-			Code:    domain.ErrInvalidConfiguration,
-			Message: fmt.Sprintf("Endpoint is required for provider %s", cfg.Name),
-		}
+	f.mutex.Lock()
+	limiter, exists := f.limiters[providerName]
+	if !exists {
+		limiter = newTokenBucket(limit.RequestsPerSecond, limit.BurstSize, f.clock)
+		f.limiters[providerName] = limiter
 	}
-
-	if cfg.MaxAmount <= 0 {
-		return &domain.PaymentError{
-			Code:    domain.ErrInvalidConfiguration,
-			Message: fmt.Sprintf("Invalid max amount for provider %s", cfg.Name),
+	f.mutex.Unlock()
+
+	if limit.FailFast {
+		if !limiter.Allow() {
+			logger.WithContext(ctx).Error("Rate limit exceeded for provider %s", providerName)
+			return &domain.PaymentError{
+				Code:      domain.ErrRateLimitExceeded,
+				Message:   fmt.Sprintf("Rate limit of %d requests per second exceeded", limit.RequestsPerSecond),
+				Provider:  providerName,
+				Retryable: true,
+			}
 		}
+		return nil
 	}
 
+	if err := limiter.Wait(ctx); err != nil {
+		logger.WithContext(ctx).Info("Cancelled while waiting for rate limiter of provider %s: %v", providerName, err)
+		return cancelledError(providerName, err)
+	}
 	return nil
 }
 
-// CreateProvider creates a new provider instance with validation and state tracking
-func (f *Factory) CreateProvider(name string) (repository.PaymentProvider, error) {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+// CancelPayment asks the specified provider to cancel a payment it has not settled yet
+func (f *Factory) CancelPayment(ctx context.Context, providerName string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	provider, err := f.getOrCreateProvider(providerName)
+	if err != nil {
+		return nil, err.(*domain.PaymentError)
+	}
 
-	logger.Debug("Creating provider: %s", name)
+	if limitErr := f.acquireRateLimit(ctx, providerName); limitErr != nil {
+		return nil, limitErr
+	}
 
-	// Check if provider already exists
-	if provider, exists := f.providers[name]; exists {
-		logger.Debug("Provider %s already exists, returning existing instance", name)
-		return provider, nil
+	payment, cancelErr := provider.CancelPayment(ctx, transactionID)
+	if cancelErr != nil {
+		return nil, cancelErr
 	}
+	logger.WithContext(ctx).Info("Cancelled transaction %s with provider %s", transactionID, providerName)
+	return payment, nil
+}
 
-	cfg, exists := f.config.Providers[name]
-	if !exists {
-		logger.Error("No configuration found for provider: %s", name)
+// RefundPayment refunds all or part of a payment through the provider that processed
+// it. The provider checks the transaction and how much of it is left to refund.
+func (f *Factory) RefundPayment(ctx context.Context, providerName string, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	if amount <= 0 {
 		return nil, &domain.PaymentError{
-			Code:    domain.ErrProviderNotFound,
-			Message: fmt.Sprintf("No configuration found for provider: %s", name),
+			Code:    domain.ErrInvalidAmount,
+			Message: "Refund amount must be greater than 0",
+		}
+	}
+	if transactionID == "" {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrTransactionNotFound,
+			Message: "Transaction ID is required",
 		}
 	}
 
-	// Validate provider configuration
-	if err := f.validateProviderConfig(cfg); err != nil {
-		logger.Error("Invalid configuration for provider %s: %v", name, err)
-		return nil, err
+	provider, err := f.getOrCreateProvider(providerName)
+	if err != nil {
+		return nil, err.(*domain.PaymentError)
+	}
+	if limitErr := f.acquireRateLimit(ctx, providerName); limitErr != nil {
+		return nil, limitErr
 	}
 
-	logger.Info("Creating new instance of provider: %s", name)
-	var provider repository.PaymentProvider
-	switch name {
-	case "ProviderA":
-		provider = NewProviderA(cfg, f.httpClient)
-	case "ProviderB":
-		provider = NewProviderB(cfg, f.httpClient)
-	default:
-		return nil, &domain.PaymentError{
+	refund, refundErr := provider.RefundPayment(ctx, transactionID, amount)
+	if refundErr != nil {
+		return nil, refundErr
+	}
+	logger.WithContext(ctx).Info("Refunded %.2f of transaction %s with provider %s", amount, transactionID, providerName)
+	return refund, nil
+}
+
+// CanProcess reports whether a payment request would be accepted without sending it:
+// the provider must exist and be enabled, the currency supported and the amount within
+// the provider's limits. It returns nil when the request is acceptable.
+func (f *Factory) CanProcess(req repository.PaymentRequest) *domain.PaymentError {
+	providerCfg, exists := f.config.Providers[req.Provider]
+	if !exists {
+		return &domain.PaymentError{
 			Code:    domain.ErrProviderNotFound,
-			Message: fmt.Sprintf("Unknown provider type: %s", name),
+			Message: fmt.Sprintf("Provider %s not found", req.Provider),
 		}
 	}
 
-	// Initialize provider state
-	f.providerStates[name] = &ProviderState{
-		IsAvailable: true,
-		LastChecked: time.Now(),
+	if snapshot, exists := f.GetProviderStateSnapshot(req.Provider); exists && !snapshot.IsAvailable {
+		return &domain.PaymentError{
+			Code:     domain.ErrProviderUnavailable,
+			Message:  fmt.Sprintf("Provider %s is unavailable (%s)", req.Provider, snapshot.UnavailableReason),
+			Provider: req.Provider,
+		}
 	}
 
-	f.providers[name] = provider
-	return provider, nil
+	if f.inMaintenance(req.Provider) {
+		return maintenanceError(req.Provider)
+	}
+
+	if !currencyAllowed(f.withGlobalCurrencies(providerCfg).SupportedCurrencies, req.Currency) {
+		return &domain.PaymentError{
+			Code:     domain.ErrInvalidCurrency,
+			Message:  fmt.Sprintf("Currency %q is not supported", req.Currency),
+			Provider: req.Provider,
+		}
+	}
+
+	if req.Amount <= 0 {
+		return &domain.PaymentError{
+			Code:     domain.ErrInvalidAmount,
+			Message:  "Amount must be greater than 0",
+			Provider: req.Provider,
+		}
+	}
+	if req.Amount < providerCfg.MinAmount {
+		return &domain.PaymentError{
+			Code:     domain.ErrInvalidAmount,
+			Message:  fmt.Sprintf("Amount is below minimum limit of %v", providerCfg.MinAmount),
+			Provider: req.Provider,
+		}
+	}
+	if maxAmount := providerCfg.MaxAmountFor(req.Currency); req.Amount > maxAmount {
+		return &domain.PaymentError{
+			Code:     domain.ErrInvalidAmount,
+			Message:  fmt.Sprintf("Amount exceeds maximum limit of %v %s", maxAmount, req.Currency),
+			Provider: req.Provider,
+		}
+	}
+
+	return nil
 }
 
-// UpdateProviderState updates the state of a provider based on operation results
-func (f *Factory) UpdateProviderState(name string, err error) {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+// withGlobalCurrencies returns cfg with the global supported currencies filled in
+// when the provider does not restrict its own
+func (f *Factory) withGlobalCurrencies(cfg config.PaymentProviderConfig) config.PaymentProviderConfig {
+	if len(cfg.SupportedCurrencies) == 0 {
+		cfg.SupportedCurrencies = f.config.Global.SupportedCurrencies
+	}
+	return cfg
+}
 
-	state, exists := f.providerStates[name]
-	if !exists {
+// defaultCurrencies are the currencies known to the domain, accepted when no
+// supported currencies are configured at all
+var defaultCurrencies = []string{string(domain.USD), string(domain.EUR), string(domain.GBP)}
+
+// currencyAllowed checks the currency against supported, falling back to
+// defaultCurrencies when the list is empty
+func currencyAllowed(supported []string, currency string) bool {
+	if len(supported) == 0 {
+		supported = defaultCurrencies
+	}
+	return domain.IsSupportedCurrency(domain.Currency(currency), supported)
+}
+
+// CreateProvider returns the named provider, creating it with its configuration
+// validated and its state tracked unless it already exists. Payments create their
+// provider through the same path on first use, without the validation.
+func (f *Factory) CreateProvider(name string) (repository.PaymentProvider, error) {
+	return f.loadProvider(name, true)
+}
+
+// defaultFailureThreshold is used when CircuitBreakerConfig.FailureThreshold is not set
+const defaultFailureThreshold = 3
+
+// failureThreshold returns how many consecutive errors mark a provider unavailable
+func (f *Factory) failureThreshold() int {
+	if threshold := f.config.Global.CircuitBreaker.FailureThreshold; threshold > 0 {
+		return threshold
+	}
+	return defaultFailureThreshold
+}
+
+// UpdateProviderState records the outcome of a request to a provider; a nil err is a
+// success. The state of a configured provider is created on first use and starts
+// available; outcomes for unknown providers are ignored. After FailureThreshold
+// consecutive errors the provider is marked unavailable, and the next success makes
+// it available again unless it was disabled manually. Once the circuit breaker's
+// ResetTimeout has passed the provider is available again to be probed; a single
+// error then marks it unavailable again.
+func (f *Factory) UpdateProviderState(name string, err error) {
+	state, stateErr := f.stateFor(name)
+	if stateErr != nil {
 		return
 	}
+	threshold := f.failureThreshold()
 
 	state.mutex.Lock()
 	defer state.mutex.Unlock()
 
-	state.LastChecked = time.Now()
+	state.LastChecked = f.clock.Now()
 	if err != nil {
 		state.ConsecutiveErrs++
-		state.ErrorCount++
+		atomic.AddInt64(&state.ErrorCount, 1)
 		state.LastError = err
-
-		// Disable provider if too many consecutive errors
-		if state.ConsecutiveErrs >= 3 {
-			state.IsAvailable = false
+		if state.ConsecutiveErrs >= threshold && (state.IsAvailable || state.UnavailableReason == ReasonConsecutiveErrors) {
+			// A failed probe after the reset timeout opens the circuit again
+			state.markUnavailable(ReasonConsecutiveErrors)
+			state.openedAt = state.LastChecked
 		}
 	} else {
 		state.ConsecutiveErrs = 0
-		state.SuccessCount++
-		state.IsAvailable = true
+		atomic.AddInt64(&state.SuccessCount, 1)
+		state.markAvailable()
 	}
 }
 
-// GetProviderState returns the current state of a provider
+// GetProviderState returns the current state of a provider. The returned state is
+// live; use GetProviderStateSnapshot to read it without racing concurrent updates.
 func (f *Factory) GetProviderState(name string) *ProviderState {
 	f.mutex.RLock()
 	defer f.mutex.RUnlock()
@@ -307,6 +902,33 @@ func (f *Factory) GetProviderState(name string) *ProviderState {
 	return f.providerStates[name]
 }
 
+// GetProviderStateSnapshot returns a race-free copy of a provider's state.
+// The boolean is false when the provider has no state yet.
+func (f *Factory) GetProviderStateSnapshot(name string) (ProviderStateSnapshot, bool) {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return ProviderStateSnapshot{}, false
+	}
+	return f.currentSnapshot(state), true
+}
+
+// currentSnapshot copies state as it applies now. A provider marked unavailable for
+// consecutive errors is reported available once the circuit breaker's ResetTimeout has
+// passed since its last error, so the next request can probe it. Its consecutive
+// errors are kept, so a failed probe marks it unavailable again. Without a
+// ResetTimeout only a success makes the provider available. The state is not changed.
+func (f *Factory) currentSnapshot(state *ProviderState) ProviderStateSnapshot {
+	snapshot := state.snapshot()
+	timeout := f.config.Global.CircuitBreaker.ResetTimeout
+	if timeout > 0 && snapshot.UnavailableReason == ReasonConsecutiveErrors && !f.clock.Now().Before(snapshot.openedAt.Add(timeout)) {
+		snapshot.IsAvailable = true
+		snapshot.UnavailableReason = ReasonNone
+	}
+	return snapshot
+}
+
 // GetAllProviders returns all registered providers
 func (f *Factory) GetAllProviders() []repository.PaymentProvider {
 	f.mutex.RLock()
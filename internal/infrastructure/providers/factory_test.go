@@ -1,12 +1,30 @@
 package providers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/httpclient"
+	"yuno_assesment/pkg/logger"
+	"yuno_assesment/pkg/metrics"
 )
 
 func TestFactory_CreateProvider(t *testing.T) {
@@ -71,6 +89,39 @@ func TestFactory_CreateProvider(t *testing.T) {
 			expectedError: true,
 			errorCode:     domain.ErrInvalidConfiguration,
 		},
+		{
+			name:         "invalid provider config - missing timeout",
+			providerName: "ProviderA",
+			modifyConfig: func(c *config.Config) {
+				providerConfig := c.Providers["ProviderA"]
+				providerConfig.Timeout = 0
+				c.Providers["ProviderA"] = providerConfig
+			},
+			expectedError: true,
+			errorCode:     domain.ErrInvalidConfiguration,
+		},
+		{
+			name:         "invalid provider config - retries without max attempts",
+			providerName: "ProviderA",
+			modifyConfig: func(c *config.Config) {
+				providerConfig := c.Providers["ProviderA"]
+				providerConfig.RetryPolicy = config.RetryPolicy{InitialDelay: time.Millisecond}
+				c.Providers["ProviderA"] = providerConfig
+			},
+			expectedError: true,
+			errorCode:     domain.ErrInvalidConfiguration,
+		},
+		{
+			name:         "invalid provider config - negative rate limit",
+			providerName: "ProviderA",
+			modifyConfig: func(c *config.Config) {
+				providerConfig := c.Providers["ProviderA"]
+				providerConfig.RateLimit.RequestsPerSecond = -1
+				c.Providers["ProviderA"] = providerConfig
+			},
+			expectedError: true,
+			errorCode:     domain.ErrInvalidConfiguration,
+		},
 		{
 			name:         "invalid provider config - invalid max amount",
 			providerName: "ProviderA",
@@ -249,3 +300,1939 @@ func TestFactory_UpdateProviderState(t *testing.T) {
 		})
 	}
 }
+
+func TestFactory_ProcessPayment_Retry(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-RETRY-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	tests := []struct {
+		name            string
+		retryCount      int
+		policy          config.RetryPolicy
+		failures        int
+		expectedCalls   int
+		expectedError   bool
+		expectedRetries int
+	}{
+		{
+			name:            "succeeds on first attempt",
+			policy:          config.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond},
+			failures:        0,
+			expectedCalls:   1,
+			expectedRetries: 0,
+		},
+		{
+			name:            "retries transient failures until success",
+			policy:          config.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, RetryableCodes: []int{500}},
+			failures:        2,
+			expectedCalls:   3,
+			expectedRetries: 2,
+		},
+		{
+			name:          "gives up after max attempts",
+			policy:        config.RetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond},
+			failures:      5,
+			expectedCalls: 2,
+			expectedError: true,
+		},
+		{
+			name:          "does not retry codes outside the policy",
+			policy:        config.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, RetryableCodes: []int{503}},
+			failures:      1,
+			expectedCalls: 1,
+			expectedError: true,
+		},
+		{
+			name:          "legacy retry count does not govern retries",
+			retryCount:    5,
+			policy:        config.RetryPolicy{MaxAttempts: 1},
+			failures:      5,
+			expectedCalls: 1,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if calls <= tt.failures {
+					return httpclient.NewMockResponse(http.StatusInternalServerError, nil), nil
+				}
+				return httpclient.NewMockResponse(http.StatusOK, approved), nil
+			})
+
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {
+						Name:        "ProviderA",
+						Endpoint:    "http://provider-a.test",
+						MaxAmount:   10000,
+						RetryCount:  tt.retryCount,
+						RetryPolicy: tt.policy,
+					},
+				},
+			}
+			now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+			factory := NewFactory(cfg, client, WithClock(NewFakeClock(now)))
+
+			payment, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d provider calls, got %d", tt.expectedCalls, calls)
+			}
+			if tt.expectedError {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.RetryCount != tt.expectedRetries {
+				t.Errorf("expected RetryCount %d, got %d", tt.expectedRetries, payment.RetryCount)
+			}
+			if tt.expectedRetries > 0 && (payment.LastRetryTime == nil || !payment.LastRetryTime.Equal(now)) {
+				t.Errorf("expected LastRetryTime from the factory clock at %v, got %v", now, payment.LastRetryTime)
+			}
+		})
+	}
+}
+
+func TestFactory_ProcessPayment_RetryBudget(t *testing.T) {
+	calls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httpclient.NewMockResponse(http.StatusServiceUnavailable, nil), nil
+	})
+
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:        "ProviderA",
+				Endpoint:    "http://provider-a.test",
+				MaxAmount:   10000,
+				RetryPolicy: config.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, RetryBudget: 2},
+			},
+		},
+	}
+	factory := NewFactory(cfg, client)
+
+	// The first payment uses up the budget with its two retries
+	_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+	if err == nil || err.Code != domain.ErrProviderUnavailable {
+		t.Fatalf("expected %s, got %v", domain.ErrProviderUnavailable, err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 provider calls, got %d", calls)
+	}
+
+	// The second payment fails fast after its first attempt
+	_, err = factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+	if err == nil || err.Code != domain.ErrTooManyRetries {
+		t.Fatalf("expected %s, got %v", domain.ErrTooManyRetries, err)
+	}
+	var cause *domain.PaymentError
+	if !errors.As(errors.Unwrap(err), &cause) || cause.Code != domain.ErrProviderUnavailable {
+		t.Errorf("expected the provider failure as the cause, got %v", errors.Unwrap(err))
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 provider calls, got %d", calls)
+	}
+
+	if remaining := factory.MetricsRegistry().Gauge("provider_retry_budget_remaining", "").Value("ProviderA"); remaining >= 1 {
+		t.Errorf("expected less than one retry left in the budget, got %v", remaining)
+	}
+
+	// The budget refills over time
+	budget := factory.retryBudgets["ProviderA"]
+	budget.mutex.Lock()
+	budget.last = budget.last.Add(-time.Minute)
+	budget.mutex.Unlock()
+	if !factory.takeRetry("ProviderA") {
+		t.Error("expected the budget to allow a retry after it refilled")
+	}
+}
+
+func TestFactory_RefundPayment(t *testing.T) {
+	refundCalls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/process/TXN-REFUND-1/refund" {
+			t.Errorf("unexpected request to %s", req.URL.Path)
+			return httpclient.NewMockResponse(http.StatusNotFound, nil), nil
+		}
+		refundCalls++
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "RFD-1",
+			"status":         "REFUNDED",
+			"amount":         40.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T11:00:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:      "ProviderA",
+				Endpoint:  "http://provider-a.test/process",
+				MaxAmount: 10000,
+			},
+		},
+	}
+	// The payment was approved by another instance, so this factory has never seen it
+	factory := NewFactory(cfg, client)
+
+	if _, err := factory.RefundPayment(context.Background(), "ProviderA", "TXN-REFUND-1", 0); err == nil || err.Code != domain.ErrInvalidAmount {
+		t.Errorf("expected %s for a zero refund, got %v", domain.ErrInvalidAmount, err)
+	}
+	if _, err := factory.RefundPayment(context.Background(), "ProviderX", "TXN-REFUND-1", 40.00); err == nil || err.Code != domain.ErrProviderNotFound {
+		t.Errorf("expected %s for an unknown provider, got %v", domain.ErrProviderNotFound, err)
+	}
+
+	refund, err := factory.RefundPayment(context.Background(), "ProviderA", "TXN-REFUND-1", 40.00)
+	if err != nil {
+		t.Fatalf("unexpected refund error: %v", err)
+	}
+	if refund.Status != domain.StatusRefunded || refund.TransactionID != "TXN-REFUND-1" {
+		t.Errorf("expected refunded payment for TXN-REFUND-1, got %+v", refund)
+	}
+	if refundCalls != 1 {
+		t.Errorf("expected 1 refund call to the provider, got %d", refundCalls)
+	}
+}
+
+func TestFactory_UnavailableReason(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:      "ProviderA",
+				Endpoint:  "http://provider-a.test",
+				MaxAmount: 10000,
+				Timeout:   time.Second,
+			},
+		},
+	}
+
+	t.Run("consecutive errors", func(t *testing.T) {
+		factory := NewFactory(cfg, &http.Client{})
+		if _, err := factory.CreateProvider("ProviderA"); err != nil {
+			t.Fatalf("failed to create provider: %v", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			factory.UpdateProviderState("ProviderA", &domain.PaymentError{Code: domain.ErrNetworkError})
+		}
+
+		state := factory.GetProviderState("ProviderA")
+		if state.IsAvailable {
+			t.Error("expected provider to be unavailable")
+		}
+		if state.UnavailableReason != ReasonConsecutiveErrors {
+			t.Errorf("expected reason %q, got %q", ReasonConsecutiveErrors, state.UnavailableReason)
+		}
+
+		health := factory.Health()
+		if len(health) != 1 || health[0].UnavailableReason != ReasonConsecutiveErrors {
+			t.Errorf("expected health to report reason %q, got %+v", ReasonConsecutiveErrors, health)
+		}
+
+		// A success clears the reason again
+		factory.UpdateProviderState("ProviderA", nil)
+		if state.UnavailableReason != ReasonNone || !state.IsAvailable {
+			t.Errorf("expected provider to recover, got available=%v reason=%q", state.IsAvailable, state.UnavailableReason)
+		}
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		calls := 0
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return httpclient.NewMockResponse(http.StatusInternalServerError, nil), nil
+		})
+		factory := NewFactory(cfg, client)
+
+		if err := factory.DisableProvider("ProviderA"); err != nil {
+			t.Fatalf("failed to disable provider: %v", err)
+		}
+
+		state := factory.GetProviderState("ProviderA")
+		if state.IsAvailable || state.UnavailableReason != ReasonManual {
+			t.Errorf("expected manual disable, got available=%v reason=%q", state.IsAvailable, state.UnavailableReason)
+		}
+
+		_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+		if err == nil || err.Code != domain.ErrProviderUnavailable {
+			t.Errorf("expected %s for disabled provider, got %v", domain.ErrProviderUnavailable, err)
+		}
+		if calls != 0 {
+			t.Errorf("expected no provider calls while disabled, got %d", calls)
+		}
+
+		// Successes must not silently re-enable a manually disabled provider
+		factory.UpdateProviderState("ProviderA", nil)
+		if state.UnavailableReason != ReasonManual {
+			t.Errorf("expected reason to stay %q, got %q", ReasonManual, state.UnavailableReason)
+		}
+
+		if err := factory.EnableProvider("ProviderA"); err != nil {
+			t.Fatalf("failed to enable provider: %v", err)
+		}
+		if !state.IsAvailable || state.UnavailableReason != ReasonNone {
+			t.Errorf("expected provider to be enabled, got available=%v reason=%q", state.IsAvailable, state.UnavailableReason)
+		}
+
+		if err := factory.DisableProvider("Unknown"); err == nil {
+			t.Error("expected error disabling an unknown provider")
+		}
+	})
+}
+
+func TestFactory_ProcessPayment_RateLimit(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-LIMIT-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	newFactory := func(limit config.RateLimit, calls *int) *Factory {
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			*calls++
+			return httpclient.NewMockResponse(http.StatusOK, approved), nil
+		})
+		cfg := &config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {
+					Name:      "ProviderA",
+					Endpoint:  "http://provider-a.test",
+					MaxAmount: 10000,
+					RateLimit: limit,
+				},
+			},
+		}
+		return NewFactory(cfg, client)
+	}
+
+	t.Run("blocks until a token is available", func(t *testing.T) {
+		calls := 0
+		factory := newFactory(config.RateLimit{RequestsPerSecond: 20, BurstSize: 1}, &calls)
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		// The burst covers the first request; the other two wait 50ms each
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("expected requests to be throttled, took %v", elapsed)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 provider calls, got %d", calls)
+		}
+	})
+
+	t.Run("fails fast when configured", func(t *testing.T) {
+		calls := 0
+		factory := newFactory(config.RateLimit{RequestsPerSecond: 1, BurstSize: 1, FailFast: true}, &calls)
+
+		if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+		if err == nil || err.Code != domain.ErrRateLimitExceeded {
+			t.Errorf("expected %s, got %v", domain.ErrRateLimitExceeded, err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 provider call, got %d", calls)
+		}
+	})
+}
+
+func TestFactory_ProcessPayment_Cancellation(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-CANCEL-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	tests := []struct {
+		name          string
+		providerCfg   config.PaymentProviderConfig
+		status        int
+		warmUp        bool
+		expectedCalls int
+	}{
+		{
+			name: "during backoff sleep",
+			providerCfg: config.PaymentProviderConfig{
+				RetryPolicy: config.RetryPolicy{MaxAttempts: 3, InitialDelay: 5 * time.Second},
+			},
+			status:        http.StatusInternalServerError,
+			expectedCalls: 1,
+		},
+		{
+			name: "while blocked on an empty token bucket",
+			providerCfg: config.PaymentProviderConfig{
+				RateLimit: config.RateLimit{RequestsPerSecond: 1, BurstSize: 1},
+			},
+			status:        http.StatusOK,
+			warmUp:        true,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return httpclient.NewMockResponse(tt.status, approved), nil
+			})
+
+			providerCfg := tt.providerCfg
+			providerCfg.Name = "ProviderA"
+			providerCfg.Endpoint = "http://provider-a.test"
+			providerCfg.MaxAmount = 10000
+			factory := NewFactory(&config.Config{
+				Providers: map[string]config.PaymentProviderConfig{"ProviderA": providerCfg},
+			}, client)
+
+			// Drain the bucket so the next payment has to wait for a token
+			if tt.warmUp {
+				if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+					t.Fatalf("unexpected warm-up error: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := factory.ProcessPayment(ctx, "ProviderA", 100.00, "USD")
+			elapsed := time.Since(start)
+
+			if err == nil || err.Code != domain.ErrCancelled {
+				t.Errorf("expected %s, got %v", domain.ErrCancelled, err)
+			}
+			if elapsed > 200*time.Millisecond {
+				t.Errorf("expected prompt return after cancellation, took %v", elapsed)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d provider calls, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestFactory_BatchProcessPayments_Cancellation(t *testing.T) {
+	const (
+		batchSize   = 20
+		workerCount = 5
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		// Cancel the batch as soon as the first payment is sent
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-BATCH-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	factory := NewFactory(&config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}, client)
+
+	requests := make([]repository.PaymentRequest, batchSize)
+	for i := range requests {
+		requests[i] = repository.PaymentRequest{Amount: 100.00, Currency: "USD", Provider: "ProviderA"}
+	}
+
+	results := factory.BatchProcessPayments(ctx, requests)
+	if len(results) != batchSize {
+		t.Fatalf("expected %d results, got %d", batchSize, len(results))
+	}
+
+	// Only payments already picked up by a worker when the context was cancelled may be attempted
+	attempted := int(atomic.LoadInt32(&calls))
+	if attempted > workerCount {
+		t.Errorf("expected at most %d provider calls, got %d", workerCount, attempted)
+	}
+
+	cancelled := 0
+	for i, result := range results {
+		switch {
+		case result.Error == nil:
+		case result.Error.Code == domain.ErrCancelled:
+			cancelled++
+		default:
+			t.Errorf("result %d: expected success or %s, got %v", i, domain.ErrCancelled, result.Error)
+		}
+	}
+	if cancelled < batchSize-attempted {
+		t.Errorf("expected at least %d cancelled results, got %d", batchSize-attempted, cancelled)
+	}
+}
+
+func TestFactory_ProcessPayments(t *testing.T) {
+	var calls int32
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-BATCH-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+	factory := NewFactory(&config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}, client)
+	requests := []repository.PaymentRequest{
+		{Amount: 100.00, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 100.00, Currency: "USD", Provider: "ProviderX"},
+	}
+
+	t.Run("cancelled before start", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, err := factory.ProcessPayments(ctx, requests)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected a cancellation error, got %v", err)
+		}
+		if results != nil {
+			t.Errorf("expected no results, got %d", len(results))
+		}
+		if calls := atomic.LoadInt32(&calls); calls != 0 {
+			t.Errorf("expected no provider calls, got %d", calls)
+		}
+	})
+
+	t.Run("cancelled during the batch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cancelling := NewFactory(&config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			},
+		}, httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			cancel()
+			return nil, req.Context().Err()
+		}))
+		batch := make([]repository.PaymentRequest, 20)
+		for i := range batch {
+			batch[i] = requests[0]
+		}
+
+		results, err := cancelling.ProcessPayments(ctx, batch)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected a cancellation error, got %v", err)
+		}
+		if len(results) != len(batch) {
+			t.Errorf("expected %d results alongside the error, got %d", len(batch), len(results))
+		}
+	})
+
+	t.Run("nil requests", func(t *testing.T) {
+		if _, err := factory.ProcessPayments(context.Background(), nil); !errors.Is(err, ErrNilRequests) {
+			t.Errorf("expected %v, got %v", ErrNilRequests, err)
+		}
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		results, err := factory.ProcessPayments(context.Background(), []repository.PaymentRequest{})
+		if err != nil || len(results) != 0 {
+			t.Errorf("expected no results and no error, got %d results, %v", len(results), err)
+		}
+	})
+
+	t.Run("payment failures stay in the results", func(t *testing.T) {
+		results, err := factory.ProcessPayments(context.Background(), requests)
+		if err != nil {
+			t.Fatalf("unexpected batch error: %v", err)
+		}
+		if len(results) != 2 || results[0].Error != nil || results[1].Error == nil || results[1].Error.Code != domain.ErrProviderNotFound {
+			t.Errorf("expected a payment and a PROVIDER_NOT_FOUND result, got %+v", results)
+		}
+	})
+}
+
+func TestFactory_BatchProcessPayments_TightDeadline(t *testing.T) {
+	var slowCalls, fastCalls int32
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "provider-a.test" {
+			atomic.AddInt32(&slowCalls, 1)
+		} else {
+			atomic.AddInt32(&fastCalls, 1)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-DEADLINE-1",
+			"state":     "SUCCESS",
+			"value": map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "USD",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	// ProviderA needs more time than the batch has left; ProviderB fits within it
+	factory := NewFactory(&config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000, Timeout: time.Minute},
+			"ProviderB": {Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 10000, Timeout: 100 * time.Millisecond},
+		},
+	}, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	requests := []repository.PaymentRequest{
+		{Amount: 100.00, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 100.00, Currency: "USD", Provider: "ProviderB"},
+		{Amount: 100.00, Currency: "USD", Provider: "ProviderA"},
+	}
+	results := factory.BatchProcessPayments(ctx, requests)
+
+	for i, result := range results {
+		if requests[i].Provider == "ProviderA" {
+			if result.Error == nil || result.Error.Code != domain.ErrProviderTimeout {
+				t.Errorf("result %d: expected %s, got %v", i, domain.ErrProviderTimeout, result.Error)
+			}
+			continue
+		}
+		if result.Error != nil {
+			t.Errorf("result %d: unexpected error: %v", i, result.Error)
+		}
+	}
+	if calls := atomic.LoadInt32(&slowCalls); calls != 0 {
+		t.Errorf("expected no calls to the provider without enough time left, got %d", calls)
+	}
+	if calls := atomic.LoadInt32(&fastCalls); calls != 1 {
+		t.Errorf("expected 1 call to the provider that fits the deadline, got %d", calls)
+	}
+}
+
+func TestFactory_EffectiveEndpoint(t *testing.T) {
+	const override = "https://payments.provider-a.example/v1/process"
+	t.Setenv("PROVIDER_A_ENDPOINT", override)
+	t.Setenv("PROVIDER_A_SANDBOX", "false")
+
+	cfg := config.DefaultConfig()
+	cfg.LoadEnvironment()
+	factory := NewFactory(cfg, &http.Client{})
+
+	metadata := factory.GetProviderMetadata("ProviderA")
+	if metadata["endpoint"] != override {
+		t.Errorf("expected metadata endpoint %s, got %v", override, metadata["endpoint"])
+	}
+	if metadata["sandbox"] != false {
+		t.Errorf("expected metadata sandbox false, got %v", metadata["sandbox"])
+	}
+
+	// ProviderB keeps its defaults
+	if metadata := factory.GetProviderMetadata("ProviderB"); metadata["endpoint"] != config.DefaultServiceEndpoints().ProviderB || metadata["sandbox"] != true {
+		t.Errorf("expected ProviderB defaults, got %v", metadata)
+	}
+
+	for _, health := range factory.Health() {
+		if health.Name == "ProviderA" && (health.Endpoint != override || health.Sandbox) {
+			t.Errorf("expected health to report endpoint %s outside the sandbox, got %+v", override, health)
+		}
+	}
+}
+
+func TestFactory_GetProviderMetadata_LiveState(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}
+	factory := NewFactory(cfg, &http.Client{})
+
+	metadata := factory.GetProviderMetadata("ProviderA")
+	if metadata["endpoint"] != "http://provider-a.test" {
+		t.Errorf("expected static metadata to be kept, got %v", metadata)
+	}
+	if metadata["isAvailable"] != true || metadata["successCount"] != int64(0) || metadata["lastError"] != "" {
+		t.Errorf("expected a fresh available provider, got %v", metadata)
+	}
+
+	networkErr := &domain.PaymentError{Code: domain.ErrNetworkError, Message: "connection refused"}
+	factory.UpdateProviderState("ProviderA", nil)
+	for i := 0; i < defaultFailureThreshold; i++ {
+		factory.UpdateProviderState("ProviderA", networkErr)
+	}
+
+	metadata = factory.GetProviderMetadata("ProviderA")
+	expected := map[string]interface{}{
+		"isAvailable":       false,
+		"successCount":      int64(1),
+		"errorCount":        int64(defaultFailureThreshold),
+		"consecutiveErrors": defaultFailureThreshold,
+		"lastError":         networkErr.Error(),
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, metadata[key])
+		}
+	}
+
+	if metadata := factory.GetProviderMetadata("ProviderX"); metadata["error"] == nil {
+		t.Errorf("expected an error for an unknown provider, got %v", metadata)
+	}
+}
+
+func TestFactory_Metrics(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body["amount"] == 999.0 {
+			return httpclient.NewMockResponse(http.StatusBadRequest, nil), nil
+		}
+		resp, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-METRICS-1",
+			"status":         "APPROVED",
+			"amount":         body["amount"],
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, resp), nil
+	})
+
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:      "ProviderA",
+				Endpoint:  "http://provider-a.test",
+				MaxAmount: 10000,
+			},
+		},
+	}
+	registry := metrics.NewRegistry()
+	factory := NewFactory(cfg, client, WithMetricsRegistry(registry))
+
+	for _, amount := range []float64{100, 200, 999} {
+		factory.ProcessPayment(context.Background(), "ProviderA", amount, "USD")
+	}
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+
+	expected := []string{
+		`payments_processed_total{provider="ProviderA"} 3`,
+		`payments_succeeded_total{provider="ProviderA"} 2`,
+		`payments_failed_total{provider="ProviderA",code="PROVIDER_INVALID_RESPONSE"} 1`,
+		`provider_request_duration_seconds_count{provider="ProviderA"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected line %q in scraped metrics:\n%s", line, out)
+		}
+	}
+}
+
+func TestFactory_StartMetricsServer_Disabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitoring.Metrics.Enabled = false
+
+	server, err := NewFactory(cfg, &http.Client{}).StartMetricsServer()
+	if err != nil || server != nil {
+		t.Errorf("expected no metrics server when disabled, got %v, %v", server, err)
+	}
+}
+
+func TestFactory_ListProvidersDetailed(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderB": {
+				Name:      "ProviderB",
+				Endpoint:  "http://provider-b.test",
+				MaxAmount: 5000,
+				Timeout:   time.Second,
+			},
+			"ProviderA": {
+				Name:                "ProviderA",
+				Endpoint:            "http://provider-a.test",
+				MaxAmount:           10000,
+				SupportedCurrencies: []string{"USD"},
+				Timeout:             time.Second,
+			},
+		},
+		Global: config.GlobalConfig{SupportedCurrencies: []string{"USD", "EUR"}},
+	}
+	factory := NewFactory(cfg, &http.Client{})
+	if err := factory.DisableProvider("ProviderB"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []repository.ProviderInfo{
+		{Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000, SupportedCurrencies: []string{"USD"}, Available: true},
+		{Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 5000, SupportedCurrencies: []string{"USD", "EUR"}, Available: false},
+	}
+	providers := factory.ListProvidersDetailed()
+	if !reflect.DeepEqual(providers, expected) {
+		t.Errorf("expected %+v, got %+v", expected, providers)
+	}
+
+	// The list must not share slices with the configuration
+	providers[0].SupportedCurrencies[0] = "GBP"
+	if cfg.Providers["ProviderA"].SupportedCurrencies[0] != "USD" {
+		t.Error("expected the configured currencies to be left unchanged")
+	}
+}
+
+func TestFactory_Close(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transaction_id": "TXN-CLOSE-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	baseline := runtime.NumGoroutine()
+
+	cfg := config.DefaultConfig()
+	cfg.Monitoring.Metrics.Enabled = true
+	cfg.Monitoring.Metrics.Exporters = []config.ExporterConfig{{Type: "prometheus", Port: port}}
+	providerCfg := cfg.Providers["ProviderA"]
+	providerCfg.Endpoint = server.URL
+	cfg.Providers["ProviderA"] = providerCfg
+
+	factory := NewFactory(cfg, &http.Client{Transport: &http.Transport{}})
+	if _, err := factory.StartMetricsServer(); err != nil {
+		t.Fatalf("failed to start metrics server: %v", err)
+	}
+	if _, perr := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); perr != nil {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	if running := runtime.NumGoroutine(); running <= baseline {
+		t.Fatalf("expected the factory to start goroutines, got %d running with a baseline of %d", running, baseline)
+	}
+
+	if err := factory.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := factory.Close(); err != nil {
+		t.Errorf("expected a second Close to succeed, got %v", err)
+	}
+
+	// Goroutines of closed connections exit asynchronously, so sample until they are gone
+	deadline := time.Now().Add(2 * time.Second)
+	running := runtime.NumGoroutine()
+	for running > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		running = runtime.NumGoroutine()
+	}
+	if running > baseline {
+		t.Errorf("expected %d goroutines after Close, got %d", baseline, running)
+	}
+}
+
+func TestFactory_DeclineInjection(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-SIM-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	tests := []struct {
+		name        string
+		simulation  config.SimulationConfig
+		minDeclines float64
+		maxDeclines float64
+	}{
+		{
+			name:        "staging at rate 0.3",
+			simulation:  config.SimulationConfig{Staging: true, DeclineInjectionRate: 0.3},
+			minDeclines: 0.27,
+			maxDeclines: 0.33,
+		},
+		{
+			name:        "rate ignored outside staging",
+			simulation:  config.SimulationConfig{DeclineInjectionRate: 0.3},
+			minDeclines: 0,
+			maxDeclines: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return httpclient.NewMockResponse(http.StatusOK, approved), nil
+			})
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {
+						Name:      "ProviderA",
+						Endpoint:  "http://provider-a.test",
+						MaxAmount: 10000,
+					},
+				},
+				Global: config.GlobalConfig{Simulation: tt.simulation},
+			}
+			factory := NewFactory(cfg, client, WithRandSource(rand.NewSource(42)))
+
+			const total = 5000
+			declines := 0
+			for i := 0; i < total; i++ {
+				_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+				if err != nil {
+					if err.Code != domain.ErrCardDeclined {
+						t.Fatalf("expected %s, got %v", domain.ErrCardDeclined, err)
+					}
+					declines++
+				}
+			}
+
+			rate := float64(declines) / total
+			if rate < tt.minDeclines || rate > tt.maxDeclines {
+				t.Errorf("expected decline rate in [%v, %v], got %v", tt.minDeclines, tt.maxDeclines, rate)
+			}
+			// Simulated declines never reach the provider
+			if calls != total-declines {
+				t.Errorf("expected %d provider calls, got %d", total-declines, calls)
+			}
+		})
+	}
+}
+
+func TestFactory_ProcessPayment_ConnectionReset(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-RESET-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "connection reset by peer", err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if calls == 1 {
+					return nil, tt.err
+				}
+				return httpclient.NewMockResponse(http.StatusOK, approved), nil
+			})
+
+			// The policy excludes network errors, but resets must still be retried
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {
+						Name:      "ProviderA",
+						Endpoint:  "http://provider-a.test",
+						MaxAmount: 10000,
+						RetryPolicy: config.RetryPolicy{
+							MaxAttempts:     3,
+							InitialDelay:    time.Millisecond,
+							RetryableErrors: []string{domain.ErrProviderTimeout},
+							RetryableCodes:  []int{503},
+						},
+					},
+				},
+			}
+			factory := NewFactory(cfg, client)
+
+			payment, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if calls != 2 {
+				t.Errorf("expected 2 provider calls, got %d", calls)
+			}
+			if payment.RetryCount != 1 {
+				t.Errorf("expected RetryCount 1, got %d", payment.RetryCount)
+			}
+		})
+	}
+}
+
+func TestFactory_CanProcess(t *testing.T) {
+	calls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httpclient.NewMockResponse(http.StatusOK, nil), nil
+	})
+	cfg := config.DefaultConfig()
+	providerA := cfg.Providers["ProviderA"]
+	providerA.MinAmount = 1.00
+	cfg.Providers["ProviderA"] = providerA
+	factory := NewFactory(cfg, client)
+	if err := factory.DisableProvider("ProviderB"); err != nil {
+		t.Fatalf("failed to disable provider: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		req          repository.PaymentRequest
+		expectedCode string
+	}{
+		{
+			name: "acceptable request",
+			req:  repository.PaymentRequest{Provider: "ProviderA", Amount: 100.00, Currency: "USD"},
+		},
+		{
+			name:         "unsupported currency",
+			req:          repository.PaymentRequest{Provider: "ProviderA", Amount: 100.00, Currency: "JPY"},
+			expectedCode: domain.ErrInvalidCurrency,
+		},
+		{
+			name:         "over-limit amount",
+			req:          repository.PaymentRequest{Provider: "ProviderA", Amount: 10000.01, Currency: "USD"},
+			expectedCode: domain.ErrInvalidAmount,
+		},
+		{
+			name:         "below-minimum amount",
+			req:          repository.PaymentRequest{Provider: "ProviderA", Amount: 0.99, Currency: "USD"},
+			expectedCode: domain.ErrInvalidAmount,
+		},
+		{
+			name:         "unknown provider",
+			req:          repository.PaymentRequest{Provider: "ProviderX", Amount: 100.00, Currency: "USD"},
+			expectedCode: domain.ErrProviderNotFound,
+		},
+		{
+			name:         "disabled provider",
+			req:          repository.PaymentRequest{Provider: "ProviderB", Amount: 100.00, Currency: "USD"},
+			expectedCode: domain.ErrProviderUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := factory.CanProcess(tt.req)
+			if tt.expectedCode == "" {
+				if err != nil {
+					t.Errorf("expected request to be accepted, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.expectedCode {
+				t.Errorf("expected %s, got %v", tt.expectedCode, err)
+			}
+		})
+	}
+
+	if calls != 0 {
+		t.Errorf("expected no provider calls, got %d", calls)
+	}
+}
+
+func TestFactory_ProcessPayment_IdempotencyKey(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-IDEM-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+
+	var calls int32
+	var mu sync.Mutex
+	var keys []string
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		mu.Lock()
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		return httpclient.NewMockResponse(http.StatusOK, approved), nil
+	})
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:      "ProviderA",
+				Endpoint:  "http://provider-a.test",
+				MaxAmount: 10000,
+			},
+		},
+	}
+	factory := NewFactory(cfg, client)
+	ctx := domain.WithIdempotencyKey(context.Background(), "order-42")
+
+	type result struct {
+		payment *domain.Payment
+		err     *domain.PaymentError
+	}
+	firstDone := make(chan result, 1)
+	go func() {
+		payment, err := factory.ProcessPayment(ctx, "ProviderA", 100.00, "USD")
+		firstDone <- result{payment, err}
+	}()
+	<-started
+
+	// Reusing the key in flight for a different amount is a conflict
+	if _, err := factory.ProcessPayment(ctx, "ProviderA", 200.00, "USD"); err == nil || err.Code != domain.ErrDuplicateTransaction {
+		t.Errorf("expected %s, got %v", domain.ErrDuplicateTransaction, err)
+	}
+
+	// A concurrent request with the same key waits for the first one
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	second, err := factory.ProcessPayment(ctx, "ProviderA", 100.00, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error on repeated key: %v", err)
+	}
+	first := <-firstDone
+	if first.err != nil {
+		t.Fatalf("unexpected error: %v", first.err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", calls)
+	}
+	if first.payment.ID != second.ID {
+		t.Errorf("expected the in-flight payment %s, got %s", first.payment.ID, second.ID)
+	}
+	if len(keys) != 1 || keys[0] != "order-42" {
+		t.Errorf("expected Idempotency-Key header order-42, got %v", keys)
+	}
+}
+
+func TestFactory_ProcessPayment_MaintenanceWindow(t *testing.T) {
+	approved, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-MAINT-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		window        config.TimeWindow
+		expectedError bool
+		expectedCalls int
+	}{
+		{
+			name:          "window covering now",
+			window:        config.TimeWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+			expectedError: true,
+			expectedCalls: 0,
+		},
+		{
+			name:          "window in the future",
+			window:        config.TimeWindow{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return httpclient.NewMockResponse(http.StatusOK, approved), nil
+			})
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {
+						Name:               "ProviderA",
+						Endpoint:           "http://provider-a.test",
+						MaxAmount:          10000,
+						MaintenanceWindows: []config.TimeWindow{tt.window},
+					},
+				},
+			}
+			factory := NewFactory(cfg, client, WithClock(ClockFunc(func() time.Time { return now })))
+
+			_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d provider calls, got %d", tt.expectedCalls, calls)
+			}
+
+			canErr := factory.CanProcess(repository.PaymentRequest{Provider: "ProviderA", Amount: 100.00, Currency: "USD"})
+			health := factory.Health()
+
+			if !tt.expectedError {
+				if err != nil || canErr != nil {
+					t.Errorf("expected payment to be accepted, got %v / %v", err, canErr)
+				}
+				return
+			}
+
+			if err == nil || err.Code != domain.ErrProviderUnavailable || !strings.Contains(err.Message, "maintenance") {
+				t.Errorf("expected %s for maintenance, got %v", domain.ErrProviderUnavailable, err)
+			}
+			if canErr == nil || canErr.Code != domain.ErrProviderUnavailable {
+				t.Errorf("expected CanProcess to reject the provider, got %v", canErr)
+			}
+			if len(health) != 1 || health[0].Available || health[0].UnavailableReason != ReasonMaintenance {
+				t.Errorf("expected health to report maintenance, got %+v", health)
+			}
+		})
+	}
+}
+
+func TestFactory_GetProviderStateSnapshot(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {
+				Name:      "ProviderA",
+				Endpoint:  "http://provider-a.test",
+				MaxAmount: 10000,
+				Timeout:   time.Second,
+			},
+		},
+	}
+	factory := NewFactory(cfg, &http.Client{})
+
+	if _, exists := factory.GetProviderStateSnapshot("ProviderA"); exists {
+		t.Error("expected no snapshot before the provider is initialized")
+	}
+	if _, err := factory.CreateProvider("ProviderA"); err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	// Readers take snapshots while writers update the state; run with -race
+	const updates = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < updates; i++ {
+			if i%2 == 0 {
+				factory.UpdateProviderState("ProviderA", &domain.PaymentError{Code: domain.ErrNetworkError})
+			} else {
+				factory.UpdateProviderState("ProviderA", nil)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < updates; i++ {
+			snapshot, _ := factory.GetProviderStateSnapshot("ProviderA")
+			if snapshot.ConsecutiveErrs > 1 {
+				t.Errorf("inconsistent snapshot: %+v", snapshot)
+			}
+			factory.Health()
+		}
+	}()
+	wg.Wait()
+
+	snapshot, exists := factory.GetProviderStateSnapshot("ProviderA")
+	if !exists {
+		t.Fatal("expected a snapshot")
+	}
+	if snapshot.ErrorCount != updates/2 || snapshot.SuccessCount != updates/2 {
+		t.Errorf("expected %d errors and successes, got %+v", updates/2, snapshot)
+	}
+
+	// The snapshot is a copy and does not follow later updates
+	factory.UpdateProviderState("ProviderA", &domain.PaymentError{Code: domain.ErrNetworkError})
+	if snapshot.ConsecutiveErrs != 0 {
+		t.Errorf("expected snapshot to be unaffected by later updates, got %+v", snapshot)
+	}
+}
+
+func TestFactory_LatencyStability(t *testing.T) {
+	tests := []struct {
+		name             string
+		latencies        []time.Duration
+		expectedUnstable bool
+	}{
+		{
+			name:      "stable latency",
+			latencies: []time.Duration{100, 102, 99, 101, 98, 100, 103, 97, 100, 101},
+		},
+		{
+			name:             "erratic latency",
+			latencies:        []time.Duration{10, 900, 15, 850, 20, 1200, 5, 700, 30, 950},
+			expectedUnstable: true,
+		},
+		{
+			name:      "too few samples to judge",
+			latencies: []time.Duration{10, 900, 15},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {
+						Name:      "ProviderA",
+						Endpoint:  "http://provider-a.test",
+						MaxAmount: 10000,
+						Timeout:   time.Second,
+						LatencyStability: config.LatencyStabilityConfig{
+							WindowSize: 10,
+							MaxStdDev:  50 * time.Millisecond,
+						},
+					},
+				},
+			}
+			factory := NewFactory(cfg, &http.Client{})
+			if _, err := factory.CreateProvider("ProviderA"); err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+
+			for _, latency := range tt.latencies {
+				factory.recordLatency("ProviderA", latency*time.Millisecond)
+			}
+
+			snapshot, _ := factory.GetProviderStateSnapshot("ProviderA")
+			if snapshot.Unstable != tt.expectedUnstable {
+				t.Errorf("expected unstable=%v, got %v", tt.expectedUnstable, snapshot.Unstable)
+			}
+			if health := factory.Health(); health[0].Unstable != tt.expectedUnstable {
+				t.Errorf("expected health unstable=%v, got %+v", tt.expectedUnstable, health[0])
+			}
+		})
+	}
+}
+
+func TestFactory_AvgLatency(t *testing.T) {
+	t.Run("moving average of recorded latencies", func(t *testing.T) {
+		factory := NewFactory(&config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000, Timeout: time.Second},
+			},
+		}, &http.Client{})
+		if _, err := factory.CreateProvider("ProviderA"); err != nil {
+			t.Fatalf("failed to create provider: %v", err)
+		}
+
+		// The first sample seeds the average; later ones move it by latencyEMAWeight
+		expected := []time.Duration{100 * time.Millisecond, 120 * time.Millisecond, 96 * time.Millisecond}
+		for i, latency := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 0} {
+			factory.recordLatency("ProviderA", latency)
+			snapshot, _ := factory.GetProviderStateSnapshot("ProviderA")
+			if snapshot.AvgLatency != expected[i] {
+				t.Errorf("sample %d: expected average %v, got %v", i+1, expected[i], snapshot.AvgLatency)
+			}
+		}
+	})
+
+	t.Run("measured around provider calls", func(t *testing.T) {
+		var delay int64
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			time.Sleep(time.Duration(atomic.LoadInt64(&delay)))
+			body, _ := json.Marshal(map[string]interface{}{
+				"transaction_id": "TXN-LATENCY-1",
+				"status":         "APPROVED",
+				"amount":         100.00,
+				"currency":       "USD",
+				"timestamp":      "2024-01-15T10:30:00Z",
+			})
+			return httpclient.NewMockResponse(http.StatusOK, body), nil
+		})
+		factory := NewFactory(&config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			},
+		}, client)
+
+		if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fast, _ := factory.GetProviderStateSnapshot("ProviderA")
+
+		const slow = 20 * time.Millisecond
+		atomic.StoreInt64(&delay, int64(slow))
+		previous := fast.AvgLatency
+		for i := 0; i < 10; i++ {
+			if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			snapshot, _ := factory.GetProviderStateSnapshot("ProviderA")
+			if snapshot.AvgLatency <= previous {
+				t.Fatalf("payment %d: expected the average to grow towards %v, got %v after %v", i+1, slow, snapshot.AvgLatency, previous)
+			}
+			previous = snapshot.AvgLatency
+		}
+		if previous < slow/2 {
+			t.Errorf("expected the average to approach %v, got %v", slow, previous)
+		}
+		if metadata := factory.GetProviderMetadata("ProviderA"); metadata["avgLatency"] != previous {
+			t.Errorf("expected avgLatency %v in metadata, got %v", previous, metadata["avgLatency"])
+		}
+	})
+}
+
+func TestFactory_ProviderCurrenciesFallBackToGlobal(t *testing.T) {
+	calls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httpclient.NewMockResponse(http.StatusOK, nil), nil
+	})
+	cfg := config.DefaultConfig()
+	cfg.Global.SupportedCurrencies = []string{"USD"}
+	factory := NewFactory(cfg, client)
+
+	if err := factory.CanProcess(repository.PaymentRequest{Provider: "ProviderA", Amount: 100.00, Currency: "EUR"}); err == nil || err.Code != domain.ErrInvalidCurrency {
+		t.Errorf("expected %s from CanProcess, got %v", domain.ErrInvalidCurrency, err)
+	}
+	if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "EUR"); err == nil || err.Code != domain.ErrInvalidCurrency {
+		t.Errorf("expected %s from ProcessPayment, got %v", domain.ErrInvalidCurrency, err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no provider calls, got %d", calls)
+	}
+}
+
+func TestFactory_ProcessPaymentRequest(t *testing.T) {
+	tests := []struct {
+		name              string
+		provider          string
+		endpoint          string
+		response          map[string]interface{}
+		referenceField    string
+		expectedReference string
+	}{
+		{
+			name:     "ProviderA",
+			provider: "ProviderA",
+			endpoint: "http://provider-a.test",
+			response: map[string]interface{}{
+				"transaction_id": "TXN-REQ-1",
+				"status":         "APPROVED",
+				"amount":         100.00,
+				"currency":       "USD",
+				"timestamp":      "2024-01-15T10:30:00Z",
+			},
+			referenceField: "reference_id",
+		},
+		{
+			name:     "ProviderB",
+			provider: "ProviderB",
+			endpoint: "http://provider-b.test",
+			response: map[string]interface{}{
+				"paymentId":   "PAY-REQ-1",
+				"state":       "SUCCESS",
+				"value":       map[string]interface{}{"amount": "100.00", "currencyCode": "USD"},
+				"processedAt": 1705318200000,
+			},
+			referenceField: "referenceId",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			var key string
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				key = req.Header.Get("Idempotency-Key")
+				json.NewDecoder(req.Body).Decode(&sent)
+				body, _ := json.Marshal(tt.response)
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					tt.provider: {Name: tt.provider, Endpoint: tt.endpoint, MaxAmount: 10000},
+				},
+			}
+			factory := NewFactory(cfg, client)
+
+			_, err := factory.ProcessPaymentRequest(context.Background(), repository.PaymentRequest{
+				Provider:       tt.provider,
+				Amount:         100.00,
+				Currency:       "USD",
+				IdempotencyKey: "order-7",
+				ReferenceID:    "INV-7",
+				Metadata:       map[string]string{"customer": "C-1"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != "order-7" {
+				t.Errorf("expected Idempotency-Key order-7, got %q", key)
+			}
+			if sent[tt.referenceField] != "INV-7" {
+				t.Errorf("expected %s INV-7 in request body, got %v", tt.referenceField, sent)
+			}
+			metadata, _ := sent["metadata"].(map[string]interface{})
+			if metadata["customer"] != "C-1" {
+				t.Errorf("expected metadata customer C-1 in request body, got %v", sent["metadata"])
+			}
+		})
+	}
+
+	t.Run("ProcessPayment sends no reference or metadata", func(t *testing.T) {
+		var sent map[string]interface{}
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			body, _ := json.Marshal(tests[0].response)
+			return httpclient.NewMockResponse(http.StatusOK, body), nil
+		})
+		factory := NewFactory(config.DefaultConfig(), client)
+
+		if _, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, exists := sent["reference_id"]; exists {
+			t.Errorf("expected no reference_id in request body, got %v", sent)
+		}
+		if _, exists := sent["metadata"]; exists {
+			t.Errorf("expected no metadata in request body, got %v", sent)
+		}
+	})
+}
+
+func TestFactory_UpdateProviderState_FailureThreshold(t *testing.T) {
+	networkErr := &domain.PaymentError{Code: domain.ErrNetworkError}
+
+	tests := []struct {
+		name              string
+		threshold         int
+		errors            int
+		expectedAvailable bool
+	}{
+		{name: "default threshold not reached", errors: 2, expectedAvailable: true},
+		{name: "default threshold reached", errors: 3, expectedAvailable: false},
+		{name: "configured threshold not reached", threshold: 5, errors: 4, expectedAvailable: true},
+		{name: "configured threshold reached", threshold: 5, errors: 5, expectedAvailable: false},
+		{name: "threshold of two not reached", threshold: 2, errors: 1, expectedAvailable: true},
+		{name: "threshold of two reached", threshold: 2, errors: 2, expectedAvailable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Global: config.GlobalConfig{
+					CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: tt.threshold},
+				},
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+				},
+			}
+			factory := NewFactory(cfg, &http.Client{})
+
+			// No CreateProvider call: the state is created by the first report
+			for i := 0; i < tt.errors; i++ {
+				factory.UpdateProviderState("ProviderA", networkErr)
+			}
+
+			snapshot, exists := factory.GetProviderStateSnapshot("ProviderA")
+			if !exists {
+				t.Fatal("expected provider state to be created")
+			}
+			if snapshot.IsAvailable != tt.expectedAvailable {
+				t.Errorf("expected IsAvailable %v after %d errors, got %v", tt.expectedAvailable, tt.errors, snapshot.IsAvailable)
+			}
+			if snapshot.ConsecutiveErrs != tt.errors {
+				t.Errorf("expected %d consecutive errors, got %d", tt.errors, snapshot.ConsecutiveErrs)
+			}
+		})
+	}
+
+	t.Run("unknown provider is ignored", func(t *testing.T) {
+		factory := NewFactory(&config.Config{}, &http.Client{})
+		factory.UpdateProviderState("ProviderX", networkErr)
+		if _, exists := factory.GetProviderStateSnapshot("ProviderX"); exists {
+			t.Error("expected no state for an unknown provider")
+		}
+	})
+}
+
+func TestFactory_CircuitBreakerReset(t *testing.T) {
+	networkErr := &domain.PaymentError{Code: domain.ErrNetworkError}
+	newFactory := func(resetTimeout time.Duration) (*Factory, *FakeClock) {
+		cfg := &config.Config{
+			Global: config.GlobalConfig{
+				CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 2, ResetTimeout: resetTimeout},
+			},
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			},
+		}
+		clock := NewFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+		factory := NewFactory(cfg, &http.Client{}, WithClock(clock))
+		for i := 0; i < 2; i++ {
+			factory.UpdateProviderState("ProviderA", networkErr)
+		}
+		return factory, clock
+	}
+
+	t.Run("available again after the reset timeout", func(t *testing.T) {
+		factory, clock := newFactory(time.Minute)
+		clock.Advance(59 * time.Second)
+		if factory.IsProviderAvailable("ProviderA") {
+			t.Fatal("expected the provider to stay unavailable before the reset timeout")
+		}
+		clock.Advance(time.Second)
+		if health := factory.Health(); len(health) != 1 || !health[0].Available || health[0].UnavailableReason != ReasonNone {
+			t.Errorf("expected health to report the provider available, got %+v", health)
+		}
+		if !factory.IsProviderAvailable("ProviderA") {
+			t.Fatal("expected the provider to be available once the reset timeout passed")
+		}
+	})
+
+	t.Run("failed probe reopens the circuit", func(t *testing.T) {
+		factory, clock := newFactory(time.Minute)
+		clock.Advance(time.Minute)
+		if !factory.IsProviderAvailable("ProviderA") {
+			t.Fatal("expected the provider to be available once the reset timeout passed")
+		}
+
+		factory.UpdateProviderState("ProviderA", networkErr)
+		if factory.IsProviderAvailable("ProviderA") {
+			t.Fatal("expected a single failed probe to mark the provider unavailable")
+		}
+		clock.Advance(time.Minute)
+		factory.UpdateProviderState("ProviderA", nil)
+		if snapshot, _ := factory.GetProviderStateSnapshot("ProviderA"); !snapshot.IsAvailable || snapshot.ConsecutiveErrs != 0 {
+			t.Errorf("expected a successful probe to close the circuit, got %+v", snapshot)
+		}
+	})
+
+	t.Run("no reset timeout", func(t *testing.T) {
+		factory, clock := newFactory(0)
+		clock.Advance(24 * time.Hour)
+		if factory.IsProviderAvailable("ProviderA") {
+			t.Error("expected the provider to stay unavailable without a reset timeout")
+		}
+	})
+
+	t.Run("manual disable is not reset", func(t *testing.T) {
+		factory, clock := newFactory(time.Minute)
+		if err := factory.DisableProvider("ProviderA"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.Advance(time.Hour)
+		if factory.IsProviderAvailable("ProviderA") {
+			t.Error("expected a manually disabled provider to stay unavailable")
+		}
+	})
+}
+
+func TestFactory_SuccessRate(t *testing.T) {
+	networkErr := &domain.PaymentError{Code: domain.ErrNetworkError}
+
+	tests := []struct {
+		name          string
+		successes     int
+		errors        int
+		expectedRate  float64
+		expectedTotal int64
+	}{
+		{name: "no requests", expectedRate: 0, expectedTotal: 0},
+		{name: "all succeeded", successes: 4, expectedRate: 1, expectedTotal: 4},
+		{name: "mixed", successes: 3, errors: 1, expectedRate: 0.75, expectedTotal: 4},
+		{name: "all failed", errors: 2, expectedRate: 0, expectedTotal: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Global: config.GlobalConfig{
+					CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 10},
+				},
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000, Timeout: time.Second},
+				},
+			}
+			factory := NewFactory(cfg, &http.Client{})
+			if _, err := factory.CreateProvider("ProviderA"); err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+
+			for i := 0; i < tt.successes; i++ {
+				factory.UpdateProviderState("ProviderA", nil)
+			}
+			for i := 0; i < tt.errors; i++ {
+				factory.UpdateProviderState("ProviderA", networkErr)
+			}
+
+			rate, total := factory.SuccessRate("ProviderA")
+			if rate != tt.expectedRate {
+				t.Errorf("expected success rate %v, got %v", tt.expectedRate, rate)
+			}
+			if total != tt.expectedTotal {
+				t.Errorf("expected %d total requests, got %d", tt.expectedTotal, total)
+			}
+		})
+	}
+
+	t.Run("concurrent updates", func(t *testing.T) {
+		cfg := &config.Config{
+			Global: config.GlobalConfig{
+				CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 1000},
+			},
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			},
+		}
+		factory := NewFactory(cfg, &http.Client{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				factory.UpdateProviderState("ProviderA", nil)
+			}()
+			go func() {
+				defer wg.Done()
+				factory.SuccessRate("ProviderA")
+			}()
+		}
+		wg.Wait()
+
+		if rate, total := factory.SuccessRate("ProviderA"); rate != 1 || total != 50 {
+			t.Errorf("expected rate 1 over 50 requests, got %v over %d", rate, total)
+		}
+	})
+}
+
+func TestFactory_ProcessPaymentRequest_LogsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	previous := logger.ErrorLogger.Writer()
+	logger.ErrorLogger.SetOutput(&buf)
+	defer logger.ErrorLogger.SetOutput(previous)
+
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusBadRequest, []byte(`{}`)), nil
+	})
+	factory := NewFactory(&config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}, client)
+
+	_, err := factory.ProcessPaymentRequest(context.Background(), repository.PaymentRequest{
+		Amount:    100,
+		Currency:  "USD",
+		Provider:  "ProviderA",
+		RequestID: "a1b2c3d4",
+	})
+	if err == nil {
+		t.Fatal("expected the payment to fail")
+	}
+	if !strings.Contains(buf.String(), "[req=a1b2c3d4]") {
+		t.Errorf("expected error logs to be tagged with the request ID, got:\n%s", buf.String())
+	}
+}
+
+func TestFactory_ProcessPayment_ResponseSizeLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxSize       string
+		padding       int
+		expectedError string
+	}{
+		{name: "within the limit", maxSize: "1KB", padding: 100},
+		{name: "oversized response", maxSize: "1KB", padding: 2048, expectedError: domain.ErrProviderInvalidResp},
+		{name: "no limit", maxSize: "", padding: 2048},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]interface{}{
+					"transaction_id": "TXN-SIZE-1",
+					"status":         "APPROVED",
+					"amount":         100.00,
+					"currency":       "USD",
+					"timestamp":      "2024-01-15T10:30:00Z",
+					"padding":        strings.Repeat("x", tt.padding),
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+
+			cfg := &config.Config{
+				Global: config.GlobalConfig{MaxRequestSize: tt.maxSize},
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+				},
+			}
+			factory := NewFactory(cfg, client)
+
+			_, err := factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.expectedError {
+				t.Fatalf("expected %s, got %v", tt.expectedError, err)
+			}
+			if !strings.Contains(err.Message, "1024 bytes") {
+				t.Errorf("expected the limit in the message, got %q", err.Message)
+			}
+		})
+	}
+}
+
+func TestFactory_ConcurrentProviderCreation(t *testing.T) {
+	registerStub.Do(func() {
+		Register("ProviderStub", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+			return &stubProvider{name: cfg.Name}
+		})
+	})
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderStub": {Name: "ProviderStub", Endpoint: "http://stub", MaxAmount: 1000, Timeout: time.Second},
+		},
+	}
+
+	// Middleware wraps each provider once, as it is created
+	var created int32
+	counting := func(provider repository.PaymentProvider) repository.PaymentProvider {
+		atomic.AddInt32(&created, 1)
+		return provider
+	}
+	factory := NewFactory(cfg, &http.Client{}, WithProviderMiddleware(counting))
+
+	// Payments and explicit creation race for the first use; run with -race
+	const callers = 100
+	var wg sync.WaitGroup
+	instances := make([]repository.PaymentProvider, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%10 == 0 {
+				provider, err := factory.CreateProvider("ProviderStub")
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				instances[i] = provider
+				return
+			}
+			if _, err := factory.ProcessPayment(context.Background(), "ProviderStub", 10, "USD"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&created); got != 1 {
+		t.Errorf("expected exactly one provider instance to be created, got %d", got)
+	}
+	factory.mutex.RLock()
+	providers := len(factory.providers)
+	provider := factory.providers["ProviderStub"]
+	factory.mutex.RUnlock()
+	if providers != 1 {
+		t.Errorf("expected a single provider, got %d", providers)
+	}
+	for i, instance := range instances {
+		if instance != nil && instance != provider {
+			t.Errorf("caller %d got a different provider instance", i)
+		}
+	}
+}
+
+func TestFactory_ClockReachesProviders(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			MockProviderName: {Name: MockProviderName, Endpoint: "mock://", MaxAmount: 10000, Timeout: time.Second},
+		},
+	}
+	factory := NewFactory(cfg, &http.Client{}, WithClock(NewFakeClock(now)))
+
+	payment, err := factory.ProcessPayment(context.Background(), MockProviderName, 10, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !payment.Timestamp.Equal(now) {
+		t.Errorf("expected the payment timestamped by the factory clock at %v, got %v", now, payment.Timestamp)
+	}
+}
+
+func TestFactory_ProcessPayment_OpenCircuit(t *testing.T) {
+	registerStub.Do(func() {
+		Register("ProviderStub", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+			return &stubProvider{name: cfg.Name}
+		})
+	})
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 2, ResetTimeout: time.Minute},
+		},
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderStub": {Name: "ProviderStub", Endpoint: "http://stub", MaxAmount: 1000},
+		},
+	}
+	var calls []string
+	clock := NewFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	factory := NewFactory(cfg, &http.Client{}, WithClock(clock), WithProviderMiddleware(recordingMiddleware("stub", &calls)))
+	for i := 0; i < 2; i++ {
+		factory.UpdateProviderState("ProviderStub", &domain.PaymentError{Code: domain.ErrNetworkError})
+	}
+
+	_, err := factory.ProcessPayment(context.Background(), "ProviderStub", 10, "USD")
+	if err == nil || err.Code != domain.ErrProviderUnavailable {
+		t.Fatalf("expected %s while the circuit is open, got %v", domain.ErrProviderUnavailable, err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected no payment sent while the circuit is open, got %d", len(calls))
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := factory.ProcessPayment(context.Background(), "ProviderStub", 10, "USD"); err != nil {
+		t.Fatalf("expected the payment to probe the provider after the reset timeout, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected one payment sent after the reset timeout, got %d", len(calls))
+	}
+}
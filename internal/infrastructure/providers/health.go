@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/logger"
+)
+
+// ProviderHealth is the health report of a single provider. Endpoint and Sandbox
+// reflect the configuration in effect after environment overrides.
+type ProviderHealth struct {
+	Name              string            `json:"name"`
+	Endpoint          string            `json:"endpoint"`
+	Sandbox           bool              `json:"sandbox"`
+	Available         bool              `json:"available"`
+	UnavailableReason UnavailableReason `json:"unavailable_reason,omitempty"`
+	ConsecutiveErrors int               `json:"consecutive_errors"`
+	TotalRequests     int64             `json:"total_requests"`
+	SuccessRate       float64           `json:"success_rate"`
+	Unstable          bool              `json:"unstable"`
+	LastError         string            `json:"last_error,omitempty"`
+	LastChecked       time.Time         `json:"last_checked"`
+}
+
+// Health returns the health of every provider that has been initialized, sorted by name
+func (f *Factory) Health() []ProviderHealth {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	health := make([]ProviderHealth, 0, len(f.providerStates))
+	for name, state := range f.providerStates {
+		snapshot := f.currentSnapshot(state)
+		cfg := f.config.Providers[name]
+		entry := ProviderHealth{
+			Name:              name,
+			Endpoint:          cfg.Endpoint,
+			Sandbox:           cfg.Sandbox,
+			Available:         snapshot.IsAvailable,
+			UnavailableReason: snapshot.UnavailableReason,
+			ConsecutiveErrors: snapshot.ConsecutiveErrs,
+			TotalRequests:     state.TotalRequests(),
+			SuccessRate:       state.SuccessRate(),
+			Unstable:          snapshot.Unstable,
+			LastChecked:       snapshot.LastChecked,
+		}
+		if snapshot.LastError != nil {
+			entry.LastError = snapshot.LastError.Error()
+		}
+		if entry.Available && f.inMaintenance(name) {
+			entry.Available = false
+			entry.UnavailableReason = ReasonMaintenance
+		}
+		health = append(health, entry)
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Name < health[j].Name
+	})
+	return health
+}
+
+// DisableProvider manually marks a provider as unavailable. Payments to a manually
+// disabled provider are rejected until EnableProvider is called.
+func (f *Factory) DisableProvider(name string) error {
+	state, err := f.stateFor(name)
+	if err != nil {
+		return err
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.LastChecked = f.clock.Now()
+	state.markUnavailable(ReasonManual)
+	logger.Info("Provider %s disabled manually", name)
+	return nil
+}
+
+// EnableProvider clears a manual disable and marks the provider as available again
+func (f *Factory) EnableProvider(name string) error {
+	state, err := f.stateFor(name)
+	if err != nil {
+		return err
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.LastChecked = f.clock.Now()
+	state.ConsecutiveErrs = 0
+	state.IsAvailable = true
+	state.UnavailableReason = ReasonNone
+	logger.Info("Provider %s enabled manually", name)
+	return nil
+}
+
+// stateFor returns the state of a configured provider, initializing it if needed
+func (f *Factory) stateFor(name string) (*ProviderState, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.config.Providers[name]; !exists {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderNotFound,
+			Message: fmt.Sprintf("Provider %s not found", name),
+		}
+	}
+
+	state, exists := f.providerStates[name]
+	if !exists {
+		state = &ProviderState{
+			IsAvailable: true,
+			LastChecked: f.clock.Now(),
+		}
+		f.providerStates[name] = state
+	}
+	return state, nil
+}
+
+// unavailableError returns the error for a payment to a provider that is currently
+// unavailable, because it was disabled manually or its circuit breaker is open, or nil
+// when payments may be sent to it
+func (f *Factory) unavailableError(name string) *domain.PaymentError {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	switch snapshot := f.currentSnapshot(state); snapshot.UnavailableReason {
+	case ReasonManual:
+		return &domain.PaymentError{
+			Code:      domain.ErrProviderUnavailable,
+			Message:   fmt.Sprintf("Provider %s has been disabled", name),
+			Provider:  name,
+			Retryable: false,
+		}
+	case ReasonConsecutiveErrors:
+		return &domain.PaymentError{
+			Code:      domain.ErrProviderUnavailable,
+			Message:   fmt.Sprintf("Provider %s is unavailable after %d consecutive errors", name, snapshot.ConsecutiveErrs),
+			Provider:  name,
+			Retryable: false,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/logger"
+)
+
+// transactionIDPlaceholder is replaced by the escaped transaction ID in operation paths
+const transactionIDPlaceholder = "{id}"
+
+// Default requests of the provider operations, used for unset OperationConfig fields
+var (
+	defaultProcessOperation = config.OperationConfig{Method: http.MethodPost}
+	defaultStatusOperation  = config.OperationConfig{Method: http.MethodGet, Path: "/{id}"}
+	defaultRefundOperation  = config.OperationConfig{Method: http.MethodPost, Path: "/{id}/refund"}
+	defaultCancelOperation  = config.OperationConfig{Method: http.MethodPost, Path: "/{id}/cancel"}
+)
+
+// operationRequest returns the HTTP method and URL of a provider operation, e.g. POST
+// <endpoint>/<transactionID>/refund. The path of op, or of defaults when unset, is
+// appended to endpoint with {id} replaced by transactionID; an empty path leaves the
+// endpoint as it is.
+func operationRequest(endpoint string, op, defaults config.OperationConfig, transactionID string) (string, string) {
+	method := strings.ToUpper(op.Method)
+	if method == "" {
+		method = defaults.Method
+	}
+	path := op.Path
+	if path == "" {
+		path = defaults.Path
+	}
+	if path == "" {
+		return method, endpoint
+	}
+	path = strings.ReplaceAll(path, transactionIDPlaceholder, url.PathEscape(transactionID))
+	return method, strings.TrimRight(endpoint, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// statusRequest returns the HTTP method and URL of a status query for transactionID: a
+// GET of the poll URL carried by ctx, for payments accepted asynchronously and not yet
+// given an ID, or else the provider's status operation
+func statusRequest(ctx context.Context, cfg config.PaymentProviderConfig, transactionID string) (string, string) {
+	if pollURL := domain.PollURLFromContext(ctx); pollURL != "" {
+		return http.MethodGet, pollURL
+	}
+	return operationRequest(cfg.Endpoint, cfg.Operations.Status, defaultStatusOperation, transactionID)
+}
+
+// decodeResponse unmarshals a provider response body. In strict mode unknown fields
+// are rejected so that unexpected changes to the provider API surface as errors.
+func decodeResponse(body []byte, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// providerRawData decodes a provider response body for Payment.ProviderRawData. Numbers
+// are kept as json.Number so the data serializes back exactly as the provider sent it.
+// It returns nil when omit is set or the body is not JSON.
+func providerRawData(body []byte, omit bool) interface{} {
+	if omit {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil
+	}
+	return raw
+}
+
+// responseStatus parses a payment status reported by a provider, mapping an unknown
+// status to ErrProviderInvalidResp
+func responseStatus(providerName, status string) (domain.PaymentStatus, *domain.PaymentError) {
+	parsed, err := domain.ParsePaymentStatus(status)
+	if err != nil {
+		return "", (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid payment status: " + status,
+			Provider:  providerName,
+			Retryable: false,
+		}).WithCause(err)
+	}
+	return parsed, nil
+}
+
+// responseCurrency parses a currency code reported by a provider, mapping a missing
+// code or one outside the provider's supported currencies to ErrProviderInvalidResp
+func responseCurrency(cfg config.PaymentProviderConfig, code string) (domain.Currency, *domain.PaymentError) {
+	currency, err := domain.ParseCurrency(code, cfg.SupportedCurrencies)
+	if err != nil {
+		return "", (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   fmt.Sprintf("Invalid currency in response: %q", code),
+			Provider:  cfg.Name,
+			Retryable: false,
+		}).WithCause(err)
+	}
+	return currency, nil
+}
+
+// currencyMismatchError reports a payment the provider recorded in another currency
+// than requested. It returns nil when the currencies match, or when cfg allows a
+// mismatch.
+func currencyMismatchError(ctx context.Context, cfg config.PaymentProviderConfig, payment *domain.Payment, requested string) *domain.PaymentError {
+	if cfg.AllowCurrencyMismatch || strings.EqualFold(string(payment.Currency), requested) {
+		return nil
+	}
+	logger.WithContext(ctx).Error("[%s] Currency mismatch: requested %s, provider reported %s", cfg.Name, requested, payment.Currency)
+	return &domain.PaymentError{
+		Code:      domain.ErrProviderInvalidResp,
+		Message:   fmt.Sprintf("Provider reported currency %s for a payment in %s", payment.Currency, requested),
+		Provider:  cfg.Name,
+		Retryable: false,
+	}
+}
+
+// setAuthorization adds the provider's credentials to req, if it has any
+func setAuthorization(req *http.Request, auth config.AuthConfig) {
+	switch auth.Type {
+	case config.AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case config.AuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// withProviderTimeout bounds ctx by the provider's configured timeout. A deadline the
+// caller already set on ctx is kept when it is sooner, and a zero timeout leaves the
+// caller's deadline as the only bound. The returned cancel function must always be called.
+func withProviderTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if callerDeadline, ok := ctx.Deadline(); ok && callerDeadline.Before(deadline) {
+		deadline = callerDeadline
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// transportError maps an error returned by the HTTP client to a payment error. Every
+// provider reports transport failures through it, with one policy: timeouts, meaning an
+// expired deadline or any net.Error whose Timeout() is true, are ErrProviderTimeout;
+// any other failure to connect or send, connection resets included, is ErrNetworkError.
+// All of them are retryable.
+func transportError(providerName string, err error) *domain.PaymentError {
+	code := domain.ErrNetworkError
+	if isTimeout(err) {
+		code = domain.ErrProviderTimeout
+	}
+	return (&domain.PaymentError{
+		Code:      code,
+		Message:   "Failed to send request: " + err.Error(),
+		Provider:  providerName,
+		Retryable: true,
+		Details:   err.Error(),
+	}).WithCause(err)
+}
+
+// isTimeout reports whether the request failed because a deadline or client timeout expired
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isConnectionReset reports whether the connection was dropped by the peer mid-request
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isAuthFailure reports whether the provider rejected our credentials
+func isAuthFailure(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// authenticationError reports a 401/403 from a provider. Retrying cannot help, and the
+// failure usually means the credentials are wrong or expired, so it is logged loudly.
+func authenticationError(ctx context.Context, providerName string, statusCode int) *domain.PaymentError {
+	logger.WithContext(ctx).Error("[%s] AUTHENTICATION FAILED with HTTP %d: check the credentials configured for this provider", providerName, statusCode)
+	return &domain.PaymentError{
+		Code:       domain.ErrAuthenticationFailed,
+		Message:    fmt.Sprintf("Authentication failed: %d", statusCode),
+		Provider:   providerName,
+		Retryable:  false,
+		HTTPStatus: statusCode,
+	}
+}
+
+// MapHTTPStatusToError maps a non-2xx provider response status to a payment error code
+// and whether the request is worth retrying. Statuses below 400 map to an empty code.
+func MapHTTPStatusToError(status int) (code string, retryable bool) {
+	switch {
+	case status < http.StatusBadRequest:
+		return "", false
+	case isAuthFailure(status):
+		return domain.ErrAuthenticationFailed, false
+	case status == http.StatusTooManyRequests:
+		return domain.ErrRateLimitExceeded, true
+	case status >= http.StatusInternalServerError:
+		return domain.ErrProviderUnavailable, true
+	default:
+		return domain.ErrProviderInvalidResp, false
+	}
+}
+
+// statusError builds the payment error for a non-2xx provider response, or returns
+// nil when the status is not an error
+func statusError(ctx context.Context, providerName string, status int) *domain.PaymentError {
+	code, retryable := MapHTTPStatusToError(status)
+	var message string
+	switch code {
+	case "":
+		return nil
+	case domain.ErrAuthenticationFailed:
+		return authenticationError(ctx, providerName, status)
+	case domain.ErrRateLimitExceeded:
+		message = "Rate limit exceeded"
+	case domain.ErrProviderUnavailable:
+		message = fmt.Sprintf("Provider error: %d", status)
+	default:
+		message = fmt.Sprintf("Invalid request: %d", status)
+	}
+	logger.WithContext(ctx).Error("[%s] %s", providerName, message)
+	return &domain.PaymentError{
+		Code:       code,
+		Message:    message,
+		Provider:   providerName,
+		Retryable:  retryable,
+		HTTPStatus: status,
+	}
+}
+
+// readResponseBody reads a provider response body of at most limit bytes. A longer
+// body is reported as ErrProviderInvalidResp; a limit of zero reads any size.
+func readResponseBody(ctx context.Context, providerName string, body io.Reader, limit int64) ([]byte, *domain.PaymentError) {
+	if limit > 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		logger.WithContext(ctx).Error("[%s] Failed to read response body: %v", providerName, err)
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrInternalError,
+			Message:   "Failed to read response body: " + err.Error(),
+			Provider:  providerName,
+			Retryable: true,
+			Details:   err.Error(),
+		}).WithCause(err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		logger.WithContext(ctx).Error("[%s] Response body exceeds the limit of %d bytes", providerName, limit)
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   fmt.Sprintf("Response body exceeds the limit of %d bytes", limit),
+			Provider:  providerName,
+			Retryable: false,
+		}
+	}
+	return data, nil
+}
+
+// callProvider sends a JSON request with the provider's credentials and returns the raw
+// response body, read up to the provider's MaxResponseSize. Transport failures and
+// non-2xx responses are mapped to payment errors.
+func callProvider(ctx context.Context, client *http.Client, cfg config.PaymentProviderConfig, method, endpoint string, payload interface{}) ([]byte, *domain.PaymentError) {
+	providerName := cfg.Name
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			logger.WithContext(ctx).Error("[%s] Failed to marshal request body: %v", providerName, err)
+			return nil, (&domain.PaymentError{
+				Code:      domain.ErrInternalError,
+				Message:   "Failed to marshal request body: " + err.Error(),
+				Provider:  providerName,
+				Retryable: false,
+			}).WithCause(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		logger.WithContext(ctx).Error("[%s] Failed to create request: %v", providerName, err)
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrInternalError,
+			Message:   "Failed to create request: " + err.Error(),
+			Provider:  providerName,
+			Retryable: false,
+		}).WithCause(err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setAuthorization(req, cfg.Auth)
+
+	logger.WithContext(ctx).Debug("[%s] Sending %s request to %s", providerName, method, endpoint)
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.WithContext(ctx).Error("[%s] Request failed: %v", providerName, err)
+		return nil, transportError(providerName, err)
+	}
+	defer resp.Body.Close()
+
+	logger.WithContext(ctx).Debug("[%s] Received response with status code: %d", providerName, resp.StatusCode)
+	// Operations on an existing transaction report an unknown ID as 404
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &domain.PaymentError{
+			Code:       domain.ErrTransactionNotFound,
+			Message:    "Transaction not found",
+			Provider:   providerName,
+			Retryable:  false,
+			HTTPStatus: resp.StatusCode,
+		}
+	}
+	if perr := statusError(ctx, providerName, resp.StatusCode); perr != nil {
+		return nil, perr
+	}
+
+	return readResponseBody(ctx, providerName, resp.Body, cfg.MaxResponseSize)
+}
@@ -0,0 +1,342 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/httpclient"
+)
+
+func TestWithProviderTimeout(t *testing.T) {
+	tests := []struct {
+		name            string
+		callerTimeout   time.Duration
+		providerTimeout time.Duration
+		expected        time.Duration
+		expectDeadline  bool
+	}{
+		{
+			name:            "caller deadline sooner than provider timeout",
+			callerTimeout:   50 * time.Millisecond,
+			providerTimeout: 30 * time.Second,
+			expected:        50 * time.Millisecond,
+			expectDeadline:  true,
+		},
+		{
+			name:            "provider timeout sooner than caller deadline",
+			callerTimeout:   time.Minute,
+			providerTimeout: 100 * time.Millisecond,
+			expected:        100 * time.Millisecond,
+			expectDeadline:  true,
+		},
+		{
+			name:            "provider timeout without caller deadline",
+			providerTimeout: 100 * time.Millisecond,
+			expected:        100 * time.Millisecond,
+			expectDeadline:  true,
+		},
+		{
+			name: "no deadline at all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			if tt.callerTimeout > 0 {
+				var cancel context.CancelFunc
+				parent, cancel = context.WithTimeout(parent, tt.callerTimeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			ctx, cancel := withProviderTimeout(parent, tt.providerTimeout)
+			deadline, ok := ctx.Deadline()
+			if ok != tt.expectDeadline {
+				t.Fatalf("expected deadline=%v, got %v", tt.expectDeadline, ok)
+			}
+			if ok {
+				if got := deadline.Sub(start); got < tt.expected-10*time.Millisecond || got > tt.expected+10*time.Millisecond {
+					t.Errorf("expected deadline about %v from now, got %v", tt.expected, got)
+				}
+			}
+
+			// Cancelling the derived context releases it without affecting the caller's
+			cancel()
+			if ctx.Err() != context.Canceled {
+				t.Errorf("expected derived context to be cancelled, got %v", ctx.Err())
+			}
+			if parent.Err() != nil {
+				t.Errorf("expected caller context to be unaffected, got %v", parent.Err())
+			}
+		})
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode string
+	}{
+		{
+			name:         "context deadline",
+			err:          &url.Error{Op: "Post", URL: "http://provider", Err: context.DeadlineExceeded},
+			expectedCode: domain.ErrProviderTimeout,
+		},
+		{
+			name:         "client timeout",
+			err:          &url.Error{Op: "Post", URL: "http://provider", Err: &httpclient.TimeoutError{}},
+			expectedCode: domain.ErrProviderTimeout,
+		},
+		{
+			name:         "connection reset",
+			err:          &url.Error{Op: "Post", URL: "http://provider", Err: syscall.ECONNRESET},
+			expectedCode: domain.ErrNetworkError,
+		},
+		{
+			name:         "other network failure",
+			err:          &url.Error{Op: "Post", URL: "http://provider", Err: errors.New("connection refused")},
+			expectedCode: domain.ErrNetworkError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentErr := transportError("ProviderA", tt.err)
+			if paymentErr.Code != tt.expectedCode {
+				t.Errorf("expected %s, got %s", tt.expectedCode, paymentErr.Code)
+			}
+			if !paymentErr.Retryable {
+				t.Error("expected transport errors to be retryable")
+			}
+			if !errors.Is(paymentErr, tt.err) {
+				t.Error("expected the transport error to be wrapped")
+			}
+		})
+	}
+}
+
+func TestMapHTTPStatusToError(t *testing.T) {
+	tests := []struct {
+		status            int
+		expectedCode      string
+		expectedRetryable bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusAccepted},
+		{status: http.StatusFound},
+		{status: http.StatusBadRequest, expectedCode: domain.ErrProviderInvalidResp},
+		{status: http.StatusUnauthorized, expectedCode: domain.ErrAuthenticationFailed},
+		{status: http.StatusForbidden, expectedCode: domain.ErrAuthenticationFailed},
+		{status: http.StatusNotFound, expectedCode: domain.ErrProviderInvalidResp},
+		{status: http.StatusConflict, expectedCode: domain.ErrProviderInvalidResp},
+		{status: http.StatusUnprocessableEntity, expectedCode: domain.ErrProviderInvalidResp},
+		{status: http.StatusTooManyRequests, expectedCode: domain.ErrRateLimitExceeded, expectedRetryable: true},
+		{status: 499, expectedCode: domain.ErrProviderInvalidResp},
+		{status: http.StatusInternalServerError, expectedCode: domain.ErrProviderUnavailable, expectedRetryable: true},
+		{status: http.StatusBadGateway, expectedCode: domain.ErrProviderUnavailable, expectedRetryable: true},
+		{status: http.StatusServiceUnavailable, expectedCode: domain.ErrProviderUnavailable, expectedRetryable: true},
+		{status: http.StatusGatewayTimeout, expectedCode: domain.ErrProviderUnavailable, expectedRetryable: true},
+		{status: 599, expectedCode: domain.ErrProviderUnavailable, expectedRetryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			code, retryable := MapHTTPStatusToError(tt.status)
+			if code != tt.expectedCode || retryable != tt.expectedRetryable {
+				t.Errorf("status %d: expected (%q, %v), got (%q, %v)",
+					tt.status, tt.expectedCode, tt.expectedRetryable, code, retryable)
+			}
+		})
+	}
+}
+
+func TestProviders_TransportErrorPolicy(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	dialTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: &httpclient.TimeoutError{}}
+
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode string
+	}{
+		{name: "client timeout", err: &httpclient.TimeoutError{}, expectedCode: domain.ErrProviderTimeout},
+		{name: "dial timeout", err: dialTimeout, expectedCode: domain.ErrProviderTimeout},
+		{name: "connection refused", err: dialErr, expectedCode: domain.ErrNetworkError},
+	}
+
+	for _, tt := range tests {
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, tt.err
+		})
+		providers := []repository.PaymentProvider{
+			NewProviderA(config.PaymentProviderConfig{Name: "ProviderA", Endpoint: "http://test-provider-a.com", MaxAmount: 10000}, client),
+			NewProviderB(config.PaymentProviderConfig{Name: "ProviderB", Endpoint: "http://test-provider-b.com/payments", MaxAmount: 10000}, client),
+		}
+		for _, provider := range providers {
+			t.Run(provider.Name()+"/"+tt.name, func(t *testing.T) {
+				_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+				if err == nil || err.Code != tt.expectedCode {
+					t.Fatalf("expected %s, got %v", tt.expectedCode, err)
+				}
+				if !err.Retryable {
+					t.Error("expected transport errors to be retryable")
+				}
+			})
+		}
+	}
+}
+
+func TestProviders_OperationRequests(t *testing.T) {
+	operations := config.OperationsConfig{
+		Process: config.OperationConfig{Path: "/v2/charges"},
+		Status:  config.OperationConfig{Path: "/v2/charges/{id}"},
+		Refund:  config.OperationConfig{Method: "put", Path: "v2/charges/{id}/refunds"},
+		Cancel:  config.OperationConfig{Method: http.MethodDelete, Path: "/v2/charges/{id}"},
+	}
+
+	tests := []struct {
+		name       string
+		operations config.OperationsConfig
+		call       func(provider repository.PaymentProvider)
+		expected   string
+	}{
+		{
+			name:     "default process",
+			call:     func(p repository.PaymentProvider) { p.ProcessPayment(context.Background(), 100.00, "USD") },
+			expected: "POST http://provider.test/api/",
+		},
+		{
+			name:     "default status",
+			call:     func(p repository.PaymentProvider) { p.GetPaymentStatus(context.Background(), "TXN 1") },
+			expected: "GET http://provider.test/api/TXN%201",
+		},
+		{
+			name:     "default refund",
+			call:     func(p repository.PaymentProvider) { p.RefundPayment(context.Background(), "TXN-1", 10) },
+			expected: "POST http://provider.test/api/TXN-1/refund",
+		},
+		{
+			name:     "default cancel",
+			call:     func(p repository.PaymentProvider) { p.CancelPayment(context.Background(), "TXN-1") },
+			expected: "POST http://provider.test/api/TXN-1/cancel",
+		},
+		{
+			name:       "configured process",
+			operations: operations,
+			call:       func(p repository.PaymentProvider) { p.ProcessPayment(context.Background(), 100.00, "USD") },
+			expected:   "POST http://provider.test/api/v2/charges",
+		},
+		{
+			name:       "configured status",
+			operations: operations,
+			call:       func(p repository.PaymentProvider) { p.GetPaymentStatus(context.Background(), "TXN-1") },
+			expected:   "GET http://provider.test/api/v2/charges/TXN-1",
+		},
+		{
+			name:       "status of an accepted payment",
+			operations: operations,
+			call: func(p repository.PaymentProvider) {
+				p.GetPaymentStatus(domain.WithPollURL(context.Background(), "http://provider.test/async/ASYNC-1"), "")
+			},
+			expected: "GET http://provider.test/async/ASYNC-1",
+		},
+		{
+			name:       "configured refund",
+			operations: operations,
+			call:       func(p repository.PaymentProvider) { p.RefundPayment(context.Background(), "TXN-1", 10) },
+			expected:   "PUT http://provider.test/api/v2/charges/TXN-1/refunds",
+		},
+		{
+			name:       "configured cancel",
+			operations: operations,
+			call:       func(p repository.PaymentProvider) { p.CancelPayment(context.Background(), "TXN-1") },
+			expected:   "DELETE http://provider.test/api/v2/charges/TXN-1",
+		},
+	}
+
+	for _, tt := range tests {
+		var requested string
+		client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+			requested = req.Method + " " + req.URL.String()
+			return nil, errors.New("request recorded")
+		})
+		cfg := config.PaymentProviderConfig{Endpoint: "http://provider.test/api/", MaxAmount: 10000, Operations: tt.operations}
+		cfgA, cfgB := cfg, cfg
+		cfgA.Name, cfgB.Name = "ProviderA", "ProviderB"
+		providers := []repository.PaymentProvider{NewProviderA(cfgA, client), NewProviderB(cfgB, client)}
+		for _, provider := range providers {
+			t.Run(provider.Name()+"/"+tt.name, func(t *testing.T) {
+				requested = ""
+				tt.call(provider)
+				if requested != tt.expected {
+					t.Errorf("expected request %q, got %q", tt.expected, requested)
+				}
+			})
+		}
+	}
+}
+
+func TestProviders_CurrencyMismatch(t *testing.T) {
+	// responses are approvals of 100 in the given currency, keyed by provider
+	responses := map[string]func(currency string) map[string]interface{}{
+		"ProviderA": func(currency string) map[string]interface{} {
+			return map[string]interface{}{"transaction_id": "TXN-1", "status": "APPROVED", "amount": 100.00, "currency": currency, "timestamp": "2024-01-15T10:30:00Z"}
+		},
+		"ProviderB": func(currency string) map[string]interface{} {
+			return map[string]interface{}{"paymentId": "PAY-1", "state": "SUCCESS", "value": map[string]string{"amount": "100.00", "currencyCode": currency}, "processedAt": 1705315800000}
+		},
+	}
+
+	tests := []struct {
+		name          string
+		reported      string
+		allowMismatch bool
+		expectedCode  string
+	}{
+		{name: "matching currency", reported: "USD"},
+		{name: "matching currency in lowercase", reported: "usd"},
+		{name: "mismatching currency", reported: "EUR", expectedCode: domain.ErrProviderInvalidResp},
+		{name: "mismatch allowed", reported: "EUR", allowMismatch: true},
+	}
+
+	for _, tt := range tests {
+		for _, name := range []string{"ProviderA", "ProviderB"} {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+					body, _ := json.Marshal(responses[name](tt.reported))
+					return httpclient.NewMockResponse(http.StatusOK, body), nil
+				})
+				cfg := config.PaymentProviderConfig{Name: name, Endpoint: "http://provider.test", MaxAmount: 10000, AllowCurrencyMismatch: tt.allowMismatch}
+				provider := map[string]repository.PaymentProvider{"ProviderA": NewProviderA(cfg, client), "ProviderB": NewProviderB(cfg, client)}[name]
+
+				payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+				if tt.expectedCode != "" {
+					if err == nil || err.Code != tt.expectedCode {
+						t.Fatalf("expected %s, got %v", tt.expectedCode, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !strings.EqualFold(string(payment.Currency), tt.reported) {
+					t.Errorf("expected the reported currency %s, got %s", tt.reported, payment.Currency)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/logger"
+)
+
+// idempotentResult is a payment in flight under an idempotency key.
+// done is closed once payment and err are set.
+type idempotentResult struct {
+	amount   float64
+	currency string
+	done     chan struct{}
+	payment  *domain.Payment
+	err      *domain.PaymentError
+}
+
+// processIdempotent makes concurrent payments sharing an idempotency key a single
+// provider call: a request arriving while another one with its key is in flight waits
+// for it and returns its result. Reusing a key in flight for a different amount or
+// currency fails with ErrDuplicateTransaction. Completed payments are not remembered
+// here; the use case checks them against its TransactionStore, and the provider
+// receives the key as the Idempotency-Key header.
+func (f *Factory) processIdempotent(ctx context.Context, key string, providerName string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	f.idempotencyMutex.Lock()
+	entry, inFlight := f.idempotency[key]
+	if inFlight {
+		f.idempotencyMutex.Unlock()
+		if conflict := idempotencyConflict(key, entry.amount, entry.currency, amount, currency, providerName); conflict != nil {
+			logger.WithContext(ctx).Error("Idempotency key %s reused for a different payment", key)
+			return nil, conflict
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, cancelledError(providerName, ctx.Err())
+		}
+		logger.WithContext(ctx).Info("Returning result of concurrent request for idempotency key %s", key)
+		return entry.payment, entry.err
+	}
+
+	entry = &idempotentResult{
+		amount:   amount,
+		currency: currency,
+		done:     make(chan struct{}),
+	}
+	f.idempotency[key] = entry
+	f.idempotencyMutex.Unlock()
+
+	entry.payment, entry.err = f.processPayment(ctx, providerName, amount, currency)
+
+	f.idempotencyMutex.Lock()
+	delete(f.idempotency, key)
+	f.idempotencyMutex.Unlock()
+	close(entry.done)
+	return entry.payment, entry.err
+}
+
+// idempotencyConflict returns ErrDuplicateTransaction when a payment of amount in
+// currency reuses a key already used for a different amount or currency
+func idempotencyConflict(key string, usedAmount float64, usedCurrency string, amount float64, currency string, providerName string) *domain.PaymentError {
+	if domain.ToMinorUnits(usedAmount) == domain.ToMinorUnits(amount) && strings.EqualFold(usedCurrency, currency) {
+		return nil
+	}
+	return &domain.PaymentError{
+		Code:     domain.ErrDuplicateTransaction,
+		Message:  fmt.Sprintf("Idempotency key %s was already used for %.2f %s", key, usedAmount, usedCurrency),
+		Provider: providerName,
+	}
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/logger"
+	"yuno_assesment/pkg/metrics"
+)
+
+// paymentMetrics holds the metrics recorded by the factory
+type paymentMetrics struct {
+	registry  *metrics.Registry
+	processed *metrics.CounterVec
+	succeeded *metrics.CounterVec
+	failed    *metrics.CounterVec
+	latency   *metrics.HistogramVec
+
+	retryBudget *metrics.GaugeVec
+}
+
+// newPaymentMetrics registers the payment metrics in registry
+func newPaymentMetrics(registry *metrics.Registry) *paymentMetrics {
+	return &paymentMetrics{
+		registry:  registry,
+		processed: registry.Counter("payments_processed_total", "Payments processed, by provider.", "provider"),
+		succeeded: registry.Counter("payments_succeeded_total", "Payments that succeeded, by provider.", "provider"),
+		failed:    registry.Counter("payments_failed_total", "Payments that failed, by provider and error code.", "provider", "code"),
+		latency:   registry.Histogram("provider_request_duration_seconds", "Latency of provider requests in seconds.", metrics.DefaultBuckets, "provider"),
+
+		retryBudget: registry.Gauge("provider_retry_budget_remaining", "Retries left in the retry budget, by provider.", "provider"),
+	}
+}
+
+// observeLatency records the duration of a single provider request
+func (m *paymentMetrics) observeLatency(providerName string, duration time.Duration) {
+	m.latency.Observe(duration.Seconds(), providerName)
+}
+
+// recordResult counts the final outcome of a payment
+func (m *paymentMetrics) recordResult(providerName string, err *domain.PaymentError) {
+	m.processed.Inc(providerName)
+	if err != nil {
+		m.failed.Inc(providerName, err.Code)
+		return
+	}
+	m.succeeded.Inc(providerName)
+}
+
+// MetricsRegistry returns the registry the factory records its metrics in
+func (f *Factory) MetricsRegistry() *metrics.Registry {
+	return f.metrics.registry
+}
+
+// StartMetricsServer serves the factory's metrics in Prometheus text format on the port
+// of the configured prometheus exporter until the factory is closed. It returns a nil
+// server when metrics are disabled or no prometheus exporter is configured.
+func (f *Factory) StartMetricsServer() (*http.Server, error) {
+	metricsCfg := f.config.Monitoring.Metrics
+	if !metricsCfg.Enabled {
+		return nil, nil
+	}
+
+	port := 0
+	for _, exporter := range metricsCfg.Exporters {
+		if exporter.Type == "prometheus" {
+			port = exporter.Port
+			break
+		}
+	}
+	if port == 0 {
+		logger.Info("Metrics enabled but no prometheus exporter configured")
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on metrics port %d: %w", port, err)
+	}
+
+	mux := http.NewServeMux()
+	registryHandler := f.metrics.registry.Handler()
+	mux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.refreshRetryBudgets()
+		registryHandler.ServeHTTP(w, r)
+	}))
+	server := &http.Server{Handler: mux}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server stopped: %v", err)
+		}
+	}()
+
+	f.mutex.Lock()
+	f.metricsServer, f.metricsDone = server, done
+	f.mutex.Unlock()
+	logger.Info("Serving metrics on port %d", port)
+	return server, nil
+}
@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// Middleware wraps a provider in another provider that adds behaviour, such as logging,
+// around its calls. Retries and payment metrics are handled by the factory itself.
+type Middleware func(repository.PaymentProvider) repository.PaymentProvider
+
+// Chain wraps provider in middleware. The first middleware is the outermost, so it
+// sees every call first and every result last.
+func Chain(provider repository.PaymentProvider, middleware ...Middleware) repository.PaymentProvider {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		provider = middleware[i](provider)
+	}
+	return provider
+}
+
+// WithProviderMiddleware wraps every provider the factory creates in middleware, the
+// first one outermost
+func WithProviderMiddleware(middleware ...Middleware) FactoryOption {
+	return func(f *Factory) {
+		f.middleware = append(f.middleware, middleware...)
+	}
+}
+
+// Provider operations, as used in log lines and metric labels
+const (
+	operationProcess = "process"
+	operationRefund  = "refund"
+	operationStatus  = "status"
+	operationCancel  = "cancel"
+)
+
+// loggingProvider logs the calls made to a provider
+type loggingProvider struct {
+	repository.PaymentProvider
+	clock Clock
+}
+
+// WithLogging logs every call made to provider with its outcome and duration: failures
+// as errors, successes at debug level. The factory already logs failed payments, so
+// this is meant for providers used on their own.
+func WithLogging(provider repository.PaymentProvider) repository.PaymentProvider {
+	return &loggingProvider{PaymentProvider: provider, clock: realClock{}}
+}
+
+// setClock makes the middleware time calls with clock, and hands clock on to the
+// provider it wraps
+func (p *loggingProvider) setClock(clock Clock) {
+	p.clock = clock
+	if setter, ok := p.PaymentProvider.(clockSetter); ok {
+		setter.setClock(clock)
+	}
+}
+
+// log writes the outcome of a call of operation that started at start
+func (p *loggingProvider) log(ctx context.Context, operation string, start time.Time, payment *domain.Payment, err *domain.PaymentError) {
+	elapsed := p.clock.Now().Sub(start)
+	if err != nil {
+		logger.WithContext(ctx).Error("[%s] %s failed after %v: %v", p.Name(), operation, elapsed, err)
+		return
+	}
+	logger.WithContext(ctx).Debug("[%s] %s of %s succeeded in %v: status=%s", p.Name(), operation, payment.ID, elapsed, payment.Status)
+}
+
+// ProcessPayment processes the payment and logs the call
+func (p *loggingProvider) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	start := p.clock.Now()
+	payment, err := p.PaymentProvider.ProcessPayment(ctx, amount, currency)
+	p.log(ctx, operationProcess, start, payment, err)
+	return payment, err
+}
+
+// RefundPayment refunds the payment and logs the call
+func (p *loggingProvider) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	start := p.clock.Now()
+	payment, err := p.PaymentProvider.RefundPayment(ctx, transactionID, amount)
+	p.log(ctx, operationRefund, start, payment, err)
+	return payment, err
+}
+
+// GetPaymentStatus queries the payment status and logs the call
+func (p *loggingProvider) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	start := p.clock.Now()
+	payment, err := p.PaymentProvider.GetPaymentStatus(ctx, transactionID)
+	p.log(ctx, operationStatus, start, payment, err)
+	return payment, err
+}
+
+// CancelPayment cancels the payment and logs the call
+func (p *loggingProvider) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	start := p.clock.Now()
+	payment, err := p.PaymentProvider.CancelPayment(ctx, transactionID)
+	p.log(ctx, operationCancel, start, payment, err)
+	return payment, err
+}
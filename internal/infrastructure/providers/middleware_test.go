@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// recordingMiddleware appends name to calls whenever a payment is processed through it
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(provider repository.PaymentProvider) repository.PaymentProvider {
+		return &recordingProvider{PaymentProvider: provider, name: name, calls: calls}
+	}
+}
+
+type recordingProvider struct {
+	repository.PaymentProvider
+	name  string
+	calls *[]string
+}
+
+func (p *recordingProvider) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	*p.calls = append(*p.calls, p.name)
+	return p.PaymentProvider.ProcessPayment(ctx, amount, currency)
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	provider := Chain(&stubProvider{name: "ProviderStub"}, recordingMiddleware("outer", &calls), WithLogging, recordingMiddleware("inner", &calls))
+
+	payment, err := provider.ProcessPayment(context.Background(), 10, "USD")
+	if err != nil || payment.ID != "STUB-1" {
+		t.Fatalf("expected the stub payment, got %v, %v", payment, err)
+	}
+	if strings.Join(calls, ",") != "outer,inner" {
+		t.Errorf("expected the first middleware outermost, got %v", calls)
+	}
+	if provider.Name() != "ProviderStub" {
+		t.Errorf("expected the wrapped provider's name, got %s", provider.Name())
+	}
+}
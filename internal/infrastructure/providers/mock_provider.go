@@ -0,0 +1,266 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// MockProviderName is the name the mock provider is registered under
+const MockProviderName = "Mock"
+
+func init() {
+	Register(MockProviderName, func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+		return NewMockProvider(cfg)
+	})
+}
+
+// MockProvider is an in-process payment provider for tests and demos. It never makes
+// HTTP calls; outcomes are driven by the amount rules, latency and error rate in
+// its config.Mock settings.
+type MockProvider struct {
+	config config.PaymentProviderConfig
+	clock  Clock
+
+	mutex    sync.Mutex
+	random   *rand.Rand
+	sequence int
+	payments map[string]*domain.Payment
+	refunded map[string]float64
+}
+
+// NewMockProvider creates a new mock provider
+func NewMockProvider(cfg config.PaymentProviderConfig) *MockProvider {
+	if cfg.Name == "" {
+		cfg.Name = MockProviderName
+	}
+	return &MockProvider{
+		config:   cfg,
+		clock:    realClock{},
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		payments: make(map[string]*domain.Payment),
+		refunded: make(map[string]float64),
+	}
+}
+
+// setClock makes the provider read the time from clock
+func (p *MockProvider) setClock(clock Clock) {
+	p.clock = clock
+}
+
+// Name returns the provider name
+func (p *MockProvider) Name() string {
+	return p.config.Name
+}
+
+// GetMetadata returns provider metadata
+func (p *MockProvider) GetMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        p.config.Name,
+		"sandbox":     true,
+		"timeout":     p.config.Timeout.String(),
+		"maxAmount":   p.config.MaxAmount,
+		"description": p.config.Description,
+		"latency":     p.config.Mock.Latency.String(),
+		"errorRate":   p.config.Mock.ErrorRate,
+	}
+}
+
+// ProcessPayment applies the configured rules to the payment
+func (p *MockProvider) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	ctx, span := startProviderSpan(ctx, "Mock.ProcessPayment", p.Name(), amount, currency)
+	payment, err := p.processPayment(ctx, amount, currency)
+	endSpan(span, err)
+	return payment, err
+}
+
+// processPayment simulates the provider call and decides its outcome
+func (p *MockProvider) processPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[%s] Processing payment request: amount=%.2f, currency=%s", p.Name(), amount, currency)
+
+	if err := ctx.Err(); err != nil {
+		return nil, cancelledError(p.Name(), err)
+	}
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	if err := p.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	outcome, errorCode := config.MockApprove, ""
+	for _, rule := range p.config.Mock.Rules {
+		if rule.Matches(amount) {
+			outcome, errorCode = rule.Outcome, rule.ErrorCode
+			break
+		}
+	}
+
+	switch outcome {
+	case config.MockDecline:
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrCardDeclined,
+			Message:   "Payment declined by mock provider",
+			Provider:  p.Name(),
+			Retryable: false,
+		}
+	case config.MockError:
+		return nil, p.unavailableError(errorCode)
+	}
+
+	payment := &domain.Payment{
+		ID:        p.nextID(),
+		Amount:    amount,
+		Currency:  domain.Currency(currency),
+		Status:    domain.StatusApproved,
+		Provider:  p.Name(),
+		Timestamp: p.clock.Now(),
+	}
+	p.mutex.Lock()
+	p.payments[payment.ID] = payment
+	p.mutex.Unlock()
+	return payment, nil
+}
+
+// RefundPayment refunds a payment previously approved by this mock provider, up to
+// the amount not refunded yet
+func (p *MockProvider) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	if err := p.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	original, exists := p.payments[transactionID]
+	if !exists {
+		p.mutex.Unlock()
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrTransactionNotFound,
+			Message:  "Transaction not found",
+			Provider: p.Name(),
+		}
+	}
+	if remaining := original.Amount - p.refunded[transactionID]; amount > remaining {
+		p.mutex.Unlock()
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrInvalidAmount,
+			Message:  fmt.Sprintf("Refund amount %.2f exceeds refundable amount %.2f", amount, remaining),
+			Provider: p.Name(),
+		}
+	}
+	p.refunded[transactionID] += amount
+	p.mutex.Unlock()
+
+	return &domain.Payment{
+		ID:            p.nextID(),
+		Amount:        amount,
+		Currency:      original.Currency,
+		Status:        domain.StatusRefunded,
+		Provider:      p.Name(),
+		Timestamp:     p.clock.Now(),
+		TransactionID: transactionID,
+	}, nil
+}
+
+// CancelPayment marks a payment previously processed by this mock provider as cancelled
+func (p *MockProvider) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	if err := p.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	payment, exists := p.payments[transactionID]
+	if !exists {
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrTransactionNotFound,
+			Message:  "Transaction not found",
+			Provider: p.Name(),
+		}
+	}
+	payment.Status = domain.StatusCancelled
+	cancelled := *payment
+	cancelled.TransactionID = transactionID
+	return &cancelled, nil
+}
+
+// GetPaymentStatus returns a payment previously approved by this mock provider
+func (p *MockProvider) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	if err := p.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	payment, exists := p.payments[transactionID]
+	if !exists {
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrTransactionNotFound,
+			Message:  "Transaction not found",
+			Provider: p.Name(),
+		}
+	}
+	copied := *payment
+	return &copied, nil
+}
+
+// simulateCall waits for the configured latency and injects random failures
+func (p *MockProvider) simulateCall(ctx context.Context) *domain.PaymentError {
+	if latency := p.config.Mock.Latency; latency > 0 {
+		if err := sleepContext(ctx, latency); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return transportError(p.Name(), err)
+			}
+			return cancelledError(p.Name(), err)
+		}
+	}
+
+	if rate := p.config.Mock.ErrorRate; rate > 0 {
+		p.mutex.Lock()
+		fail := p.random.Float64() < rate
+		p.mutex.Unlock()
+		if fail {
+			return p.unavailableError("")
+		}
+	}
+	return nil
+}
+
+// unavailableError builds the error returned for MockError outcomes and injected failures
+func (p *MockProvider) unavailableError(code string) *domain.PaymentError {
+	if code == "" {
+		code = domain.ErrProviderUnavailable
+	}
+	return &domain.PaymentError{
+		Code:      code,
+		Message:   "Simulated failure from mock provider",
+		Provider:  p.Name(),
+		Retryable: code == domain.ErrProviderUnavailable,
+	}
+}
+
+// nextID returns a unique transaction ID
+func (p *MockProvider) nextID() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.sequence++
+	return fmt.Sprintf("MOCK-%d", p.sequence)
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+func TestMockProvider_ProcessPayment(t *testing.T) {
+	mockCfg := config.MockConfig{
+		Rules: []config.MockRule{
+			{MinAmount: 999, MaxAmount: 999, Outcome: config.MockDecline},
+			{MinAmount: 5000, Outcome: config.MockError},
+			{MinAmount: 13, MaxAmount: 13, Outcome: config.MockError, ErrorCode: domain.ErrInsufficientFunds},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		amount        float64
+		expectedError string
+		retryable     bool
+	}{
+		{name: "approved when no rule matches", amount: 100},
+		{name: "decline rule", amount: 999, expectedError: domain.ErrCardDeclined},
+		{name: "open-ended error rule", amount: 7500, expectedError: domain.ErrProviderUnavailable, retryable: true},
+		{name: "error rule with code", amount: 13, expectedError: domain.ErrInsufficientFunds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewMockProvider(config.PaymentProviderConfig{Mock: mockCfg})
+
+			payment, err := provider.ProcessPayment(context.Background(), tt.amount, "USD")
+			if tt.expectedError != "" {
+				if err == nil || err.Code != tt.expectedError {
+					t.Fatalf("expected %s, got %v", tt.expectedError, err)
+				}
+				if err.Retryable != tt.retryable {
+					t.Errorf("expected retryable=%v, got %v", tt.retryable, err.Retryable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Status != domain.StatusApproved || payment.Provider != MockProviderName {
+				t.Errorf("expected approved payment from %s, got %+v", MockProviderName, payment)
+			}
+
+			status, err := provider.GetPaymentStatus(context.Background(), payment.ID)
+			if err != nil || status.ID != payment.ID {
+				t.Errorf("expected status of %s, got %+v, %v", payment.ID, status, err)
+			}
+		})
+	}
+}
+
+func TestMockProvider_RefundPayment(t *testing.T) {
+	provider := NewMockProvider(config.PaymentProviderConfig{})
+	payment, err := provider.ProcessPayment(context.Background(), 100, "USD")
+	if err != nil {
+		t.Fatalf("unexpected payment error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		transactionID string
+		amount        float64
+		expectedError string
+	}{
+		{name: "unknown transaction", transactionID: "MOCK-UNKNOWN", amount: 10, expectedError: domain.ErrTransactionNotFound},
+		{name: "partial refund", transactionID: payment.ID, amount: 40},
+		{name: "more than is left", transactionID: payment.ID, amount: 75, expectedError: domain.ErrInvalidAmount},
+		{name: "the rest", transactionID: payment.ID, amount: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refund, err := provider.RefundPayment(context.Background(), tt.transactionID, tt.amount)
+			if tt.expectedError != "" {
+				if err == nil || err.Code != tt.expectedError {
+					t.Errorf("expected %s, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if refund.Status != domain.StatusRefunded || refund.Amount != tt.amount {
+				t.Errorf("expected a refund of %.2f, got %+v", tt.amount, refund)
+			}
+		})
+	}
+}
+
+func TestMockProvider_Latency(t *testing.T) {
+	provider := NewMockProvider(config.PaymentProviderConfig{
+		Timeout: 20 * time.Millisecond,
+		Mock:    config.MockConfig{Latency: time.Second},
+	})
+
+	start := time.Now()
+	_, err := provider.ProcessPayment(context.Background(), 100, "USD")
+	if err == nil || err.Code != domain.ErrProviderTimeout {
+		t.Errorf("expected %s, got %v", domain.ErrProviderTimeout, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the provider timeout to cut the latency short, took %v", elapsed)
+	}
+}
+
+func TestMockProvider_ThroughFactory(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			MockProviderName: {
+				Name:        MockProviderName,
+				Endpoint:    "mock://local",
+				MaxAmount:   10000,
+				RetryPolicy: config.RetryPolicy{MaxAttempts: 1},
+				Mock:        config.MockConfig{ErrorRate: 1},
+			},
+		},
+	}
+	// The mock never touches the HTTP client
+	factory := NewFactory(cfg, &http.Client{})
+
+	for i := 0; i < 3; i++ {
+		_, err := factory.ProcessPayment(context.Background(), MockProviderName, 100, "USD")
+		if err == nil || err.Code != domain.ErrProviderUnavailable {
+			t.Fatalf("payment %d: expected %s, got %v", i, domain.ErrProviderUnavailable, err)
+		}
+	}
+
+	// Consecutive injected failures take the provider out of rotation
+	snapshot, ok := factory.GetProviderStateSnapshot(MockProviderName)
+	if !ok || snapshot.IsAvailable {
+		t.Errorf("expected %s to be unavailable after repeated failures, got %+v", MockProviderName, snapshot)
+	}
+}
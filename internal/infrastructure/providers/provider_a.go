@@ -5,19 +5,44 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/pkg/logger"
 )
 
+// providerARequest is the body of a ProviderA payment request
+type providerARequest struct {
+	Amount      float64           `json:"amount"`
+	Currency    string            `json:"currency"`
+	ReferenceID string            `json:"reference_id,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// newProviderARequest builds the body of a ProviderA payment request
+func newProviderARequest(amount float64, currency string, details domain.PaymentDetails) providerARequest {
+	return providerARequest{
+		Amount:      amount,
+		Currency:    currency,
+		ReferenceID: details.ReferenceID,
+		Metadata:    details.Metadata,
+	}
+}
+
 // ProviderA implements the payment provider interface for Provider A
 type ProviderA struct {
 	config     config.PaymentProviderConfig
 	httpClient *http.Client
+	clock      Clock
+}
+
+func init() {
+	Register("ProviderA", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+		return NewProviderA(cfg, client)
+	})
 }
 
 // NewProviderA creates a new instance of Provider A
@@ -25,9 +50,15 @@ func NewProviderA(config config.PaymentProviderConfig, client *http.Client) *Pro
 	return &ProviderA{
 		config:     config,
 		httpClient: client,
+		clock:      realClock{},
 	}
 }
 
+// setClock makes the provider read the time from clock
+func (p *ProviderA) setClock(clock Clock) {
+	p.clock = clock
+}
+
 // Name returns the provider name
 func (p *ProviderA) Name() string {
 	return p.config.Name
@@ -38,8 +69,9 @@ func (p *ProviderA) GetMetadata() map[string]interface{} {
 	return map[string]interface{}{
 		"name":        p.config.Name,
 		"endpoint":    p.config.Endpoint,
+		"sandbox":     p.config.Sandbox,
 		"timeout":     p.config.Timeout.String(),
-		"retryCount":  p.config.RetryCount,
+		"maxAttempts": p.config.RetryPolicy.MaxAttempts,
 		"maxAmount":   p.config.MaxAmount,
 		"description": p.config.Description,
 	}
@@ -47,11 +79,28 @@ func (p *ProviderA) GetMetadata() map[string]interface{} {
 
 // ProcessPayment processes a payment through Provider A
 func (p *ProviderA) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
-	logger.Debug("[ProviderA] Processing payment request: amount=%.2f, currency=%s", amount, currency)
+	ctx, span := startProviderSpan(ctx, "ProviderA.ProcessPayment", p.Name(), amount, currency)
+	payment, err := p.processPayment(ctx, amount, currency)
+	endSpan(span, err)
+	return payment, err
+}
+
+// processPayment validates the payment, sends it to Provider A and parses the response
+func (p *ProviderA) processPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderA] Processing payment request: amount=%.2f, currency=%s", amount, currency)
+
+	if err := ctx.Err(); err != nil {
+		logger.WithContext(ctx).Error("[ProviderA] Context already done, not sending payment: %v", err)
+		return nil, cancelledError(p.Name(), err)
+	}
+
+	// Bound the call by the provider's own timeout, independent of the shared client
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
 
 	// Validate input
 	if amount <= 0 {
-		logger.Error("[ProviderA] Invalid amount: %.2f", amount)
+		logger.WithContext(ctx).Error("[ProviderA] Invalid amount: %.2f", amount)
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrInvalidAmount,
 			Message:   "Amount must be greater than 0",
@@ -59,17 +108,26 @@ func (p *ProviderA) ProcessPayment(ctx context.Context, amount float64, currency
 			Retryable: false,
 		}
 	}
-	if amount > p.config.MaxAmount {
-		logger.Error("[ProviderA] Amount %.2f exceeds maximum limit of %.2f", amount, p.config.MaxAmount)
+	if amount < p.config.MinAmount {
+		logger.WithContext(ctx).Error("[ProviderA] Amount %.2f is below minimum limit of %.2f", amount, p.config.MinAmount)
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrInvalidAmount,
-			Message:   fmt.Sprintf("Amount exceeds maximum limit of %v", p.config.MaxAmount),
+			Message:   fmt.Sprintf("Amount is below minimum limit of %v", p.config.MinAmount),
 			Provider:  p.Name(),
 			Retryable: false,
 		}
 	}
-	if currency == "" || (currency != string(domain.USD) && currency != string(domain.EUR) && currency != string(domain.GBP)) {
-		logger.Error("[ProviderA] Invalid or unsupported currency: %s", currency)
+	if maxAmount := p.config.MaxAmountFor(currency); amount > maxAmount {
+		logger.WithContext(ctx).Error("[ProviderA] Amount %.2f exceeds maximum limit of %.2f %s", amount, maxAmount, currency)
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrInvalidAmount,
+			Message:   fmt.Sprintf("Amount exceeds maximum limit of %v %s", maxAmount, currency),
+			Provider:  p.Name(),
+			Retryable: false,
+		}
+	}
+	if !currencyAllowed(p.config.SupportedCurrencies, currency) {
+		logger.WithContext(ctx).Error("[ProviderA] Invalid or unsupported currency: %s", currency)
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrInvalidCurrency,
 			Message:   "Invalid or unsupported currency",
@@ -78,92 +136,85 @@ func (p *ProviderA) ProcessPayment(ctx context.Context, amount float64, currency
 		}
 	}
 
-	logger.Debug("[ProviderA] Preparing request payload")
-	body, err := json.Marshal(map[string]interface{}{
-		"amount":   amount,
-		"currency": currency,
-	})
+	logger.WithContext(ctx).Debug("[ProviderA] Preparing request payload")
+	body, err := json.Marshal(newProviderARequest(amount, currency, domain.PaymentDetailsFromContext(ctx)))
 	if err != nil {
-		logger.Error("[ProviderA] Failed to marshal request body: %v", err)
-		return nil, &domain.PaymentError{
+		logger.WithContext(ctx).Error("[ProviderA] Failed to marshal request body: %v", err)
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrInternalError,
 			Message:   "Failed to marshal request body: " + err.Error(),
 			Provider:  p.Name(),
 			Retryable: false,
 			Details:   err.Error(),
-		}
+		}).WithCause(err)
 	}
 
-	logger.Debug("[ProviderA] Creating HTTP request to endpoint: %s", p.config.Endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint, bytes.NewReader(body))
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Process, defaultProcessOperation, "")
+	logger.WithContext(ctx).Debug("[ProviderA] Creating HTTP request to endpoint: %s", endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
 	if err != nil {
-		logger.Error("[ProviderA] Failed to create request: %v", err)
-		return nil, &domain.PaymentError{
+		logger.WithContext(ctx).Error("[ProviderA] Failed to create request: %v", err)
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrInternalError,
 			Message:   "Failed to create request: " + err.Error(),
 			Provider:  p.Name(),
 			Retryable: false,
-		}
+		}).WithCause(err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setAuthorization(req, p.config.Auth)
+	if key := domain.IdempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
 
-	logger.Debug("[ProviderA] Sending payment request")
+	logger.WithContext(ctx).Debug("[ProviderA] Sending payment request")
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		logger.Error("[ProviderA] Failed to send request: %v", err)
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrNetworkError,
-			Message:   "Failed to send request: " + err.Error(),
-			Provider:  p.Name(),
-			Retryable: true,
-			Details:   err.Error(),
-		}
+		logger.WithContext(ctx).Error("[ProviderA] Failed to send request: %v", err)
+		return nil, transportError(p.Name(), err)
 	}
 	defer resp.Body.Close()
 
-	logger.Debug("[ProviderA] Received response with status code: %d", resp.StatusCode)
+	logger.WithContext(ctx).Debug("[ProviderA] Received response with status code: %d", resp.StatusCode)
 
-	// Check HTTP status code
-	switch resp.StatusCode {
-	case http.StatusTooManyRequests:
-		logger.Error("[ProviderA] Rate limit exceeded")
-		return nil, &domain.PaymentError{
-			Code:       domain.ErrRateLimitExceeded,
-			Message:    "Rate limit exceeded",
-			Provider:   p.Name(),
-			Retryable:  true,
-			HTTPStatus: resp.StatusCode,
-		}
-	case http.StatusInternalServerError:
-		logger.Error("[ProviderA] Provider internal error occurred")
-		return nil, &domain.PaymentError{
-			Code:       domain.ErrInternalError,
-			Message:    "Provider internal error",
-			Provider:   p.Name(),
-			Retryable:  true,
-			HTTPStatus: resp.StatusCode,
-		}
-	case http.StatusBadRequest:
-		logger.Error("[ProviderA] Invalid request parameters received")
-		// This might be due to invalid amount, currency or other validation failures
-		return nil, &domain.PaymentError{
-			Code:       domain.ErrInvalidAmount,
-			Message:    "Invalid request parameters",
-			Provider:   p.Name(),
-			Retryable:  false,
-			HTTPStatus: resp.StatusCode,
-		}
+	// The provider may accept the payment and settle it asynchronously
+	if resp.StatusCode == http.StatusAccepted {
+		return acceptedPayment(p.Name(), req, resp, amount, currency, p.clock.Now())
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	if perr := statusError(ctx, p.Name(), resp.StatusCode); perr != nil {
+		return nil, perr
+	}
+
+	respBody, perr := readResponseBody(ctx, p.Name(), resp.Body, p.config.MaxResponseSize)
+	if perr != nil {
+		return nil, perr
+	}
+
+	payment, perr := p.parsePaymentResponse(respBody)
+	if perr != nil {
+		return nil, perr
+	}
+	if perr := currencyMismatchError(ctx, p.config, payment, currency); perr != nil {
+		return nil, perr
+	}
+	return payment, nil
+}
+
+// providerASettlementFields are the fee and net amount fields Provider A reports by default
+var providerASettlementFields = config.SettlementFields{Fee: "fee", NetAmount: "net_amount"}
+
+// parsePaymentResponse maps a Provider A payment response body to a domain payment
+func (p *ProviderA) parsePaymentResponse(respBody []byte) (*domain.Payment, *domain.PaymentError) {
+	rawData := providerRawData(respBody, p.config.OmitRawData)
+	respBody, settled, err := extractSettlement(respBody, p.config.SettlementFields, providerASettlementFields)
 	if err != nil {
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrInternalError,
-			Message:   "Failed to read response body: " + err.Error(),
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid settlement amounts in response: " + err.Error(),
 			Provider:  p.Name(),
-			Retryable: true,
-			Details:   err.Error(),
-		}
+			Retryable: false,
+		}).WithCause(err)
 	}
 
 	var response struct {
@@ -172,16 +223,18 @@ func (p *ProviderA) ProcessPayment(ctx context.Context, amount float64, currency
 		Amount        float64   `json:"amount"`
 		Currency      string    `json:"currency"`
 		Timestamp     time.Time `json:"timestamp"`
+		DeclineCode   string    `json:"decline_code"`
+		Reason        string    `json:"reason"`
 	}
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, &domain.PaymentError{
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
 			Message:   "Failed to parse response: " + err.Error(),
 			Provider:  p.Name(),
 			Retryable: false,
 			Details:   string(respBody),
-		}
+		}).WithCause(err)
 	}
 
 	// Validate response fields
@@ -205,30 +258,175 @@ func (p *ProviderA) ProcessPayment(ctx context.Context, amount float64, currency
 		}
 	}
 
-	switch response.Status {
-	case "APPROVED":
-		return &domain.Payment{
-			ID:        response.TransactionID,
-			Amount:    response.Amount,
-			Currency:  domain.Currency(response.Currency),
-			Status:    domain.PaymentStatus(response.Status),
+	status, perr := responseStatus(p.Name(), response.Status)
+	if perr != nil {
+		perr.Details = string(respBody)
+		return nil, perr
+	}
+	currency, perr := responseCurrency(p.config, response.Currency)
+	if perr != nil {
+		perr.Details = string(respBody)
+		return nil, perr
+	}
+
+	switch status {
+	case domain.StatusApproved, domain.StatusPending:
+		// A pending payment settles later; callers poll GetPaymentStatus until it is terminal
+		payment := &domain.Payment{
+			ID:              response.TransactionID,
+			Amount:          response.Amount,
+			Currency:        currency,
+			Status:          status,
+			Provider:        p.Name(),
+			Timestamp:       response.Timestamp,
+			ProviderRawData: rawData,
+		}
+		settled.apply(payment)
+		return payment, nil
+	case domain.StatusDeclined:
+		return nil, declineError(p.Name(), "Payment was declined", response.DeclineCode, response.Reason)
+	default:
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid payment status: " + response.Status,
+			Provider:  p.Name(),
+			Retryable: false,
+			Details:   string(respBody),
+		}
+	}
+}
+
+// RefundPayment refunds all or part of a previously approved payment through Provider A
+func (p *ProviderA) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderA] Processing refund request: transaction=%s, amount=%.2f", transactionID, amount)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Refund, defaultRefundOperation, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint,
+		map[string]interface{}{"amount": amount})
+	if perr != nil {
+		return nil, perr
+	}
+
+	var response struct {
+		TransactionID string    `json:"transaction_id"`
+		Status        string    `json:"status"`
+		Amount        float64   `json:"amount"`
+		Currency      string    `json:"currency"`
+		Timestamp     time.Time `json:"timestamp"`
+	}
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Failed to parse refund response: " + err.Error(),
 			Provider:  p.Name(),
-			Timestamp: response.Timestamp,
-		}, nil
-	case "DECLINED":
+			Retryable: false,
+			Details:   string(respBody),
+		}).WithCause(err)
+	}
+
+	if status, err := domain.ParsePaymentStatus(response.Status); err != nil || status != domain.StatusRefunded {
 		return nil, &domain.PaymentError{
-			Code:      domain.ErrCardDeclined,
-			Message:   "Payment was declined",
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid refund status: " + response.Status,
 			Provider:  p.Name(),
 			Retryable: false,
+			Details:   string(respBody),
 		}
-	default:
+	}
+	currency, perr := responseCurrency(p.config, response.Currency)
+	if perr != nil {
+		perr.Details = string(respBody)
+		return nil, perr
+	}
+
+	return &domain.Payment{
+		ID:              response.TransactionID,
+		Amount:          response.Amount,
+		Currency:        currency,
+		Status:          domain.StatusRefunded,
+		Provider:        p.Name(),
+		Timestamp:       response.Timestamp,
+		TransactionID:   transactionID,
+		ProviderRawData: providerRawData(respBody, p.config.OmitRawData),
+	}, nil
+}
+
+// CancelPayment cancels a payment that Provider A has not settled yet
+func (p *ProviderA) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderA] Processing cancel request: transaction=%s", transactionID)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Cancel, defaultCancelOperation, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint, nil)
+	if perr != nil {
+		return nil, perr
+	}
+
+	var response struct {
+		TransactionID string    `json:"transaction_id"`
+		Status        string    `json:"status"`
+		Amount        float64   `json:"amount"`
+		Currency      string    `json:"currency"`
+		Timestamp     time.Time `json:"timestamp"`
+	}
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Failed to parse cancel response: " + err.Error(),
+			Provider:  p.Name(),
+			Retryable: false,
+			Details:   string(respBody),
+		}).WithCause(err)
+	}
+
+	if status, err := domain.ParsePaymentStatus(response.Status); err != nil || status != domain.StatusCancelled {
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
-			Message:   "Invalid payment status: " + response.Status,
+			Message:   "Invalid cancel status: " + response.Status,
 			Provider:  p.Name(),
 			Retryable: false,
 			Details:   string(respBody),
 		}
 	}
+	currency, perr := responseCurrency(p.config, response.Currency)
+	if perr != nil {
+		perr.Details = string(respBody)
+		return nil, perr
+	}
+
+	return &domain.Payment{
+		ID:              response.TransactionID,
+		Amount:          response.Amount,
+		Currency:        currency,
+		Status:          domain.StatusCancelled,
+		Provider:        p.Name(),
+		Timestamp:       response.Timestamp,
+		TransactionID:   transactionID,
+		ProviderRawData: providerRawData(respBody, p.config.OmitRawData),
+	}, nil
+}
+
+// GetPaymentStatus queries Provider A for the current status of a payment
+func (p *ProviderA) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderA] Querying payment status: transaction=%s", transactionID)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	method, endpoint := statusRequest(ctx, p.config, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint, nil)
+	if perr != nil {
+		return nil, perr
+	}
+
+	payment, perr := p.parsePaymentResponse(respBody)
+	if perr != nil {
+		return nil, perr
+	}
+	return keepPollURL(ctx, payment), nil
 }
@@ -3,7 +3,9 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -108,7 +110,7 @@ func TestProviderA_ProcessPayment_Extended(t *testing.T) {
 			},
 			mockStatus:    http.StatusOK,
 			expectedError: true,
-			errorCode:     domain.ErrNetworkError,
+			errorCode:     domain.ErrProviderTimeout,
 		},
 		{
 			name:     "malformed timestamp",
@@ -185,7 +187,7 @@ func TestProviderA_ProcessPayment_Extended(t *testing.T) {
 			currency:      "USD",
 			mockStatus:    http.StatusInternalServerError,
 			expectedError: true,
-			errorCode:     domain.ErrInternalError,
+			errorCode:     domain.ErrProviderUnavailable,
 		},
 		{
 			name:          "rate limit exceeded",
@@ -287,3 +289,740 @@ func TestProviderA_ProcessPayment_Extended(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderA_ProcessPayment_Accepted(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		resp := httpclient.NewMockResponse(http.StatusAccepted, nil)
+		resp.Header.Set("Location", "/payments/ASYNC-A-1")
+		return resp, nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com/process",
+		Timeout:   5 * time.Second,
+		MaxAmount: 10000,
+	}
+	provider := NewProviderA(cfg, client)
+
+	payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.Status != domain.StatusPending {
+		t.Errorf("expected status %v, got %v", domain.StatusPending, payment.Status)
+	}
+	if payment.PollURL != "http://test-provider-a.com/payments/ASYNC-A-1" {
+		t.Errorf("expected poll URL to be captured, got %q", payment.PollURL)
+	}
+
+	// An accepted response without a Location cannot be polled
+	client = httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusAccepted, nil), nil
+	})
+	provider = NewProviderA(cfg, client)
+	if _, err := provider.ProcessPayment(context.Background(), 100.00, "USD"); err == nil || err.Code != domain.ErrProviderInvalidResp {
+		t.Errorf("expected %s for missing Location, got %v", domain.ErrProviderInvalidResp, err)
+	}
+}
+
+func TestProviderA_PollAcceptedPayment(t *testing.T) {
+	polls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/process" {
+			resp := httpclient.NewMockResponse(http.StatusAccepted, nil)
+			resp.Header.Set("Location", "/async/ASYNC-A-1")
+			return resp, nil
+		}
+		if req.URL.Path != "/async/ASYNC-A-1" {
+			t.Errorf("unexpected request to %s", req.URL.Path)
+		}
+		polls++
+		status := "PENDING"
+		if polls > 1 {
+			status = "APPROVED"
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-ASYNC-A-1",
+			"status":         status,
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+	provider := NewProviderA(config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com/process",
+		MaxAmount: 10000,
+	}, client)
+
+	accepted, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if accepted.Status != domain.StatusPending || accepted.PollURL == "" {
+		t.Fatalf("expected a pending payment with a poll URL, got %+v", accepted)
+	}
+
+	ctx := domain.WithPollURL(context.Background(), accepted.PollURL)
+	pending, err := provider.GetPaymentStatus(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error while pending: %v", err)
+	}
+	if pending.Status != domain.StatusPending || pending.ID != "TXN-ASYNC-A-1" || pending.PollURL != accepted.PollURL {
+		t.Errorf("expected pending payment TXN-ASYNC-A-1 keeping its poll URL, got %+v", pending)
+	}
+
+	approved, err := provider.GetPaymentStatus(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if approved.Status != domain.StatusApproved || approved.ID != "TXN-ASYNC-A-1" {
+		t.Errorf("expected approved payment TXN-ASYNC-A-1, got %+v", approved)
+	}
+}
+
+func TestProviderA_ProcessPayment_StrictResponse(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-STRICT-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+		"unexpected":     "field",
+	})
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	tests := []struct {
+		name          string
+		strict        bool
+		expectedError bool
+	}{
+		{name: "lenient mode ignores unknown fields", strict: false, expectedError: false},
+		{name: "strict mode rejects unknown fields", strict: true, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.PaymentProviderConfig{
+				Name:           "ProviderA",
+				Endpoint:       "http://test-provider-a.com",
+				MaxAmount:      10000,
+				StrictResponse: tt.strict,
+			}
+			provider := NewProviderA(cfg, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if tt.expectedError {
+				if err == nil || err.Code != domain.ErrProviderInvalidResp {
+					t.Errorf("expected %s, got %v", domain.ErrProviderInvalidResp, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.ID != "TXN-STRICT-1" {
+				t.Errorf("expected payment ID TXN-STRICT-1, got %s", payment.ID)
+			}
+		})
+	}
+}
+
+func TestProviderA_GetPaymentStatus(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			t.Errorf("expected GET request, got %s", req.Method)
+		}
+		if req.URL.String() != "http://test-provider-a.com/process/TXN-STATUS-1" {
+			t.Errorf("unexpected status URL: %s", req.URL)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-STATUS-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com/process",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderA(cfg, client)
+
+	payment, err := provider.GetPaymentStatus(context.Background(), "TXN-STATUS-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.ID != "TXN-STATUS-1" || payment.Status != domain.StatusApproved {
+		t.Errorf("expected approved payment TXN-STATUS-1, got %+v", payment)
+	}
+
+	client = httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusNotFound, nil), nil
+	})
+	provider = NewProviderA(cfg, client)
+	if _, err := provider.GetPaymentStatus(context.Background(), "TXN-MISSING"); err == nil || err.Code != domain.ErrTransactionNotFound {
+		t.Errorf("expected %s, got %v", domain.ErrTransactionNotFound, err)
+	}
+}
+
+func TestProviderA_CancelPayment(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", req.Method)
+		}
+		if req.URL.String() != "http://test-provider-a.com/process/TXN-CANCEL-1/cancel" {
+			t.Errorf("unexpected cancel URL: %s", req.URL)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-CANCEL-1",
+			"status":         "CANCELLED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com/process",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderA(cfg, client)
+
+	payment, err := provider.CancelPayment(context.Background(), "TXN-CANCEL-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.TransactionID != "TXN-CANCEL-1" || payment.Status != domain.StatusCancelled {
+		t.Errorf("expected cancelled payment TXN-CANCEL-1, got %+v", payment)
+	}
+
+	client = httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		body, _ := json.Marshal(map[string]interface{}{"transaction_id": "TXN-CANCEL-2", "status": "APPROVED"})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+	provider = NewProviderA(cfg, client)
+	if _, err := provider.CancelPayment(context.Background(), "TXN-CANCEL-2"); err == nil || err.Code != domain.ErrProviderInvalidResp {
+		t.Errorf("expected %s, got %v", domain.ErrProviderInvalidResp, err)
+	}
+}
+
+func TestProviderA_ProcessPayment_ProviderTimeout(t *testing.T) {
+	// The transport blocks until the request context ends, like a provider that never answers
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	client.Timeout = time.Minute
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com",
+		Timeout:   20 * time.Millisecond,
+		MaxAmount: 10000,
+	}
+	provider := NewProviderA(cfg, client)
+
+	start := time.Now()
+	_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+	elapsed := time.Since(start)
+
+	if err == nil || err.Code != domain.ErrProviderTimeout {
+		t.Errorf("expected %s, got %v", domain.ErrProviderTimeout, err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected the provider timeout to apply, took %v", elapsed)
+	}
+}
+
+func TestProviderA_ProcessPayment_AuthenticationFailed(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				return httpclient.NewMockResponse(status, nil), nil
+			})
+			cfg := config.PaymentProviderConfig{
+				Name:      "ProviderA",
+				Endpoint:  "http://test-provider-a.com",
+				MaxAmount: 10000,
+			}
+			provider := NewProviderA(cfg, client)
+
+			_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if err == nil || err.Code != domain.ErrAuthenticationFailed {
+				t.Fatalf("expected %s, got %v", domain.ErrAuthenticationFailed, err)
+			}
+			if err.Retryable {
+				t.Error("expected authentication failures to be non-retryable")
+			}
+			if err.HTTPStatus != status {
+				t.Errorf("expected HTTP status %d, got %d", status, err.HTTPStatus)
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_CancelledContext(t *testing.T) {
+	calls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httpclient.NewMockResponse(http.StatusOK, nil), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderA",
+		Endpoint:  "http://test-provider-a.com/payments",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderA(cfg, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := provider.ProcessPayment(ctx, 100.00, "USD")
+	if err == nil || err.Code != domain.ErrCancelled {
+		t.Errorf("expected %s, got %v", domain.ErrCancelled, err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no HTTP attempt, got %d", calls)
+	}
+}
+
+func TestProviderA_ProcessPayment_Settlement(t *testing.T) {
+	tests := []struct {
+		name        string
+		extra       map[string]interface{}
+		fields      config.SettlementFields
+		expectedFee float64
+		expectedNet float64
+	}{
+		{
+			name:        "default field names",
+			extra:       map[string]interface{}{"fee": 2.9, "net_amount": 97.1},
+			expectedFee: 2.9,
+			expectedNet: 97.1,
+		},
+		{
+			name:        "custom field names",
+			extra:       map[string]interface{}{"settlement": map[string]interface{}{"fee": "1.50", "net": "98.50"}},
+			fields:      config.SettlementFields{Fee: "settlement.fee", NetAmount: "settlement.net"},
+			expectedFee: 1.5,
+			expectedNet: 98.5,
+		},
+		{
+			name: "fields absent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := map[string]interface{}{
+				"transaction_id": "TXN-FEE-1",
+				"status":         "APPROVED",
+				"amount":         100.00,
+				"currency":       "USD",
+				"timestamp":      "2024-01-15T10:30:00Z",
+			}
+			for key, value := range tt.extra {
+				response[key] = value
+			}
+			body, _ := json.Marshal(response)
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+
+			cfg := config.PaymentProviderConfig{
+				Name:             "ProviderA",
+				Endpoint:         "http://test-provider-a.com",
+				MaxAmount:        10000,
+				StrictResponse:   true,
+				SettlementFields: tt.fields,
+			}
+			provider := NewProviderA(cfg, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Fee != tt.expectedFee || payment.NetAmount != tt.expectedNet {
+				t.Errorf("expected fee %.2f and net %.2f, got fee %.2f and net %.2f",
+					tt.expectedFee, tt.expectedNet, payment.Fee, payment.NetAmount)
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_Limits(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		var sent providerARequest
+		if err := json.NewDecoder(req.Body).Decode(&sent); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-LIMITS-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       sent.Currency,
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	tests := []struct {
+		name       string
+		currencies []string
+		amount     float64
+		currency   string
+		errorCode  string
+	}{
+		{name: "within limits", currencies: []string{"EUR"}, amount: 100.00, currency: "EUR"},
+		{name: "below minimum", currencies: []string{"EUR"}, amount: 0.50, currency: "EUR", errorCode: domain.ErrInvalidAmount},
+		{name: "currency not in provider list", currencies: []string{"EUR"}, amount: 100.00, currency: "USD", errorCode: domain.ErrInvalidCurrency},
+		{name: "empty list accepts known currencies", amount: 100.00, currency: "GBP"},
+		{name: "empty list rejects unknown currencies", amount: 100.00, currency: "JPY", errorCode: domain.ErrInvalidCurrency},
+		{name: "configured currency without a named constant", currencies: []string{"EUR", "JPY"}, amount: 100.00, currency: "JPY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.PaymentProviderConfig{
+				Name:                "ProviderA",
+				Endpoint:            "http://test-provider-a.com",
+				MinAmount:           1.00,
+				MaxAmount:           10000,
+				SupportedCurrencies: tt.currencies,
+			}
+			provider := NewProviderA(cfg, client)
+
+			_, err := provider.ProcessPayment(context.Background(), tt.amount, tt.currency)
+			if tt.errorCode == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.errorCode {
+				t.Errorf("expected %s, got %v", tt.errorCode, err)
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_RequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       float64
+		details      domain.PaymentDetails
+		expectedBody string
+	}{
+		{
+			name:         "amount and currency",
+			amount:       100.50,
+			expectedBody: `{"amount":100.5,"currency":"USD"}`,
+		},
+		{
+			name:   "with reference and metadata",
+			amount: 25,
+			details: domain.PaymentDetails{
+				ReferenceID: "INV-7",
+				Metadata:    map[string]string{"order": "O-1", "customer": "C-1"},
+			},
+			expectedBody: `{"amount":25,"currency":"USD","reference_id":"INV-7","metadata":{"customer":"C-1","order":"O-1"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				sent = string(body)
+				resp, _ := json.Marshal(map[string]interface{}{
+					"transaction_id": "TXN-BODY-1",
+					"status":         "APPROVED",
+					"amount":         tt.amount,
+					"currency":       "USD",
+					"timestamp":      "2024-01-15T10:30:00Z",
+				})
+				return httpclient.NewMockResponse(http.StatusOK, resp), nil
+			})
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:      "ProviderA",
+				Endpoint:  "http://test-provider-a.com",
+				MaxAmount: 10000,
+			}, client)
+
+			ctx := domain.WithPaymentDetails(context.Background(), tt.details)
+			if _, err := provider.ProcessPayment(ctx, tt.amount, "USD"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sent != tt.expectedBody {
+				t.Errorf("expected request body %s, got %s", tt.expectedBody, sent)
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_ProviderRawData(t *testing.T) {
+	// Keys are sorted so the body matches how the decoded data marshals back
+	const responseBody = `{"amount":100.5,"currency":"USD","fee":1.25,"status":"APPROVED","timestamp":"2024-01-15T10:30:00Z","transaction_id":"TXN-RAW-1"}`
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusOK, []byte(responseBody)), nil
+	})
+
+	tests := []struct {
+		name        string
+		omitRawData bool
+		expected    string
+	}{
+		{name: "raw data round-trips", expected: responseBody},
+		{name: "raw data omitted", omitRawData: true, expected: "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:        "ProviderA",
+				Endpoint:    "http://test-provider-a.com",
+				MaxAmount:   10000,
+				OmitRawData: tt.omitRawData,
+			}, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.50, "USD")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			raw, marshalErr := json.Marshal(payment.ProviderRawData)
+			if marshalErr != nil {
+				t.Fatalf("failed to marshal raw data: %v", marshalErr)
+			}
+			if string(raw) != tt.expected {
+				t.Errorf("expected raw data %s, got %s", tt.expected, raw)
+			}
+		})
+	}
+}
+
+func TestProviderA_Authorization(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     config.AuthConfig
+		expected string
+	}{
+		{name: "no credentials", expected: ""},
+		{name: "bearer token", auth: config.AuthConfig{Type: config.AuthBearer, Token: "tok-123"}, expected: "Bearer tok-123"},
+		{name: "basic auth", auth: config.AuthConfig{Type: config.AuthBasic, Username: "merchant", Password: "s3cret"}, expected: "Basic bWVyY2hhbnQ6czNjcmV0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				headers = append(headers, req.Header.Get("Authorization"))
+				body, _ := json.Marshal(map[string]interface{}{
+					"transaction_id": "TXN-AUTH-1",
+					"status":         "APPROVED",
+					"amount":         100.00,
+					"currency":       "USD",
+					"timestamp":      "2024-01-15T10:30:00Z",
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:      "ProviderA",
+				Endpoint:  "http://test-provider-a.com",
+				MaxAmount: 10000,
+				Auth:      tt.auth,
+			}, client)
+
+			if _, err := provider.ProcessPayment(context.Background(), 100.00, "USD"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := provider.GetPaymentStatus(context.Background(), "TXN-AUTH-1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(headers) != 2 {
+				t.Fatalf("expected 2 requests, got %d", len(headers))
+			}
+			for i, header := range headers {
+				if header != tt.expected {
+					t.Errorf("request %d: expected Authorization %q, got %q", i+1, tt.expected, header)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_MaxAmountByCurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        float64
+		currency      string
+		expectedError bool
+	}{
+		{name: "within the currency limit", amount: 500000, currency: "JPY"},
+		{name: "above the currency limit", amount: 2000000, currency: "JPY", expectedError: true},
+		{name: "within the default limit", amount: 5000, currency: "USD"},
+		{name: "above the default limit", amount: 50000, currency: "USD", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]interface{}{
+					"transaction_id": "TXN-LIMIT-1",
+					"status":         "APPROVED",
+					"amount":         tt.amount,
+					"currency":       tt.currency,
+					"timestamp":      "2024-01-15T10:30:00Z",
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:                "ProviderA",
+				Endpoint:            "http://test-provider-a.com",
+				MaxAmount:           10000,
+				MaxAmountByCurrency: map[string]float64{"JPY": 1000000},
+				SupportedCurrencies: []string{"USD", "JPY"},
+			}, client)
+
+			_, err := provider.ProcessPayment(context.Background(), tt.amount, tt.currency)
+			if tt.expectedError {
+				if err == nil || err.Code != domain.ErrInvalidAmount {
+					t.Errorf("expected %s, got %v", domain.ErrInvalidAmount, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_DeclineReasons(t *testing.T) {
+	tests := []struct {
+		name            string
+		declineCode     string
+		reason          string
+		strict          bool
+		expectedCode    string
+		expectedDetails string
+	}{
+		{name: "no reason", expectedCode: domain.ErrCardDeclined},
+		{name: "insufficient funds", declineCode: "insufficient_funds", reason: "Not enough balance", expectedCode: domain.ErrInsufficientFunds, expectedDetails: "insufficient_funds: Not enough balance"},
+		{name: "expired card", declineCode: "EXPIRED_CARD", expectedCode: domain.ErrCardExpired, expectedDetails: "EXPIRED_CARD"},
+		{name: "reason only", reason: "insufficient_funds", expectedCode: domain.ErrInsufficientFunds, expectedDetails: "insufficient_funds"},
+		{name: "unmapped code", declineCode: "do_not_honor", expectedCode: domain.ErrCardDeclined, expectedDetails: "do_not_honor"},
+		{name: "strict response", declineCode: "insufficient_funds", strict: true, expectedCode: domain.ErrInsufficientFunds, expectedDetails: "insufficient_funds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				response := map[string]interface{}{
+					"transaction_id": "TXN-DECLINE-1",
+					"status":         "DECLINED",
+					"amount":         100.00,
+					"currency":       "USD",
+					"timestamp":      "2024-01-15T10:30:00Z",
+				}
+				if tt.declineCode != "" {
+					response["decline_code"] = tt.declineCode
+				}
+				if tt.reason != "" {
+					response["reason"] = tt.reason
+				}
+				body, _ := json.Marshal(response)
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:           "ProviderA",
+				Endpoint:       "http://test-provider-a.com",
+				MaxAmount:      10000,
+				StrictResponse: tt.strict,
+			}, client)
+
+			_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if err == nil {
+				t.Fatal("expected a decline error")
+			}
+			if err.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, err.Code)
+			}
+			if err.Details != tt.expectedDetails {
+				t.Errorf("expected details %q, got %q", tt.expectedDetails, err.Details)
+			}
+			if tt.expectedDetails != "" && !strings.Contains(err.Message, tt.expectedDetails) {
+				t.Errorf("expected the message to include %q, got %q", tt.expectedDetails, err.Message)
+			}
+			if err.Retryable {
+				t.Error("expected a decline not to be retryable")
+			}
+		})
+	}
+}
+
+func TestProviderA_ProcessPayment_ResponseCanonicalization(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           string
+		currency         string
+		expectedCode     string
+		expectedCurrency domain.Currency
+	}{
+		{name: "lowercase status and currency", status: "approved", currency: "usd", expectedCurrency: domain.USD},
+		{name: "lowercase decline", status: "declined", currency: "USD", expectedCode: domain.ErrCardDeclined},
+		{name: "unknown status", status: "SETTLED", currency: "USD", expectedCode: domain.ErrProviderInvalidResp},
+		{name: "invalid currency", status: "APPROVED", currency: "US-DOLLAR", expectedCode: domain.ErrProviderInvalidResp},
+		{name: "unknown currency", status: "APPROVED", currency: "XYZ", expectedCode: domain.ErrProviderInvalidResp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]interface{}{
+					"transaction_id": "TXN-CASE-1",
+					"status":         tt.status,
+					"amount":         100.00,
+					"currency":       tt.currency,
+					"timestamp":      "2024-01-15T10:30:00Z",
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderA(config.PaymentProviderConfig{
+				Name:      "ProviderA",
+				Endpoint:  "http://test-provider-a.com",
+				MaxAmount: 10000,
+			}, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if tt.expectedCode != "" {
+				if err == nil || err.Code != tt.expectedCode {
+					t.Fatalf("expected error code %s, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Status != domain.StatusApproved {
+				t.Errorf("expected status %s, got %s", domain.StatusApproved, payment.Status)
+			}
+			if payment.Currency != tt.expectedCurrency {
+				t.Errorf("expected currency %s, got %s", tt.expectedCurrency, payment.Currency)
+			}
+		})
+	}
+}
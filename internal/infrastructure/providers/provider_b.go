@@ -5,20 +5,106 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/pkg/logger"
 )
 
+// providerBRequest is the body of a ProviderB payment request. Like its responses it
+// nests the amount, formatted as a string with the currency's decimal places, under a
+// value object.
+type providerBRequest struct {
+	PaymentValue providerBValue    `json:"paymentValue"`
+	ReferenceID  string            `json:"referenceId,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// providerBValue is an amount in ProviderB's wire format
+type providerBValue struct {
+	Amount       string `json:"amount"`
+	CurrencyCode string `json:"currencyCode"`
+}
+
+// newProviderBRequest builds the body of a ProviderB payment request, sending the amount
+// with decimals decimal places
+func newProviderBRequest(amount float64, currency string, decimals int, details domain.PaymentDetails) providerBRequest {
+	return providerBRequest{
+		PaymentValue: providerBValue{
+			Amount:       formatProviderBAmount(amount, decimals),
+			CurrencyCode: currency,
+		},
+		ReferenceID: details.ReferenceID,
+		Metadata:    details.Metadata,
+	}
+}
+
+// providerBRefundRequest is the body of a ProviderB refund request
+type providerBRefundRequest struct {
+	Amount string `json:"amount"`
+}
+
+// formatProviderBAmount formats amount as ProviderB expects it: a string rounded to
+// decimals decimal places, such as "100.50"
+func formatProviderBAmount(amount float64, decimals int) string {
+	return strconv.FormatFloat(roundToDecimals(amount, decimals), 'f', decimals, 64)
+}
+
+// roundToDecimals rounds amount to the given number of decimal places
+func roundToDecimals(amount float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(amount*scale) / scale
+}
+
+// parseProviderBAmount parses an amount as ProviderB reports it: plain digits with at
+// most decimals significant decimal places, such as "100", "100.50", or "1000.00" for a
+// currency without decimals. Unlike strconv.ParseFloat it rejects signs, exponents
+// ("1e3"), "NaN", "Inf" and amounts too large to represent.
+func parseProviderBAmount(value string, decimals int) (float64, error) {
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return 0, fmt.Errorf("%q is not a decimal amount", value)
+	}
+	if len(fraction) > decimals && strings.TrimRight(fraction[decimals:], "0") != "" {
+		return 0, fmt.Errorf("%q has more than %d decimal places", value, decimals)
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("%q is out of range", value)
+	}
+	return amount, nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // ProviderB implements the payment provider interface for Provider B
 type ProviderB struct {
 	config     config.PaymentProviderConfig
 	httpClient *http.Client
+	clock      Clock
+}
+
+func init() {
+	Register("ProviderB", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+		return NewProviderB(cfg, client)
+	})
 }
 
 // NewProviderB creates a new instance of Provider B
@@ -26,9 +112,15 @@ func NewProviderB(config config.PaymentProviderConfig, client *http.Client) *Pro
 	return &ProviderB{
 		config:     config,
 		httpClient: client,
+		clock:      realClock{},
 	}
 }
 
+// setClock makes the provider read the time from clock
+func (p *ProviderB) setClock(clock Clock) {
+	p.clock = clock
+}
+
 // Name returns the provider name
 func (p *ProviderB) Name() string {
 	return p.config.Name
@@ -39,8 +131,9 @@ func (p *ProviderB) GetMetadata() map[string]interface{} {
 	return map[string]interface{}{
 		"name":        p.config.Name,
 		"endpoint":    p.config.Endpoint,
+		"sandbox":     p.config.Sandbox,
 		"timeout":     p.config.Timeout.String(),
-		"retryCount":  p.config.RetryCount,
+		"maxAttempts": p.config.RetryPolicy.MaxAttempts,
 		"maxAmount":   p.config.MaxAmount,
 		"description": p.config.Description,
 	}
@@ -48,117 +141,157 @@ func (p *ProviderB) GetMetadata() map[string]interface{} {
 
 // ProcessPayment processes a payment through Provider B
 func (p *ProviderB) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
-	logger.Debug("[ProviderB] Processing payment request: amount=%.2f, currency=%s", amount, currency)
+	ctx, span := startProviderSpan(ctx, "ProviderB.ProcessPayment", p.Name(), amount, currency)
+	payment, err := p.processPayment(ctx, amount, currency)
+	endSpan(span, err)
+	return payment, err
+}
+
+// processPayment validates the payment, sends it to Provider B and parses the response
+func (p *ProviderB) processPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderB] Processing payment request: amount=%.2f, currency=%s", amount, currency)
+
+	if err := ctx.Err(); err != nil {
+		logger.WithContext(ctx).Error("[ProviderB] Context already done, not sending payment: %v", err)
+		return nil, cancelledError(p.Name(), err)
+	}
+
+	// Bound the call by the provider's own timeout, independent of the shared client
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
 
 	// Validate amount and currency
 	if amount <= 0 {
-		logger.Error("[ProviderB] Invalid amount: %.2f", amount)
+		logger.WithContext(ctx).Error("[ProviderB] Invalid amount: %.2f", amount)
 		return nil, &domain.PaymentError{
 			Code:    domain.ErrInvalidAmount,
 			Message: "Amount must be greater than 0",
 		}
 	}
 
-	if amount > p.config.MaxAmount {
-		logger.Error("[ProviderB] Amount %.2f exceeds maximum limit of %.2f", amount, p.config.MaxAmount)
+	if amount < p.config.MinAmount {
+		logger.WithContext(ctx).Error("[ProviderB] Amount %.2f is below minimum limit of %.2f", amount, p.config.MinAmount)
 		return nil, &domain.PaymentError{
 			Code:    domain.ErrInvalidAmount,
-			Message: fmt.Sprintf("Amount exceeds maximum limit of %v", p.config.MaxAmount),
+			Message: fmt.Sprintf("Amount is below minimum limit of %v", p.config.MinAmount),
+		}
+	}
+
+	if maxAmount := p.config.MaxAmountFor(currency); amount > maxAmount {
+		logger.WithContext(ctx).Error("[ProviderB] Amount %.2f exceeds maximum limit of %.2f %s", amount, maxAmount, currency)
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrInvalidAmount,
+			Message: fmt.Sprintf("Amount exceeds maximum limit of %v %s", maxAmount, currency),
 		}
 	}
 
 	if currency == "" {
-		logger.Error("[ProviderB] Currency is required")
+		logger.WithContext(ctx).Error("[ProviderB] Currency is required")
 		return nil, &domain.PaymentError{
 			Code:    domain.ErrInvalidCurrency,
 			Message: "Currency is required",
 		}
 	}
 
-	// Prepare request body
-	logger.Debug("[ProviderB] Preparing request payload")
-	body, err := json.Marshal(map[string]interface{}{
-		"amount":   amount,
-		"currency": currency,
-	})
-	if err != nil {
-		logger.Error("[ProviderB] Failed to marshal request body: %v", err)
+	if !currencyAllowed(p.config.SupportedCurrencies, currency) {
+		logger.WithContext(ctx).Error("[ProviderB] Unsupported currency: %s", currency)
 		return nil, &domain.PaymentError{
+			Code:    domain.ErrInvalidCurrency,
+			Message: fmt.Sprintf("Currency %s is not supported", currency),
+		}
+	}
+
+	// Prepare request body. The amount is sent, and so charged, with the currency's
+	// decimal places.
+	logger.WithContext(ctx).Debug("[ProviderB] Preparing request payload")
+	decimals := p.config.DecimalsFor(currency)
+	amount = roundToDecimals(amount, decimals)
+	body, err := json.Marshal(newProviderBRequest(amount, currency, decimals, domain.PaymentDetailsFromContext(ctx)))
+	if err != nil {
+		logger.WithContext(ctx).Error("[ProviderB] Failed to marshal request body: %v", err)
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrInternalError,
 			Message:   "Failed to marshal request body",
 			Provider:  p.Name(),
 			Details:   err.Error(),
 			Retryable: false,
-		}
+		}).WithCause(err)
 	}
 
-	logger.Debug("[ProviderB] Creating HTTP request to endpoint: %s", p.config.Endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint, bytes.NewReader(body))
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Process, defaultProcessOperation, "")
+	logger.WithContext(ctx).Debug("[ProviderB] Creating HTTP request to endpoint: %s", endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
 	if err != nil {
-		logger.Error("[ProviderB] Failed to create request: %v", err)
-		return nil, &domain.PaymentError{
+		logger.WithContext(ctx).Error("[ProviderB] Failed to create request: %v", err)
+		return nil, (&domain.PaymentError{
 			Code:    domain.ErrInternalError,
 			Message: "Failed to create request",
-		}
+		}).WithCause(err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setAuthorization(req, p.config.Auth)
+	if key := domain.IdempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
 
-	logger.Debug("[ProviderB] Sending payment request")
+	logger.WithContext(ctx).Debug("[ProviderB] Sending payment request")
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		logger.Error("[ProviderB] Request failed: %v", err)
-		errCode := domain.ErrNetworkError
-		if err.Error() == "context deadline exceeded" {
-			errCode = domain.ErrProviderTimeout
-		}
-		return nil, &domain.PaymentError{
-			Code:      errCode,
-			Message:   "Failed to send request: " + err.Error(),
-			Provider:  p.Name(),
-			Retryable: true,
-		}
+		logger.WithContext(ctx).Error("[ProviderB] Request failed: %v", err)
+		return nil, transportError(p.Name(), err)
 	}
 	defer resp.Body.Close()
 
-	logger.Debug("[ProviderB] Received response with status code: %d", resp.StatusCode)
+	logger.WithContext(ctx).Debug("[ProviderB] Received response with status code: %d", resp.StatusCode)
 
-	// Check response status
-	if resp.StatusCode >= 500 {
-		logger.Error("[ProviderB] Provider server error: %d", resp.StatusCode)
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrProviderUnavailable,
-			Message:   fmt.Sprintf("Provider error: %d", resp.StatusCode),
-			Provider:  p.Name(),
-			Retryable: true,
-		}
-	} else if resp.StatusCode == http.StatusTooManyRequests {
-		logger.Error("[ProviderB] Rate limit exceeded")
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrRateLimitExceeded,
-			Message:   "Rate limit exceeded",
-			Provider:  p.Name(),
-			Retryable: true,
-		}
-	} else if resp.StatusCode >= 400 {
-		logger.Error("[ProviderB] Invalid request error: %d", resp.StatusCode)
+	// The provider may accept the payment and settle it asynchronously
+	if resp.StatusCode == http.StatusAccepted {
+		return acceptedPayment(p.Name(), req, resp, amount, currency, p.clock.Now())
+	}
+
+	if perr := statusError(ctx, p.Name(), resp.StatusCode); perr != nil {
+		return nil, perr
+	}
+
+	logger.WithContext(ctx).Debug("[ProviderB] Reading response body")
+	respBody, perr := readResponseBody(ctx, p.Name(), resp.Body, p.config.MaxResponseSize)
+	if perr != nil {
+		return nil, perr
+	}
+
+	payment, perr := p.parsePaymentResponse(respBody)
+	if perr != nil {
+		return nil, perr
+	}
+	if !domain.AmountsMatch(payment.Amount, amount) {
+		logger.WithContext(ctx).Error("[ProviderB] Amount mismatch: requested %.2f, provider reported %.2f", amount, payment.Amount)
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
-			Message:   fmt.Sprintf("Invalid request: %d", resp.StatusCode),
+			Message:   fmt.Sprintf("Provider reported amount %.2f for a payment of %.2f", payment.Amount, amount),
 			Provider:  p.Name(),
 			Retryable: false,
 		}
 	}
+	if perr := currencyMismatchError(ctx, p.config, payment, currency); perr != nil {
+		return nil, perr
+	}
+	return payment, nil
+}
 
-	logger.Debug("[ProviderB] Reading response body")
-	respBody, err := io.ReadAll(resp.Body)
+// providerBSettlementFields are the fee and net amount fields Provider B reports by default
+var providerBSettlementFields = config.SettlementFields{Fee: "value.fee", NetAmount: "value.netAmount"}
+
+// parsePaymentResponse maps a Provider B payment response body to a domain payment
+func (p *ProviderB) parsePaymentResponse(respBody []byte) (*domain.Payment, *domain.PaymentError) {
+	rawData := providerRawData(respBody, p.config.OmitRawData)
+	respBody, settled, err := extractSettlement(respBody, p.config.SettlementFields, providerBSettlementFields)
 	if err != nil {
-		logger.Error("[ProviderB] Failed to read response body: %v", err)
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrInternalError,
-			Message:   "Failed to read response body: " + err.Error(),
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid settlement amounts in response: " + err.Error(),
 			Provider:  p.Name(),
-			Retryable: true,
-		}
+			Retryable: false,
+		}).WithCause(err)
 	}
 
 	var response struct {
@@ -168,31 +301,31 @@ func (p *ProviderB) ProcessPayment(ctx context.Context, amount float64, currency
 			Amount       string `json:"amount"`
 			CurrencyCode string `json:"currencyCode"`
 		} `json:"value"`
-		ProcessedAt int64 `json:"processedAt"`
+		ProcessedAt int64  `json:"processedAt"`
+		DeclineCode string `json:"declineCode"`
+		Reason      string `json:"reason"`
 	}
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, &domain.PaymentError{
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
 			Message:   "Failed to parse provider response: " + err.Error(),
 			Provider:  p.Name(),
 			Retryable: false,
 			Details:   string(respBody),
-		}
+		}).WithCause(err)
 	}
 
 	// Map provider status to domain status
 	var status domain.PaymentStatus
-	switch response.State {
+	switch strings.ToUpper(strings.TrimSpace(response.State)) {
 	case "SUCCESS":
 		status = domain.StatusApproved
+	case "PENDING":
+		// Settles later; callers poll GetPaymentStatus until it is terminal
+		status = domain.StatusPending
 	case "FAILED":
-		return nil, &domain.PaymentError{
-			Code:      domain.ErrCardDeclined,
-			Message:   "Payment was declined by provider",
-			Provider:  p.Name(),
-			Retryable: false,
-		}
+		return nil, declineError(p.Name(), "Payment was declined by provider", response.DeclineCode, response.Reason)
 	default:
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
@@ -202,33 +335,187 @@ func (p *ProviderB) ProcessPayment(ctx context.Context, amount float64, currency
 		}
 	}
 
-	// Validate and parse amount
-	amount, err = strconv.ParseFloat(response.Value.Amount, 64)
+	// Validate currency
+	currency, perr := responseCurrency(p.config, response.Value.CurrencyCode)
+	if perr != nil {
+		return nil, perr
+	}
+
+	// Validate and parse amount, which has as many decimals as the currency allows
+	amount, err := parseProviderBAmount(response.Value.Amount, p.config.DecimalsFor(string(currency)))
 	if err != nil {
-		return nil, &domain.PaymentError{
+		return nil, (&domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
 			Message:   "Invalid amount format in response: " + err.Error(),
 			Provider:  p.Name(),
 			Retryable: false,
+		}).WithCause(err)
+	}
+
+	payment := &domain.Payment{
+		ID:              response.PaymentID,
+		Amount:          domain.RoundAmount(amount),
+		Currency:        currency,
+		Status:          status,
+		Provider:        p.Name(),
+		Timestamp:       time.Unix(response.ProcessedAt/1000, 0),
+		ProviderRawData: rawData,
+	}
+	settled.apply(payment)
+	return payment, nil
+}
+
+// RefundPayment refunds all or part of a previously approved payment through Provider B
+func (p *ProviderB) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderB] Processing refund request: transaction=%s, amount=%.2f", transactionID, amount)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	// A refund names only the transaction, not its currency, so the amount is sent with
+	// the default number of decimal places
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Refund, defaultRefundOperation, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint,
+		providerBRefundRequest{Amount: formatProviderBAmount(amount, config.DefaultAmountDecimals)})
+	if perr != nil {
+		return nil, perr
+	}
+
+	var response struct {
+		RefundID string `json:"refundId"`
+		State    string `json:"state"`
+		Value    struct {
+			Amount       string `json:"amount"`
+			CurrencyCode string `json:"currencyCode"`
+		} `json:"value"`
+		ProcessedAt int64 `json:"processedAt"`
+	}
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Failed to parse refund response: " + err.Error(),
+			Provider:  p.Name(),
+			Retryable: false,
+		}).WithCause(err)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(response.State), "REFUNDED") {
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid refund state from provider: " + response.State,
+			Provider:  p.Name(),
+			Retryable: false,
 		}
 	}
 
-	// Validate currency
-	if response.Value.CurrencyCode == "" {
+	currency, perr := responseCurrency(p.config, response.Value.CurrencyCode)
+	if perr != nil {
+		return nil, perr
+	}
+	refunded, err := parseProviderBAmount(response.Value.Amount, p.config.DecimalsFor(string(currency)))
+	if err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid amount format in refund response: " + err.Error(),
+			Provider:  p.Name(),
+			Retryable: false,
+		}).WithCause(err)
+	}
+
+	return &domain.Payment{
+		ID:              response.RefundID,
+		Amount:          domain.RoundAmount(refunded),
+		Currency:        currency,
+		Status:          domain.StatusRefunded,
+		Provider:        p.Name(),
+		Timestamp:       time.Unix(response.ProcessedAt/1000, 0),
+		TransactionID:   transactionID,
+		ProviderRawData: providerRawData(respBody, p.config.OmitRawData),
+	}, nil
+}
+
+// CancelPayment cancels a payment that Provider B has not settled yet
+func (p *ProviderB) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderB] Processing cancel request: transaction=%s", transactionID)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	method, endpoint := operationRequest(p.config.Endpoint, p.config.Operations.Cancel, defaultCancelOperation, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint, nil)
+	if perr != nil {
+		return nil, perr
+	}
+
+	var response struct {
+		PaymentID string `json:"paymentId"`
+		State     string `json:"state"`
+		Value     struct {
+			Amount       string `json:"amount"`
+			CurrencyCode string `json:"currencyCode"`
+		} `json:"value"`
+		ProcessedAt int64 `json:"processedAt"`
+	}
+	if err := decodeResponse(respBody, &response, p.config.StrictResponse); err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Failed to parse cancel response: " + err.Error(),
+			Provider:  p.Name(),
+			Retryable: false,
+		}).WithCause(err)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(response.State), "CANCELLED") {
 		return nil, &domain.PaymentError{
 			Code:      domain.ErrProviderInvalidResp,
-			Message:   "Missing currency in provider response",
+			Message:   "Invalid cancel state from provider: " + response.State,
 			Provider:  p.Name(),
 			Retryable: false,
 		}
 	}
 
+	currency, perr := responseCurrency(p.config, response.Value.CurrencyCode)
+	if perr != nil {
+		return nil, perr
+	}
+	amount, err := parseProviderBAmount(response.Value.Amount, p.config.DecimalsFor(string(currency)))
+	if err != nil {
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrProviderInvalidResp,
+			Message:   "Invalid amount format in cancel response: " + err.Error(),
+			Provider:  p.Name(),
+			Retryable: false,
+		}).WithCause(err)
+	}
+
 	return &domain.Payment{
-		ID:        response.PaymentID,
-		Amount:    amount,
-		Currency:  domain.Currency(response.Value.CurrencyCode),
-		Status:    status,
-		Provider:  p.Name(),
-		Timestamp: time.Unix(response.ProcessedAt/1000, 0),
+		ID:              response.PaymentID,
+		Amount:          domain.RoundAmount(amount),
+		Currency:        currency,
+		Status:          domain.StatusCancelled,
+		Provider:        p.Name(),
+		Timestamp:       time.Unix(response.ProcessedAt/1000, 0),
+		TransactionID:   transactionID,
+		ProviderRawData: providerRawData(respBody, p.config.OmitRawData),
 	}, nil
 }
+
+// GetPaymentStatus queries Provider B for the current status of a payment
+func (p *ProviderB) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	logger.WithContext(ctx).Debug("[ProviderB] Querying payment status: transaction=%s", transactionID)
+
+	ctx, cancel := withProviderTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	method, endpoint := statusRequest(ctx, p.config, transactionID)
+	respBody, perr := callProvider(ctx, p.httpClient, p.config, method, endpoint, nil)
+	if perr != nil {
+		return nil, perr
+	}
+
+	payment, perr := p.parsePaymentResponse(respBody)
+	if perr != nil {
+		return nil, perr
+	}
+	return keepPollURL(ctx, payment), nil
+}
@@ -3,7 +3,9 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,6 +43,40 @@ func TestProviderB_ProcessPayment_Extended(t *testing.T) {
 			expectedError:  false,
 			expectedStatus: domain.StatusApproved,
 		},
+		{
+			name:     "float noise in requested amount",
+			amount:   0.1 + 0.2,
+			currency: "USD",
+			mockResponse: map[string]interface{}{
+				"paymentId": "PAY-TEST-104",
+				"state":     "SUCCESS",
+				"value": map[string]interface{}{
+					"amount":       "0.30",
+					"currencyCode": "USD",
+				},
+				"processedAt": 1705318200000,
+			},
+			mockStatus:     http.StatusOK,
+			expectedError:  false,
+			expectedStatus: domain.StatusApproved,
+		},
+		{
+			name:     "amount mismatch in response",
+			amount:   100.00,
+			currency: "USD",
+			mockResponse: map[string]interface{}{
+				"paymentId": "PAY-TEST-105",
+				"state":     "SUCCESS",
+				"value": map[string]interface{}{
+					"amount":       "10.00",
+					"currencyCode": "USD",
+				},
+				"processedAt": 1705318200000,
+			},
+			mockStatus:    http.StatusOK,
+			expectedError: true,
+			errorCode:     domain.ErrProviderInvalidResp,
+		},
 		{
 			name:     "payment with decimal amount",
 			amount:   100.50,
@@ -100,7 +136,7 @@ func TestProviderB_ProcessPayment_Extended(t *testing.T) {
 			delay:         6 * time.Second, // Greater than client timeout
 			mockStatus:    http.StatusOK,
 			expectedError: true,
-			errorCode:     domain.ErrNetworkError,
+			errorCode:     domain.ErrProviderTimeout,
 		},
 		{
 			name:     "invalid amount in response",
@@ -257,3 +293,823 @@ func TestProviderB_ProcessPayment_Extended(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderB_ProcessPayment_Accepted(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		resp := httpclient.NewMockResponse(http.StatusAccepted, nil)
+		resp.Header.Set("Location", "/payments/ASYNC-B-1")
+		return resp, nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/process",
+		Timeout:   5 * time.Second,
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.Status != domain.StatusPending {
+		t.Errorf("expected status %v, got %v", domain.StatusPending, payment.Status)
+	}
+	if payment.PollURL != "http://test-provider-b.com/payments/ASYNC-B-1" {
+		t.Errorf("expected poll URL to be captured, got %q", payment.PollURL)
+	}
+
+	// An accepted response without a Location cannot be polled
+	client = httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusAccepted, nil), nil
+	})
+	provider = NewProviderB(cfg, client)
+	if _, err := provider.ProcessPayment(context.Background(), 100.00, "USD"); err == nil || err.Code != domain.ErrProviderInvalidResp {
+		t.Errorf("expected %s for missing Location, got %v", domain.ErrProviderInvalidResp, err)
+	}
+}
+
+func TestProviderB_ProcessPayment_StrictResponse(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"paymentId": "PAY-STRICT-1",
+		"state":     "SUCCESS",
+		"value": map[string]interface{}{
+			"amount":       "100.00",
+			"currencyCode": "USD",
+			"exchangeRate": "1.0",
+		},
+		"processedAt": 1705318200000,
+	})
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	for _, strict := range []bool{false, true} {
+		cfg := config.PaymentProviderConfig{
+			Name:           "ProviderB",
+			Endpoint:       "http://test-provider-b.com",
+			MaxAmount:      10000,
+			StrictResponse: strict,
+		}
+		provider := NewProviderB(cfg, client)
+
+		_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+		if !strict && err != nil {
+			t.Errorf("lenient mode: unexpected error: %v", err)
+		}
+		if strict && (err == nil || err.Code != domain.ErrProviderInvalidResp) {
+			t.Errorf("strict mode: expected %s, got %v", domain.ErrProviderInvalidResp, err)
+		}
+	}
+}
+
+func TestProviderB_GetPaymentStatus(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			t.Errorf("expected GET request, got %s", req.Method)
+		}
+		if req.URL.String() != "http://test-provider-b.com/payments/PAY-STATUS-1" {
+			t.Errorf("unexpected status URL: %s", req.URL)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-STATUS-1",
+			"state":     "SUCCESS",
+			"value": map[string]interface{}{
+				"amount":       "50.75",
+				"currencyCode": "EUR",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/payments",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	payment, err := provider.GetPaymentStatus(context.Background(), "PAY-STATUS-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.ID != "PAY-STATUS-1" || payment.Status != domain.StatusApproved || payment.Amount != 50.75 {
+		t.Errorf("expected approved payment PAY-STATUS-1 of 50.75, got %+v", payment)
+	}
+}
+
+func TestProviderB_CancelPayment(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", req.Method)
+		}
+		if req.URL.String() != "http://test-provider-b.com/payments/PAY-CANCEL-1/cancel" {
+			t.Errorf("unexpected cancel URL: %s", req.URL)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-CANCEL-1",
+			"state":     "CANCELLED",
+			"value": map[string]interface{}{
+				"amount":       "50.75",
+				"currencyCode": "EUR",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/payments",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	payment, err := provider.CancelPayment(context.Background(), "PAY-CANCEL-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.TransactionID != "PAY-CANCEL-1" || payment.Status != domain.StatusCancelled || payment.Amount != 50.75 {
+		t.Errorf("expected cancelled payment PAY-CANCEL-1 of 50.75, got %+v", payment)
+	}
+}
+
+func TestProviderB_ProcessPayment_ProviderTimeout(t *testing.T) {
+	// The transport blocks until the request context ends, like a provider that never answers
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	client.Timeout = time.Minute
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com",
+		Timeout:   20 * time.Millisecond,
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	start := time.Now()
+	_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+	elapsed := time.Since(start)
+
+	if err == nil || err.Code != domain.ErrProviderTimeout {
+		t.Errorf("expected %s, got %v", domain.ErrProviderTimeout, err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected the provider timeout to apply, took %v", elapsed)
+	}
+}
+
+func TestProviderB_ProcessPayment_AuthenticationFailed(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				return httpclient.NewMockResponse(status, nil), nil
+			})
+			cfg := config.PaymentProviderConfig{
+				Name:      "ProviderB",
+				Endpoint:  "http://test-provider-b.com",
+				MaxAmount: 10000,
+			}
+			provider := NewProviderB(cfg, client)
+
+			_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if err == nil || err.Code != domain.ErrAuthenticationFailed {
+				t.Fatalf("expected %s, got %v", domain.ErrAuthenticationFailed, err)
+			}
+			if err.Retryable {
+				t.Error("expected authentication failures to be non-retryable")
+			}
+			if err.HTTPStatus != status {
+				t.Errorf("expected HTTP status %d, got %d", status, err.HTTPStatus)
+			}
+		})
+	}
+}
+
+func TestProviderB_ProcessPayment_CallerDeadline(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com",
+		Timeout:   30 * time.Second,
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.ProcessPayment(ctx, 100.00, "USD")
+	elapsed := time.Since(start)
+
+	if err == nil || err.Code != domain.ErrProviderTimeout {
+		t.Errorf("expected %s, got %v", domain.ErrProviderTimeout, err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected the caller's deadline to win over the provider timeout, took %v", elapsed)
+	}
+}
+
+func TestProviderB_ProcessPayment_Pending(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-PENDING-1",
+			"state":     "PENDING",
+			"value": map[string]interface{}{
+				"amount":       "25.00",
+				"currencyCode": "EUR",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/payments",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	payment, err := provider.ProcessPayment(context.Background(), 25.00, "EUR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.ID != "PAY-PENDING-1" || payment.Status != domain.StatusPending {
+		t.Errorf("expected pending payment PAY-PENDING-1, got %+v", payment)
+	}
+}
+
+func TestProviderB_ProcessPayment_CancelledContext(t *testing.T) {
+	calls := 0
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httpclient.NewMockResponse(http.StatusOK, nil), nil
+	})
+
+	cfg := config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/payments",
+		MaxAmount: 10000,
+	}
+	provider := NewProviderB(cfg, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := provider.ProcessPayment(ctx, 100.00, "USD")
+	if err == nil || err.Code != domain.ErrCancelled {
+		t.Errorf("expected %s, got %v", domain.ErrCancelled, err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no HTTP attempt, got %d", calls)
+	}
+}
+
+func TestProviderB_ProcessPayment_Settlement(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       map[string]interface{}
+		expectedFee float64
+		expectedNet float64
+		expectedErr string
+	}{
+		{
+			name:        "fee and net reported",
+			value:       map[string]interface{}{"fee": "3.20", "netAmount": "96.80"},
+			expectedFee: 3.2,
+			expectedNet: 96.8,
+		},
+		{
+			name:  "fields absent",
+			value: map[string]interface{}{},
+		},
+		{
+			name:        "malformed fee",
+			value:       map[string]interface{}{"fee": "abc"},
+			expectedErr: domain.ErrProviderInvalidResp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "USD",
+			}
+			for key, v := range tt.value {
+				value[key] = v
+			}
+			body, _ := json.Marshal(map[string]interface{}{
+				"paymentId":   "PAY-FEE-1",
+				"state":       "SUCCESS",
+				"value":       value,
+				"processedAt": 1705318200000,
+			})
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+
+			cfg := config.PaymentProviderConfig{
+				Name:      "ProviderB",
+				Endpoint:  "http://test-provider-b.com",
+				MaxAmount: 10000,
+			}
+			provider := NewProviderB(cfg, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if tt.expectedErr != "" {
+				if err == nil || err.Code != tt.expectedErr {
+					t.Errorf("expected %s, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Fee != tt.expectedFee || payment.NetAmount != tt.expectedNet {
+				t.Errorf("expected fee %.2f and net %.2f, got fee %.2f and net %.2f",
+					tt.expectedFee, tt.expectedNet, payment.Fee, payment.NetAmount)
+			}
+		})
+	}
+}
+
+func TestProviderB_ProcessPayment_Limits(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-LIMITS-1",
+			"state":     "SUCCESS",
+			"value": map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "EUR",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	tests := []struct {
+		name      string
+		amount    float64
+		currency  string
+		errorCode string
+	}{
+		{name: "within limits", amount: 100.00, currency: "EUR"},
+		{name: "below minimum", amount: 4.99, currency: "EUR", errorCode: domain.ErrInvalidAmount},
+		{name: "currency not in provider list", amount: 100.00, currency: "GBP", errorCode: domain.ErrInvalidCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.PaymentProviderConfig{
+				Name:                "ProviderB",
+				Endpoint:            "http://test-provider-b.com",
+				MinAmount:           5.00,
+				MaxAmount:           10000,
+				SupportedCurrencies: []string{"EUR"},
+			}
+			provider := NewProviderB(cfg, client)
+
+			_, err := provider.ProcessPayment(context.Background(), tt.amount, tt.currency)
+			if tt.errorCode == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.errorCode {
+				t.Errorf("expected %s, got %v", tt.errorCode, err)
+			}
+		})
+	}
+}
+
+func TestProviderB_ProcessPayment_RequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       float64
+		currency     string
+		details      domain.PaymentDetails
+		expectedBody string
+	}{
+		{
+			name:         "amount formatted with two decimals",
+			amount:       100,
+			currency:     "USD",
+			expectedBody: `{"paymentValue":{"amount":"100.00","currencyCode":"USD"}}`,
+		},
+		{
+			name:         "float noise is rounded",
+			amount:       0.1 + 0.2,
+			currency:     "USD",
+			expectedBody: `{"paymentValue":{"amount":"0.30","currencyCode":"USD"}}`,
+		},
+		{
+			name:         "currency without decimals",
+			amount:       1500,
+			currency:     "JPY",
+			expectedBody: `{"paymentValue":{"amount":"1500","currencyCode":"JPY"}}`,
+		},
+		{
+			name:         "currency without decimals is rounded",
+			amount:       1499.6,
+			currency:     "JPY",
+			expectedBody: `{"paymentValue":{"amount":"1500","currencyCode":"JPY"}}`,
+		},
+		{
+			name:     "with reference and metadata",
+			amount:   50.75,
+			currency: "USD",
+			details: domain.PaymentDetails{
+				ReferenceID: "INV-7",
+				Metadata:    map[string]string{"customer": "C-1"},
+			},
+			expectedBody: `{"paymentValue":{"amount":"50.75","currencyCode":"USD"},"referenceId":"INV-7","metadata":{"customer":"C-1"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				sent = string(body)
+				// Echo the amount as sent, as the provider would
+				var request providerBRequest
+				if err := json.Unmarshal(body, &request); err != nil {
+					return nil, err
+				}
+				resp, _ := json.Marshal(map[string]interface{}{
+					"paymentId":   "PAY-BODY-1",
+					"state":       "SUCCESS",
+					"value":       request.PaymentValue,
+					"processedAt": 1705318200000,
+				})
+				return httpclient.NewMockResponse(http.StatusOK, resp), nil
+			})
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:                "ProviderB",
+				Endpoint:            "http://test-provider-b.com",
+				MaxAmount:           10000,
+				SupportedCurrencies: []string{"USD", "JPY"},
+				AmountDecimals:      map[string]int{"JPY": 0},
+			}, client)
+
+			ctx := domain.WithPaymentDetails(context.Background(), tt.details)
+			if _, err := provider.ProcessPayment(ctx, tt.amount, tt.currency); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sent != tt.expectedBody {
+				t.Errorf("expected request body %s, got %s", tt.expectedBody, sent)
+			}
+		})
+	}
+}
+
+func TestProviderB_RefundPayment_RequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       float64
+		expectedBody string
+	}{
+		{name: "amount formatted with two decimals", amount: 10, expectedBody: `{"amount":"10.00"}`},
+		{name: "float noise is rounded", amount: 10.1 + 0.2, expectedBody: `{"amount":"10.30"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				sent = string(body)
+				var request providerBRefundRequest
+				if err := json.Unmarshal(body, &request); err != nil {
+					return nil, err
+				}
+				resp, _ := json.Marshal(map[string]interface{}{
+					"refundId":    "REF-BODY-1",
+					"state":       "REFUNDED",
+					"value":       providerBValue{Amount: request.Amount, CurrencyCode: "USD"},
+					"processedAt": 1705318200000,
+				})
+				return httpclient.NewMockResponse(http.StatusOK, resp), nil
+			})
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:      "ProviderB",
+				Endpoint:  "http://test-provider-b.com",
+				MaxAmount: 10000,
+			}, client)
+
+			if _, err := provider.RefundPayment(context.Background(), "PAY-BODY-1", tt.amount); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sent != tt.expectedBody {
+				t.Errorf("expected request body %s, got %s", tt.expectedBody, sent)
+			}
+		})
+	}
+}
+
+func TestProviderB_ProcessPayment_ProviderRawData(t *testing.T) {
+	// Keys are sorted so the body matches how the decoded data marshals back
+	const responseBody = `{"paymentId":"PAY-RAW-1","processedAt":1705318200000,"state":"SUCCESS","value":{"amount":"100.50","currencyCode":"USD","fee":"1.25"}}`
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusOK, []byte(responseBody)), nil
+	})
+
+	tests := []struct {
+		name        string
+		omitRawData bool
+		expected    string
+	}{
+		{name: "raw data round-trips", expected: responseBody},
+		{name: "raw data omitted", omitRawData: true, expected: "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:        "ProviderB",
+				Endpoint:    "http://test-provider-b.com",
+				MaxAmount:   10000,
+				OmitRawData: tt.omitRawData,
+			}, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.50, "USD")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			raw, marshalErr := json.Marshal(payment.ProviderRawData)
+			if marshalErr != nil {
+				t.Fatalf("failed to marshal raw data: %v", marshalErr)
+			}
+			if string(raw) != tt.expected {
+				t.Errorf("expected raw data %s, got %s", tt.expected, raw)
+			}
+		})
+	}
+}
+
+func TestProviderB_Authorization(t *testing.T) {
+	var headers []string
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		headers = append(headers, req.Header.Get("Authorization"))
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-AUTH-1",
+			"state":     "SUCCESS",
+			"value": map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "USD",
+			},
+			"processedAt": 1705318200000,
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+	provider := NewProviderB(config.PaymentProviderConfig{
+		Name:      "ProviderB",
+		Endpoint:  "http://test-provider-b.com/payments",
+		MaxAmount: 10000,
+		Auth:      config.AuthConfig{Type: config.AuthBearer, Token: "tok-456"},
+	}, client)
+
+	if _, err := provider.ProcessPayment(context.Background(), 100.00, "USD"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.GetPaymentStatus(context.Background(), "PAY-AUTH-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, header := range headers {
+		if header != "Bearer tok-456" {
+			t.Errorf("request %d: expected Authorization %q, got %q", i+1, "Bearer tok-456", header)
+		}
+	}
+	if len(headers) != 2 {
+		t.Errorf("expected 2 requests, got %d", len(headers))
+	}
+}
+
+func TestProviderB_ProcessPayment_DeclineReasons(t *testing.T) {
+	tests := []struct {
+		name            string
+		declineCode     string
+		reason          string
+		expectedCode    string
+		expectedDetails string
+	}{
+		{name: "no reason", expectedCode: domain.ErrCardDeclined},
+		{name: "insufficient funds", declineCode: "insufficient_funds", expectedCode: domain.ErrInsufficientFunds, expectedDetails: "insufficient_funds"},
+		{name: "expired card", declineCode: "expired_card", reason: "Card expired 01/24", expectedCode: domain.ErrCardExpired, expectedDetails: "expired_card: Card expired 01/24"},
+		{name: "unmapped reason", reason: "Suspected fraud", expectedCode: domain.ErrCardDeclined, expectedDetails: "Suspected fraud"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				response := map[string]interface{}{
+					"paymentId": "PAY-DECLINE-1",
+					"state":     "FAILED",
+					"value": map[string]interface{}{
+						"amount":       "100.00",
+						"currencyCode": "USD",
+					},
+					"processedAt": 1705318200000,
+				}
+				if tt.declineCode != "" {
+					response["declineCode"] = tt.declineCode
+				}
+				if tt.reason != "" {
+					response["reason"] = tt.reason
+				}
+				body, _ := json.Marshal(response)
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:      "ProviderB",
+				Endpoint:  "http://test-provider-b.com/payments",
+				MaxAmount: 10000,
+			}, client)
+
+			_, err := provider.ProcessPayment(context.Background(), 100.00, "USD")
+			if err == nil {
+				t.Fatal("expected a decline error")
+			}
+			if err.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, err.Code)
+			}
+			if err.Details != tt.expectedDetails {
+				t.Errorf("expected details %q, got %q", tt.expectedDetails, err.Details)
+			}
+			if tt.expectedDetails != "" && !strings.Contains(err.Message, tt.expectedDetails) {
+				t.Errorf("expected the message to include %q, got %q", tt.expectedDetails, err.Message)
+			}
+		})
+	}
+}
+
+func TestProviderB_ResponseSizeLimit(t *testing.T) {
+	oversized := []byte(`{"paymentId": "PAY-SIZE-1", "state": "SUCCESS", "note": "` + strings.Repeat("x", 512) + `"}`)
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		return httpclient.NewMockResponse(http.StatusOK, oversized), nil
+	})
+	provider := NewProviderB(config.PaymentProviderConfig{
+		Name:            "ProviderB",
+		Endpoint:        "http://test-provider-b.com/payments",
+		MaxAmount:       10000,
+		MaxResponseSize: 256,
+	}, client)
+
+	// Both the payment call and the calls made through callProvider are limited
+	if _, err := provider.ProcessPayment(context.Background(), 100.00, "USD"); err == nil || err.Code != domain.ErrProviderInvalidResp {
+		t.Errorf("payment: expected %s, got %v", domain.ErrProviderInvalidResp, err)
+	}
+	if _, err := provider.GetPaymentStatus(context.Background(), "PAY-SIZE-1"); err == nil || err.Code != domain.ErrProviderInvalidResp {
+		t.Errorf("status: expected %s, got %v", domain.ErrProviderInvalidResp, err)
+	}
+}
+
+func TestProviderB_ProcessPayment_ResponseCanonicalization(t *testing.T) {
+	tests := []struct {
+		name             string
+		state            string
+		currency         string
+		expectedCode     string
+		expectedCurrency domain.Currency
+	}{
+		{name: "lowercase state and currency", state: "success", currency: "eur", expectedCurrency: domain.EUR},
+		{name: "lowercase failure", state: "failed", currency: "EUR", expectedCode: domain.ErrCardDeclined},
+		{name: "unknown state", state: "SETTLED", currency: "EUR", expectedCode: domain.ErrProviderInvalidResp},
+		{name: "invalid currency", state: "SUCCESS", currency: "E1R", expectedCode: domain.ErrProviderInvalidResp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]interface{}{
+					"paymentId":   "PAY-CASE-1",
+					"state":       tt.state,
+					"value":       map[string]string{"amount": "100.00", "currencyCode": tt.currency},
+					"processedAt": 1705315800000,
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:      "ProviderB",
+				Endpoint:  "http://test-provider-b.com/payments",
+				MaxAmount: 10000,
+			}, client)
+
+			payment, err := provider.ProcessPayment(context.Background(), 100.00, "EUR")
+			if tt.expectedCode != "" {
+				if err == nil || err.Code != tt.expectedCode {
+					t.Fatalf("expected error code %s, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Status != domain.StatusApproved {
+				t.Errorf("expected status %s, got %s", domain.StatusApproved, payment.Status)
+			}
+			if payment.Currency != tt.expectedCurrency {
+				t.Errorf("expected currency %s, got %s", tt.expectedCurrency, payment.Currency)
+			}
+		})
+	}
+}
+
+func TestProviderB_ResponseAmountParsing(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      string
+		currency    string
+		value       float64
+		decimals    map[string]int
+		refund      bool
+		expectedErr bool
+	}{
+		{name: "two decimals", amount: "100.00"},
+		{name: "trailing zero decimals in a zero-decimal currency", amount: "1000.00", currency: "JPY", value: 1000},
+		{name: "trailing zeros beyond the currency's decimals", amount: "100.0000"},
+		{name: "significant decimals beyond trailing zeros", amount: "100.001", expectedErr: true},
+		{name: "three decimals in a three-decimal currency", amount: "100.000", decimals: map[string]int{"USD": 3}},
+		{name: "decimals in a zero-decimal currency", amount: "100.50", decimals: map[string]int{"USD": 0}, expectedErr: true},
+		{name: "whole amount", amount: "100"},
+		{name: "one decimal", amount: "100.0"},
+		{name: "not a number", amount: "NaN", expectedErr: true},
+		{name: "infinity", amount: "Inf", expectedErr: true},
+		{name: "scientific notation", amount: "1e2", expectedErr: true},
+		{name: "overflow", amount: "1e309", expectedErr: true},
+		{name: "too many decimals", amount: "100.999", expectedErr: true},
+		{name: "negative", amount: "-100.00", expectedErr: true},
+		{name: "missing whole part", amount: ".50", expectedErr: true},
+		{name: "empty", amount: "", expectedErr: true},
+		{name: "refund of NaN", amount: "NaN", refund: true, expectedErr: true},
+		{name: "refund with too many decimals", amount: "100.999", refund: true, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := "SUCCESS"
+			if tt.refund {
+				state = "REFUNDED"
+			}
+			currency, value := tt.currency, tt.value
+			if currency == "" {
+				currency = "USD"
+			}
+			if value == 0 {
+				value = 100
+			}
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]interface{}{
+					"paymentId":   "PAY-AMOUNT-1",
+					"refundId":    "REF-AMOUNT-1",
+					"state":       state,
+					"value":       map[string]string{"amount": tt.amount, "currencyCode": currency},
+					"processedAt": 1705315800000,
+				})
+				return httpclient.NewMockResponse(http.StatusOK, body), nil
+			})
+			provider := NewProviderB(config.PaymentProviderConfig{
+				Name:                "ProviderB",
+				Endpoint:            "http://test-provider-b.com/payments",
+				MaxAmount:           10000,
+				SupportedCurrencies: []string{"USD", "JPY"},
+				AmountDecimals:      tt.decimals,
+			}, client)
+
+			var payment *domain.Payment
+			var err *domain.PaymentError
+			if tt.refund {
+				payment, err = provider.RefundPayment(context.Background(), "PAY-AMOUNT-1", value)
+			} else {
+				payment, err = provider.ProcessPayment(context.Background(), value, currency)
+			}
+			if tt.expectedErr {
+				if err == nil || err.Code != domain.ErrProviderInvalidResp {
+					t.Fatalf("expected error code %s, got %v", domain.ErrProviderInvalidResp, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Amount != value {
+				t.Errorf("expected amount %v, got %v", value, payment.Amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a token bucket rate limiter. Tokens refill continuously at the
+// configured rate up to the burst size; each request consumes one token.
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+// newTokenBucket creates a full bucket allowing requestsPerSecond with the given burst,
+// refilling as clock advances
+func newTokenBucket(requestsPerSecond, burstSize int, clock Clock) *tokenBucket {
+	burst := float64(burstSize)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(requestsPerSecond),
+		burst:  burst,
+		tokens: burst,
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// newRetryBudget creates a full bucket of retriesPerMinute tokens that refills over a
+// minute of clock time
+func newRetryBudget(retriesPerMinute int, clock Clock) *tokenBucket {
+	budget := float64(retriesPerMinute)
+	return &tokenBucket{
+		rate:   budget / 60,
+		burst:  budget,
+		tokens: budget,
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// refill adds the tokens accumulated since the last update. Callers must hold the mutex.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// Allow consumes a token if one is available without waiting
+func (b *tokenBucket) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refill(b.clock.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns the number of tokens that can be consumed without waiting
+func (b *tokenBucket) Available() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refill(b.clock.Now())
+	if b.tokens < 0 {
+		return 0
+	}
+	return b.tokens
+}
+
+// Wait blocks until a token is available or the context is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	b.mutex.Lock()
+	b.refill(b.clock.Now())
+	// Reserve a token; a negative balance is the queue of waiters ahead of us
+	b.tokens--
+	if b.tokens >= 0 {
+		b.mutex.Unlock()
+		return nil
+	}
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mutex.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the reservation back so later callers don't wait for it
+		b.mutex.Lock()
+		b.tokens++
+		b.mutex.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// Constructor creates a provider from its configuration
+type Constructor func(config.PaymentProviderConfig, *http.Client) repository.PaymentProvider
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Constructor)
+)
+
+// Register makes a provider constructor available to the factory under name.
+// It is meant to be called from init and panics if name is registered twice
+// or the constructor is nil.
+func Register(name string, constructor Constructor) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if constructor == nil {
+		panic("providers: Register constructor is nil for " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("providers: Register called twice for " + name)
+	}
+	registry[name] = constructor
+}
+
+// newRegisteredProvider creates the provider registered under name
+func newRegisteredProvider(name string, cfg config.PaymentProviderConfig, client *http.Client) (repository.PaymentProvider, *domain.PaymentError) {
+	registryMutex.RLock()
+	constructor, exists := registry[name]
+	registryMutex.RUnlock()
+
+	if !exists {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderNotFound,
+			Message: fmt.Sprintf("Provider %s not supported", name),
+		}
+	}
+	return constructor(cfg, client), nil
+}
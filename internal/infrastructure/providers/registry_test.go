@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// stubProvider approves every payment without calling out
+type stubProvider struct {
+	name string
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	return &domain.Payment{ID: "STUB-1", Amount: amount, Currency: domain.Currency(currency), Status: domain.StatusApproved, Provider: p.name}, nil
+}
+
+func (p *stubProvider) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	return &domain.Payment{ID: transactionID, Amount: amount, Status: domain.StatusRefunded, Provider: p.name}, nil
+}
+
+func (p *stubProvider) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	return &domain.Payment{ID: transactionID, Status: domain.StatusApproved, Provider: p.name}, nil
+}
+
+func (p *stubProvider) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	return &domain.Payment{ID: transactionID, Status: domain.StatusCancelled, Provider: p.name}, nil
+}
+
+func (p *stubProvider) GetMetadata() map[string]interface{} {
+	return map[string]interface{}{"name": p.name}
+}
+
+// registerStub registers stubProvider once per test binary, even with -count > 1
+var registerStub sync.Once
+
+func TestRegister(t *testing.T) {
+	registerStub.Do(func() {
+		Register("ProviderStub", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+			return &stubProvider{name: cfg.Name}
+		})
+	})
+
+	t.Run("factory uses registered constructor", func(t *testing.T) {
+		cfg := &config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderStub": {Name: "ProviderStub", Endpoint: "http://stub", MaxAmount: 1000},
+			},
+		}
+		factory := NewFactory(cfg, &http.Client{})
+
+		payment, err := factory.ProcessPayment(context.Background(), "ProviderStub", 10, "USD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if payment.ID != "STUB-1" || payment.Provider != "ProviderStub" {
+			t.Errorf("expected payment from the stub provider, got %+v", payment)
+		}
+
+		provider, createErr := factory.CreateProvider("ProviderStub")
+		if createErr != nil {
+			t.Fatalf("unexpected error: %v", createErr)
+		}
+		if _, ok := provider.(*stubProvider); !ok {
+			t.Errorf("expected *stubProvider, got %T", provider)
+		}
+	})
+
+	t.Run("unregistered provider", func(t *testing.T) {
+		cfg := &config.Config{
+			Providers: map[string]config.PaymentProviderConfig{
+				"ProviderUnregistered": {Name: "ProviderUnregistered", Endpoint: "http://unregistered", MaxAmount: 1000},
+			},
+		}
+		factory := NewFactory(cfg, &http.Client{})
+
+		_, err := factory.ProcessPayment(context.Background(), "ProviderUnregistered", 10, "USD")
+		if err == nil || err.Code != domain.ErrProviderNotFound {
+			t.Errorf("expected %s, got %v", domain.ErrProviderNotFound, err)
+		}
+	})
+
+	t.Run("duplicate registration panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic on duplicate registration")
+			}
+		}()
+		Register("ProviderA", func(cfg config.PaymentProviderConfig, client *http.Client) repository.PaymentProvider {
+			return &stubProvider{name: cfg.Name}
+		})
+	})
+}
@@ -51,6 +51,22 @@ func (r *Repository) ProcessPayment(ctx context.Context, providerName string, am
 	return payment, nil
 }
 
+// GetPaymentStatus queries the specified provider for the current status of a payment
+func (r *Repository) GetPaymentStatus(ctx context.Context, providerName string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	logger.Debug("Repository: Querying payment status with provider %s: transaction=%s", providerName, transactionID)
+
+	provider, exists := r.providers[providerName]
+	if !exists {
+		logger.Error("Repository: Provider %s not found", providerName)
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderNotFound,
+			Message: fmt.Sprintf("Provider %s not found", providerName),
+		}
+	}
+
+	return provider.GetPaymentStatus(ctx, transactionID)
+}
+
 // GetProviderMetadata returns metadata for a specific provider
 func (r *Repository) GetProviderMetadata(providerName string) map[string]interface{} {
 	logger.Debug("Repository: Fetching metadata for provider: %s", providerName)
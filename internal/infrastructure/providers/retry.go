@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+// maxAttempts returns the number of attempts allowed by the retry policy
+func maxAttempts(policy config.RetryPolicy) int {
+	if policy.MaxAttempts < 1 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+// isRetryable reports whether a failed attempt should be retried under the policy.
+// Only errors flagged as retryable by the provider are considered; when the policy
+// lists error codes or HTTP status codes, the error must match one of them.
+// Connection resets are transient and always retried regardless of the policy lists.
+func isRetryable(policy config.RetryPolicy, err *domain.PaymentError) bool {
+	if err == nil || !err.Retryable {
+		return false
+	}
+
+	if isConnectionReset(err) {
+		return true
+	}
+
+	if len(policy.RetryableErrors) == 0 && len(policy.RetryableCodes) == 0 {
+		return true
+	}
+
+	for _, code := range policy.RetryableErrors {
+		if code == err.Code {
+			return true
+		}
+	}
+	for _, status := range policy.RetryableCodes {
+		if err.HTTPStatus != 0 && status == err.HTTPStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// backoffDelay returns the delay before the next attempt, doubling the initial
+// delay for every attempt already made and capping it at the policy's MaxDelay
+func backoffDelay(policy config.RetryPolicy, attempt int) time.Duration {
+	delay := policy.InitialDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if policy.MaxDelay > 0 && delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		return policy.MaxDelay
+	}
+	return delay
+}
+
+// retryDelay returns the delay before the next attempt. With jitter enabled it is
+// drawn uniformly from [0, backoffDelay] using the factory's random source.
+func (f *Factory) retryDelay(policy config.RetryPolicy, attempt int) time.Duration {
+	delay := backoffDelay(policy, attempt)
+	if !policy.JitterEnabled || delay <= 0 {
+		return delay
+	}
+
+	f.randomMutex.Lock()
+	defer f.randomMutex.Unlock()
+	return time.Duration(f.random.Int63n(int64(delay) + 1))
+}
+
+// takeRetry consumes a retry from the provider's retry budget, reporting false when the
+// budget is exhausted. Providers without a RetryBudget always allow the retry.
+func (f *Factory) takeRetry(providerName string) bool {
+	budget := f.config.Providers[providerName].RetryPolicy.RetryBudget
+	if budget <= 0 {
+		return true
+	}
+
+	f.mutex.Lock()
+	bucket, exists := f.retryBudgets[providerName]
+	if !exists {
+		bucket = newRetryBudget(budget, f.clock)
+		f.retryBudgets[providerName] = bucket
+	}
+	f.mutex.Unlock()
+
+	allowed := bucket.Allow()
+	f.metrics.retryBudget.Set(bucket.Available(), providerName)
+	return allowed
+}
+
+// refreshRetryBudgets updates the remaining retry budget metric of every provider,
+// since budgets refill between retries
+func (f *Factory) refreshRetryBudgets() {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	for providerName, bucket := range f.retryBudgets {
+		f.metrics.retryBudget.Set(bucket.Available(), providerName)
+	}
+}
+
+// retryBudgetError reports a failed payment that was not retried because the
+// provider's retry budget is exhausted
+func retryBudgetError(providerName string, cause *domain.PaymentError) *domain.PaymentError {
+	return (&domain.PaymentError{
+		Code:      domain.ErrTooManyRetries,
+		Message:   fmt.Sprintf("Retry budget of provider %s is exhausted: %s", providerName, cause.Message),
+		Provider:  providerName,
+		Retryable: false,
+	}).WithCause(cause)
+}
+
+// cancelledError reports that an operation was abandoned because its context ended
+func cancelledError(providerName string, err error) *domain.PaymentError {
+	return (&domain.PaymentError{
+		Code:      domain.ErrCancelled,
+		Message:   "Request cancelled: " + err.Error(),
+		Provider:  providerName,
+		Retryable: false,
+	}).WithCause(err)
+}
+
+// sleepContext waits for the given delay, returning early with the context's
+// error if it is done first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
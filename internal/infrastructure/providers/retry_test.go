@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	policy := config.RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: 100 * time.Millisecond},
+		{attempt: 2, expected: 200 * time.Millisecond},
+		{attempt: 4, expected: 800 * time.Millisecond},
+		{attempt: 5, expected: time.Second},
+		{attempt: 10, expected: time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(policy, tt.attempt); got != tt.expected {
+			t.Errorf("attempt %d: expected %v, got %v", tt.attempt, tt.expected, got)
+		}
+	}
+}
+
+func TestFactory_RetryDelay_Jitter(t *testing.T) {
+	policy := config.RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	newFactory := func(seed int64) *Factory {
+		return NewFactory(&config.Config{}, &http.Client{}, WithRandSource(rand.NewSource(seed)))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		factory := newFactory(1)
+		for attempt := 1; attempt <= 5; attempt++ {
+			if got, expected := factory.retryDelay(policy, attempt), backoffDelay(policy, attempt); got != expected {
+				t.Errorf("attempt %d: expected %v without jitter, got %v", attempt, expected, got)
+			}
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		jittered := policy
+		jittered.JitterEnabled = true
+
+		first, second := newFactory(42), newFactory(42)
+		varied := false
+		for attempt := 1; attempt <= 8; attempt++ {
+			ceiling := backoffDelay(jittered, attempt)
+			got := first.retryDelay(jittered, attempt)
+			if got < 0 || got > ceiling {
+				t.Errorf("attempt %d: expected a delay in [0, %v], got %v", attempt, ceiling, got)
+			}
+			if got != ceiling {
+				varied = true
+			}
+			// The same seed produces the same delays
+			if again := second.retryDelay(jittered, attempt); again != got {
+				t.Errorf("attempt %d: expected seeded delay %v, got %v", attempt, got, again)
+			}
+		}
+		if !varied {
+			t.Error("expected jitter to change at least one delay")
+		}
+	})
+
+	t.Run("zero delay", func(t *testing.T) {
+		factory := newFactory(1)
+		if got := factory.retryDelay(config.RetryPolicy{JitterEnabled: true}, 1); got != 0 {
+			t.Errorf("expected no delay, got %v", got)
+		}
+	})
+}
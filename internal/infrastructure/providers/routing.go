@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// trackInFlight counts a request against the provider's load until the returned
+// function is called
+func (f *Factory) trackInFlight(name string) func() {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return func() {}
+	}
+
+	atomic.AddInt64(&state.inFlight, 1)
+	return func() { atomic.AddInt64(&state.inFlight, -1) }
+}
+
+// InFlight returns the number of payments currently being processed by the provider
+func (f *Factory) InFlight(name string) int64 {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return 0
+	}
+	return atomic.LoadInt64(&state.inFlight)
+}
+
+// SuccessRate returns the fraction of the provider's recorded requests that succeeded
+// and how many were recorded. Both are zero for a provider without state.
+func (f *Factory) SuccessRate(name string) (float64, int64) {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return 0, 0
+	}
+	return state.SuccessRate(), state.TotalRequests()
+}
+
+// AverageLatency returns the provider's moving average response time, or zero for a
+// provider that has not been called yet
+func (f *Factory) AverageLatency(name string) time.Duration {
+	f.mutex.RLock()
+	state, exists := f.providerStates[name]
+	f.mutex.RUnlock()
+	if !exists {
+		return 0
+	}
+
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return state.AvgLatency
+}
+
+// SelectProvider chooses a provider among equivalent candidates using the configured
+// Global.Routing strategy. Unknown, unavailable and in-maintenance candidates are skipped.
+func (f *Factory) SelectProvider(candidates []string) (string, *domain.PaymentError) {
+	var available []string
+	for _, name := range candidates {
+		if f.IsProviderAvailable(name) {
+			available = append(available, name)
+		}
+	}
+
+	if len(available) == 0 {
+		logger.Error("No available provider among %v", candidates)
+		return "", &domain.PaymentError{
+			Code:      domain.ErrProviderUnavailable,
+			Message:   fmt.Sprintf("No available provider among %s", strings.Join(candidates, ", ")),
+			Retryable: true,
+		}
+	}
+	return f.pickProvider(available, f.config.Global.Routing.Strategy)
+}
+
+// ProcessPaymentBalanced processes a payment through one of the candidate providers,
+// chosen by strategy (one of the config.Routing* strategies) among the candidates
+// eligible for the payment. Candidates that are unavailable, in maintenance, or
+// cannot take the amount or currency are filtered out first.
+func (f *Factory) ProcessPaymentBalanced(ctx context.Context, amount float64, currency string, candidates []string, strategy string) (*domain.Payment, *domain.PaymentError) {
+	var eligible []string
+	for _, name := range candidates {
+		if err := f.CanProcess(repository.PaymentRequest{Provider: name, Amount: amount, Currency: currency}); err != nil {
+			logger.WithContext(ctx).Debug("Provider %s is not eligible: %s", name, err.Message)
+			continue
+		}
+		eligible = append(eligible, name)
+	}
+
+	if len(eligible) == 0 {
+		logger.WithContext(ctx).Error("No eligible provider among %v for %.2f %s", candidates, amount, currency)
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderUnavailable,
+			Message: fmt.Sprintf("No eligible provider among %s for %.2f %s", strings.Join(candidates, ", "), amount, currency),
+		}
+	}
+
+	selected, err := f.pickProvider(eligible, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return f.ProcessPayment(ctx, selected, amount, currency)
+}
+
+// pickProvider applies a routing strategy to a non-empty list of usable providers.
+// Ties go to the earlier provider; providers without latency samples yet count as the
+// fastest so that they get tried.
+func (f *Factory) pickProvider(providers []string, strategy string) (string, *domain.PaymentError) {
+	selected := providers[0]
+	switch strategy {
+	case "", config.RoutingFirstAvailable:
+	case config.RoutingLeastLoad:
+		selectedLoad := f.InFlight(selected)
+		for _, name := range providers[1:] {
+			if load := f.InFlight(name); load < selectedLoad {
+				selected, selectedLoad = name, load
+			}
+		}
+	case config.RoutingRoundRobin:
+		next := atomic.AddUint64(&f.roundRobin, 1) - 1
+		selected = providers[next%uint64(len(providers))]
+	case config.RoutingLowestLatency:
+		selectedLatency := f.AverageLatency(selected)
+		for _, name := range providers[1:] {
+			if latency := f.AverageLatency(name); latency < selectedLatency {
+				selected, selectedLatency = name, latency
+			}
+		}
+	default:
+		return "", &domain.PaymentError{
+			Code:    domain.ErrInvalidConfiguration,
+			Message: fmt.Sprintf("Unknown routing strategy %q", strategy),
+		}
+	}
+
+	logger.Debug("Selected provider %s among %v using strategy %q", selected, providers, strategy)
+	return selected, nil
+}
+
+// ProcessPaymentWithFailover processes a payment through the providers of the named
+// route in order, skipping unavailable ones, until one succeeds. Failover continues
+// after retryable errors and unavailable providers only; any other error, such as a
+// declined card, is returned immediately. The Provider of the returned request names
+// the provider that handled the payment, or the last one tried if all failed.
+func (f *Factory) ProcessPaymentWithFailover(ctx context.Context, route string, amount float64, currency string) repository.PaymentResult {
+	result := repository.PaymentResult{
+		Request: repository.PaymentRequest{Amount: amount, Currency: currency},
+	}
+
+	candidates, exists := f.config.Global.Routing.Routes[route]
+	if !exists {
+		result.Error = &domain.PaymentError{
+			Code:    domain.ErrInvalidConfiguration,
+			Message: fmt.Sprintf("Route %s is not configured", route),
+		}
+		return result
+	}
+
+	for _, name := range candidates {
+		if !f.IsProviderAvailable(name) {
+			logger.WithContext(ctx).Info("Route %s: skipping unavailable provider %s", route, name)
+			continue
+		}
+
+		result.Request.Provider = name
+		result.Payment, result.Error = f.ProcessPayment(ctx, name, amount, currency)
+		if result.Error == nil {
+			return result
+		}
+		if !shouldFailover(result.Error) {
+			return result
+		}
+		logger.WithContext(ctx).Error("Route %s: provider %s failed, failing over: %v", route, name, result.Error)
+	}
+
+	if result.Error == nil {
+		result.Error = &domain.PaymentError{
+			Code:      domain.ErrProviderUnavailable,
+			Message:   fmt.Sprintf("No available provider on route %s", route),
+			Retryable: true,
+		}
+	}
+	return result
+}
+
+// shouldFailover reports whether a payment error warrants trying the next provider
+func shouldFailover(err *domain.PaymentError) bool {
+	return err.Retryable || err.Code == domain.ErrProviderUnavailable
+}
+
+// IsProviderAvailable reports whether payments may currently be sent to the provider.
+// It is false for unknown providers, providers in a maintenance window and providers
+// disabled manually or by the circuit breaker. It has no side effects.
+func (f *Factory) IsProviderAvailable(name string) bool {
+	if _, exists := f.config.Providers[name]; !exists {
+		return false
+	}
+	if f.inMaintenance(name) {
+		return false
+	}
+	snapshot, tracked := f.GetProviderStateSnapshot(name)
+	return !tracked || snapshot.IsAvailable
+}
@@ -0,0 +1,452 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/pkg/httpclient"
+)
+
+func TestFactory_SelectProvider_LeastLoad(t *testing.T) {
+	release := make(chan struct{})
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		// Hold ProviderA requests open to load it artificially
+		if req.URL.Host == "provider-a.test" {
+			<-release
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-LOAD-1",
+			"status":         "APPROVED",
+			"amount":         100.00,
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			"ProviderB": {Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 10000},
+		},
+		Global: config.GlobalConfig{Routing: config.RoutingConfig{Strategy: config.RoutingLeastLoad}},
+	}
+	factory := NewFactory(cfg, client)
+	candidates := []string{"ProviderA", "ProviderB"}
+
+	// With no load, ties go to the first candidate
+	if selected, err := factory.SelectProvider(candidates); err != nil || selected != "ProviderA" {
+		t.Fatalf("expected ProviderA when idle, got %q, %v", selected, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			factory.ProcessPayment(context.Background(), "ProviderA", 100.00, "USD")
+		}()
+	}
+	defer func() {
+		close(release)
+		wg.Wait()
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for factory.InFlight("ProviderA") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 in-flight requests on ProviderA, got %d", factory.InFlight("ProviderA"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if selected, err := factory.SelectProvider(candidates); err != nil || selected != "ProviderB" {
+		t.Errorf("expected the less loaded ProviderB, got %q, %v", selected, err)
+	}
+
+	// Unavailable providers are skipped regardless of load
+	if err := factory.DisableProvider("ProviderB"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if selected, err := factory.SelectProvider(candidates); err != nil || selected != "ProviderA" {
+		t.Errorf("expected ProviderA while ProviderB is disabled, got %q, %v", selected, err)
+	}
+
+	if _, err := factory.SelectProvider([]string{"ProviderB", "Unknown"}); err == nil || err.Code != domain.ErrProviderUnavailable {
+		t.Errorf("expected %s, got %v", domain.ErrProviderUnavailable, err)
+	}
+}
+
+func TestFactory_SelectProvider_FirstAvailable(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+			"ProviderB": {Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 10000},
+		},
+	}
+	factory := NewFactory(cfg, &http.Client{})
+
+	if selected, err := factory.SelectProvider([]string{"ProviderB", "ProviderA"}); err != nil || selected != "ProviderB" {
+		t.Errorf("expected ProviderB, got %q, %v", selected, err)
+	}
+}
+
+func TestFactory_IsProviderAvailable(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	networkErr := &domain.PaymentError{Code: domain.ErrNetworkError}
+
+	tests := []struct {
+		name     string
+		provider string
+		setup    func(f *Factory)
+		expected bool
+	}{
+		{name: "unknown provider", provider: "ProviderX", expected: false},
+		{name: "configured provider without state", provider: "ProviderA", expected: true},
+		{
+			name:     "below failure threshold",
+			provider: "ProviderA",
+			setup: func(f *Factory) {
+				f.UpdateProviderState("ProviderA", networkErr)
+			},
+			expected: true,
+		},
+		{
+			name:     "circuit open",
+			provider: "ProviderA",
+			setup: func(f *Factory) {
+				for i := 0; i < defaultFailureThreshold; i++ {
+					f.UpdateProviderState("ProviderA", networkErr)
+				}
+			},
+			expected: false,
+		},
+		{
+			name:     "manually disabled",
+			provider: "ProviderA",
+			setup: func(f *Factory) {
+				f.DisableProvider("ProviderA")
+			},
+			expected: false,
+		},
+		{name: "in maintenance", provider: "ProviderB", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+					"ProviderB": {
+						Name:               "ProviderB",
+						Endpoint:           "http://provider-b.test",
+						MaxAmount:          10000,
+						MaintenanceWindows: []config.TimeWindow{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}},
+					},
+				},
+			}
+			factory := NewFactory(cfg, &http.Client{}, WithClock(ClockFunc(func() time.Time { return now })))
+			if tt.setup != nil {
+				tt.setup(factory)
+			}
+
+			if available := factory.IsProviderAvailable(tt.provider); available != tt.expected {
+				t.Errorf("expected available %v, got %v", tt.expected, available)
+			}
+			if _, exists := factory.GetProviderStateSnapshot(tt.provider); exists && tt.setup == nil {
+				t.Error("expected the availability check not to create provider state")
+			}
+		})
+	}
+}
+
+func TestFactory_ProcessPaymentWithFailover(t *testing.T) {
+	approvedA, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": "TXN-A-1",
+		"status":         "APPROVED",
+		"amount":         100.00,
+		"currency":       "USD",
+		"timestamp":      "2024-01-15T10:30:00Z",
+	})
+	responseB := func(state string) []byte {
+		body, _ := json.Marshal(map[string]interface{}{
+			"paymentId": "PAY-B-1",
+			"state":     state,
+			"value": map[string]interface{}{
+				"amount":       "100.00",
+				"currencyCode": "USD",
+			},
+			"processedAt": 1705318200000,
+		})
+		return body
+	}
+
+	tests := []struct {
+		name             string
+		route            string
+		statusA          int
+		stateB           string
+		disabled         []string
+		expectedProvider string
+		expectedError    string
+		expectedCalls    map[string]int
+	}{
+		{
+			name:             "primary succeeds",
+			route:            "a-first",
+			statusA:          http.StatusOK,
+			expectedProvider: "ProviderA",
+			expectedCalls:    map[string]int{"provider-a.test": 1},
+		},
+		{
+			name:             "primary unavailable",
+			route:            "a-first",
+			statusA:          http.StatusOK,
+			stateB:           "SUCCESS",
+			disabled:         []string{"ProviderA"},
+			expectedProvider: "ProviderB",
+			expectedCalls:    map[string]int{"provider-b.test": 1},
+		},
+		{
+			name:             "retryable error fails over",
+			route:            "a-first",
+			statusA:          http.StatusInternalServerError,
+			stateB:           "SUCCESS",
+			expectedProvider: "ProviderB",
+			expectedCalls:    map[string]int{"provider-a.test": 1, "provider-b.test": 1},
+		},
+		{
+			name:             "declined card stops failover",
+			route:            "b-first",
+			statusA:          http.StatusOK,
+			stateB:           "FAILED",
+			expectedProvider: "ProviderB",
+			expectedError:    domain.ErrCardDeclined,
+			expectedCalls:    map[string]int{"provider-b.test": 1},
+		},
+		{
+			name:          "all providers unavailable",
+			route:         "a-first",
+			disabled:      []string{"ProviderA", "ProviderB"},
+			expectedError: domain.ErrProviderUnavailable,
+			expectedCalls: map[string]int{},
+		},
+		{
+			name:          "unknown route",
+			route:         "missing",
+			expectedError: domain.ErrInvalidConfiguration,
+			expectedCalls: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			calls := make(map[string]int)
+			client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+				mutex.Lock()
+				calls[req.URL.Host]++
+				mutex.Unlock()
+				if req.URL.Host == "provider-a.test" {
+					return httpclient.NewMockResponse(tt.statusA, approvedA), nil
+				}
+				return httpclient.NewMockResponse(http.StatusOK, responseB(tt.stateB)), nil
+			})
+
+			noRetry := config.RetryPolicy{MaxAttempts: 1}
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000, RetryPolicy: noRetry},
+					"ProviderB": {Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 10000, RetryPolicy: noRetry},
+				},
+				Global: config.GlobalConfig{
+					Routing: config.RoutingConfig{
+						Routes: map[string][]string{
+							"a-first": {"ProviderA", "ProviderB"},
+							"b-first": {"ProviderB", "ProviderA"},
+						},
+					},
+				},
+			}
+			factory := NewFactory(cfg, client)
+			for _, name := range tt.disabled {
+				if err := factory.DisableProvider(name); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			result := factory.ProcessPaymentWithFailover(context.Background(), tt.route, 100.00, "USD")
+
+			if tt.expectedError != "" {
+				if result.Error == nil || result.Error.Code != tt.expectedError {
+					t.Errorf("expected %s, got %v", tt.expectedError, result.Error)
+				}
+			} else if result.Error != nil || result.Payment == nil {
+				t.Errorf("expected a payment, got %v", result.Error)
+			}
+			if result.Request.Provider != tt.expectedProvider {
+				t.Errorf("expected provider %q, got %q", tt.expectedProvider, result.Request.Provider)
+			}
+			if len(calls) != len(tt.expectedCalls) {
+				t.Errorf("expected calls %v, got %v", tt.expectedCalls, calls)
+			}
+			for host, expected := range tt.expectedCalls {
+				if calls[host] != expected {
+					t.Errorf("expected %d calls to %s, got %d", expected, host, calls[host])
+				}
+			}
+		})
+	}
+}
+
+func TestFactory_ProcessPaymentBalanced(t *testing.T) {
+	// Both providers approve whatever amount and currency they are sent
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		var sent struct {
+			Amount       float64 `json:"amount"`
+			Currency     string  `json:"currency"`
+			PaymentValue struct {
+				Amount       string `json:"amount"`
+				CurrencyCode string `json:"currencyCode"`
+			} `json:"paymentValue"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&sent); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		var body []byte
+		if req.URL.Host == "provider-a.test" {
+			body, _ = json.Marshal(map[string]interface{}{
+				"transaction_id": "TXN-A-1",
+				"status":         "APPROVED",
+				"amount":         sent.Amount,
+				"currency":       sent.Currency,
+				"timestamp":      "2024-01-15T10:30:00Z",
+			})
+		} else {
+			body, _ = json.Marshal(map[string]interface{}{
+				"paymentId": "PAY-B-1",
+				"state":     "SUCCESS",
+				"value": map[string]interface{}{
+					"amount":       sent.PaymentValue.Amount,
+					"currencyCode": sent.PaymentValue.CurrencyCode,
+				},
+				"processedAt": 1705318200000,
+			})
+		}
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+
+	candidates := []string{"ProviderA", "ProviderB"}
+	tests := []struct {
+		name          string
+		strategy      string
+		amount        float64
+		currency      string
+		setup         func(*Factory)
+		expected      []string
+		expectedError string
+	}{
+		{
+			name:     "round robin alternates",
+			strategy: config.RoutingRoundRobin,
+			amount:   100.00,
+			currency: "USD",
+			expected: []string{"ProviderA", "ProviderB", "ProviderA", "ProviderB"},
+		},
+		{
+			name:     "lowest latency",
+			strategy: config.RoutingLowestLatency,
+			amount:   100.00,
+			currency: "USD",
+			setup: func(f *Factory) {
+				for name, latency := range map[string]time.Duration{"ProviderA": 50 * time.Millisecond, "ProviderB": 10 * time.Millisecond} {
+					if _, err := f.stateFor(name); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					f.recordLatency(name, latency)
+				}
+			},
+			expected: []string{"ProviderB"},
+		},
+		{
+			name:     "amount over maximum is filtered out",
+			strategy: config.RoutingRoundRobin,
+			amount:   500.00,
+			currency: "USD",
+			expected: []string{"ProviderB", "ProviderB"},
+		},
+		{
+			name:     "unsupported currency is filtered out",
+			strategy: config.RoutingRoundRobin,
+			amount:   100.00,
+			currency: "EUR",
+			expected: []string{"ProviderA", "ProviderA"},
+		},
+		{
+			name:     "unavailable provider is filtered out",
+			strategy: config.RoutingRoundRobin,
+			amount:   100.00,
+			currency: "USD",
+			setup: func(f *Factory) {
+				if err := f.DisableProvider("ProviderA"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			expected: []string{"ProviderB", "ProviderB"},
+		},
+		{
+			name:          "no eligible provider",
+			strategy:      config.RoutingRoundRobin,
+			amount:        500.00,
+			currency:      "EUR",
+			expectedError: domain.ErrProviderUnavailable,
+		},
+		{
+			name:          "unknown strategy",
+			strategy:      "random",
+			amount:        100.00,
+			currency:      "USD",
+			expectedError: domain.ErrInvalidConfiguration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Providers: map[string]config.PaymentProviderConfig{
+					"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 200, SupportedCurrencies: []string{"USD", "EUR"}},
+					"ProviderB": {Name: "ProviderB", Endpoint: "http://provider-b.test", MaxAmount: 10000, SupportedCurrencies: []string{"USD"}},
+				},
+			}
+			factory := NewFactory(cfg, client)
+			if tt.setup != nil {
+				tt.setup(factory)
+			}
+
+			if tt.expectedError != "" {
+				payment, err := factory.ProcessPaymentBalanced(context.Background(), tt.amount, tt.currency, candidates, tt.strategy)
+				if err == nil || err.Code != tt.expectedError {
+					t.Errorf("expected %s, got payment %+v, error %v", tt.expectedError, payment, err)
+				}
+				return
+			}
+
+			for i, expected := range tt.expected {
+				payment, err := factory.ProcessPaymentBalanced(context.Background(), tt.amount, tt.currency, candidates, tt.strategy)
+				if err != nil {
+					t.Fatalf("payment %d: unexpected error: %v", i+1, err)
+				}
+				if payment.Provider != expected {
+					t.Errorf("payment %d: expected provider %s, got %s", i+1, expected, payment.Provider)
+				}
+			}
+		})
+	}
+}
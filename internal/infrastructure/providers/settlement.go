@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+// settlement holds the optional fee and net amount reported with a payment
+type settlement struct {
+	fee       float64
+	netAmount float64
+}
+
+// apply copies the settlement amounts onto a payment
+func (s settlement) apply(payment *domain.Payment) {
+	payment.Fee = domain.RoundAmount(s.fee)
+	payment.NetAmount = domain.RoundAmount(s.netAmount)
+}
+
+// extractSettlement reads the fee and net amount at the configured paths, falling back
+// to the given defaults, and returns the body with those fields removed so strict
+// decoding of the remaining fields is unaffected. Missing fields are left at zero.
+func extractSettlement(body []byte, fields config.SettlementFields, defaults config.SettlementFields) ([]byte, settlement, error) {
+	var result settlement
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		// Not a JSON object; leave it to the regular decoding to report
+		return body, result, nil
+	}
+
+	feePath, netPath := fields.Fee, fields.NetAmount
+	if feePath == "" {
+		feePath = defaults.Fee
+	}
+	if netPath == "" {
+		netPath = defaults.NetAmount
+	}
+
+	found := false
+	for _, target := range []struct {
+		path  string
+		value *float64
+	}{{feePath, &result.fee}, {netPath, &result.netAmount}} {
+		raw, ok := removePath(document, target.path)
+		if !ok {
+			continue
+		}
+		found = true
+		amount, err := parseSettlementAmount(raw)
+		if err != nil {
+			return nil, result, fmt.Errorf("invalid %s: %w", target.path, err)
+		}
+		*target.value = amount
+	}
+
+	if !found {
+		return body, result, nil
+	}
+	stripped, err := json.Marshal(document)
+	if err != nil {
+		return nil, result, err
+	}
+	return stripped, result, nil
+}
+
+// removePath deletes the value at a dot-separated path and returns it. Objects left
+// empty by the removal are deleted too, so a wrapper used only for settlement fields
+// does not trip strict decoding.
+func removePath(document map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	keys := strings.Split(path, ".")
+	parents := []map[string]interface{}{document}
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := parents[len(parents)-1][key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		parents = append(parents, next)
+	}
+	last := keys[len(keys)-1]
+	value, ok := parents[len(parents)-1][last]
+	if !ok {
+		return nil, false
+	}
+	delete(parents[len(parents)-1], last)
+	for i := len(parents) - 1; i > 0 && len(parents[i]) == 0; i-- {
+		delete(parents[i-1], keys[i-1])
+	}
+	return value, true
+}
+
+// parseSettlementAmount accepts amounts as JSON numbers or numeric strings
+func parseSettlementAmount(raw interface{}) (float64, error) {
+	switch v := raw.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected value %v", raw)
+	}
+}
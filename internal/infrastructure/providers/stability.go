@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"math"
+	"time"
+
+	"yuno_assesment/config"
+)
+
+// latencyWindow keeps the most recent provider latencies in a ring buffer
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	count   int
+}
+
+// newLatencyWindow creates a window holding up to size samples
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+// add records a latency, evicting the oldest sample once the window is full
+func (w *latencyWindow) add(latency time.Duration) {
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % len(w.samples)
+	if w.count < len(w.samples) {
+		w.count++
+	}
+}
+
+// full reports whether the window holds as many samples as it can
+func (w *latencyWindow) full() bool {
+	return w.count == len(w.samples)
+}
+
+// stdDev returns the population standard deviation of the samples in the window
+func (w *latencyWindow) stdDev() time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, sample := range w.samples[:w.count] {
+		mean += float64(sample)
+	}
+	mean /= float64(w.count)
+
+	var variance float64
+	for _, sample := range w.samples[:w.count] {
+		diff := float64(sample) - mean
+		variance += diff * diff
+	}
+	variance /= float64(w.count)
+	return time.Duration(math.Sqrt(variance))
+}
+
+// latencyEMAWeight is the weight of the newest sample in a provider's AvgLatency
+const latencyEMAWeight = 0.2
+
+// recordLatency folds a provider response time into its AvgLatency, an exponential
+// moving average seeded by the first sample, and adds it to the stability window to
+// update the unstable flag. Stability tracking is disabled unless the provider
+// configures it.
+func (f *Factory) recordLatency(providerName string, latency time.Duration) {
+	f.mutex.RLock()
+	state, exists := f.providerStates[providerName]
+	f.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.AvgLatency == 0 {
+		state.AvgLatency = latency
+	} else {
+		state.AvgLatency += time.Duration(latencyEMAWeight * float64(latency-state.AvgLatency))
+	}
+
+	stability := f.config.Providers[providerName].LatencyStability
+	if stability.WindowSize <= 0 || stability.MaxStdDev <= 0 {
+		return
+	}
+	if state.latencies == nil {
+		state.latencies = newLatencyWindow(stability.WindowSize)
+	}
+	state.latencies.add(latency)
+	state.Unstable = isUnstable(stability, state.latencies)
+}
+
+// isUnstable reports whether a full window's latency deviates more than allowed
+func isUnstable(stability config.LatencyStabilityConfig, window *latencyWindow) bool {
+	return window.full() && window.stdDev() > stability.MaxStdDev
+}
@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// BatchProcessPaymentsStream processes payment requests in parallel like
+// BatchProcessPayments, but emits each result on the returned channel as soon as it
+// completes, tagged with the index of its request. Results arrive in completion order
+// and the channel is closed once every result has been emitted. As in
+// BatchProcessPayments, requests without enough time left before ctx's deadline for
+// their provider's timeout fail with PROVIDER_TIMEOUT without calling the provider.
+//
+// Once ctx is done no further requests are sent to a provider and results that the
+// caller has not received yet are dropped, so the worker goroutines exit even if the
+// caller stops reading. The channel is then closed as soon as in-flight calls return.
+func (f *Factory) BatchProcessPaymentsStream(ctx context.Context, requests []repository.PaymentRequest) <-chan repository.IndexedPaymentResult {
+	ctx, span := f.tracer.Start(ctx, "Factory.BatchProcessPaymentsStream", trace.WithAttributes(attrBatchSize.Int(len(requests))))
+
+	indexCh := make(chan int)
+	resultCh := make(chan repository.IndexedPaymentResult)
+
+	go func() {
+		defer close(indexCh)
+		for i := range requests {
+			select {
+			case indexCh <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexCh {
+				if ctx.Err() != nil {
+					return
+				}
+				req := requests[idx]
+				var payment *domain.Payment
+				err := f.deadlineError(ctx, req.Provider)
+				if err == nil {
+					payment, err = f.ProcessPaymentRequest(ctx, req)
+				}
+				result := repository.IndexedPaymentResult{
+					Index: idx,
+					PaymentResult: repository.PaymentResult{
+						Request: req,
+						Payment: payment,
+						Error:   err,
+					},
+				}
+				select {
+				case resultCh <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultCh)
+		span.End()
+	}()
+
+	return resultCh
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/httpclient"
+)
+
+func newStreamTestFactory(onRequest func()) *Factory {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		if onRequest != nil {
+			onRequest()
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&payload)
+		body, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-STREAM",
+			"status":         "APPROVED",
+			"amount":         payload["amount"],
+			"currency":       payload["currency"],
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, body), nil
+	})
+	return NewFactory(&config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}, client)
+}
+
+func TestFactory_BatchProcessPaymentsStream(t *testing.T) {
+	const batchSize = 12
+
+	factory := newStreamTestFactory(nil)
+	requests := make([]repository.PaymentRequest, batchSize)
+	for i := range requests {
+		requests[i] = repository.PaymentRequest{Amount: float64(i + 1), Currency: "USD", Provider: "ProviderA"}
+	}
+
+	seen := make(map[int]bool)
+	for result := range factory.BatchProcessPaymentsStream(context.Background(), requests) {
+		if seen[result.Index] {
+			t.Errorf("index %d emitted twice", result.Index)
+		}
+		seen[result.Index] = true
+
+		if result.Request.Amount != requests[result.Index].Amount {
+			t.Errorf("index %d: expected request %+v, got %+v", result.Index, requests[result.Index], result.Request)
+		}
+		if result.Error != nil {
+			t.Errorf("index %d: unexpected error: %v", result.Index, result.Error)
+		} else if result.Payment.Amount != result.Request.Amount {
+			t.Errorf("index %d: expected amount %v, got %v", result.Index, result.Request.Amount, result.Payment.Amount)
+		}
+	}
+	if len(seen) != batchSize {
+		t.Errorf("expected %d results, got %d", batchSize, len(seen))
+	}
+}
+
+func TestFactory_BatchProcessPaymentsStream_Cancellation(t *testing.T) {
+	const batchSize = 50
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	factory := newStreamTestFactory(func() { atomic.AddInt32(&calls, 1) })
+	requests := make([]repository.PaymentRequest, batchSize)
+	for i := range requests {
+		requests[i] = repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"}
+	}
+
+	results := factory.BatchProcessPaymentsStream(ctx, requests)
+	<-results
+	// Stop reading: the workers must not block on the unread results
+	cancel()
+
+	done := make(chan int)
+	go func() {
+		received := 1
+		for range results {
+			received++
+		}
+		done <- received
+	}()
+
+	select {
+	case received := <-done:
+		if received >= batchSize {
+			t.Errorf("expected cancellation to stop the batch early, got all %d results", received)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the result channel to be closed after cancellation")
+	}
+
+	if attempted := int(atomic.LoadInt32(&calls)); attempted >= batchSize {
+		t.Errorf("expected fewer than %d provider calls, got %d", batchSize, attempted)
+	}
+}
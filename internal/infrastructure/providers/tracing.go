@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+// tracerName identifies the spans created by this package
+const tracerName = "yuno_assesment/internal/infrastructure/providers"
+
+// Span attributes recorded on payment spans
+const (
+	attrProvider  = attribute.Key("payment.provider")
+	attrAmount    = attribute.Key("payment.amount")
+	attrCurrency  = attribute.Key("payment.currency")
+	attrErrorCode = attribute.Key("payment.error_code")
+	attrBatchSize = attribute.Key("payment.batch_size")
+)
+
+// WithTracerProvider creates the factory's spans with tp instead of the tracer
+// provider chosen from the tracing configuration
+func WithTracerProvider(tp trace.TracerProvider) FactoryOption {
+	return func(f *Factory) {
+		f.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// newTracerProvider returns the global tracer provider when tracing is enabled and a
+// no-op one otherwise. The exporter and sampler are installed on the global provider
+// by the application.
+func newTracerProvider(cfg *config.Config) trace.TracerProvider {
+	if cfg != nil && cfg.Monitoring.Tracing.Enabled {
+		return otel.GetTracerProvider()
+	}
+	return noop.NewTracerProvider()
+}
+
+// startPaymentSpan starts a span for a payment with tracer
+func startPaymentSpan(ctx context.Context, tracer trace.Tracer, name, provider string, amount float64, currency string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attrProvider.String(provider),
+		attrAmount.Float64(amount),
+		attrCurrency.String(currency),
+	))
+}
+
+// startProviderSpan starts a span for a provider call as a child of the span in ctx,
+// so that provider spans are only recorded when the factory traces the payment
+func startProviderSpan(ctx context.Context, name, provider string, amount float64, currency string) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+	return startPaymentSpan(ctx, tracer, name, provider, amount, currency)
+}
+
+// endSpan records the error code of a failed payment on span and ends it
+func endSpan(span trace.Span, err *domain.PaymentError) {
+	if err != nil {
+		span.SetAttributes(attrErrorCode.String(err.Code))
+		span.SetStatus(codes.Error, err.Message)
+	}
+	span.End()
+}
@@ -0,0 +1,180 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/httpclient"
+)
+
+// recordingTracerProvider keeps every span started through it
+type recordingTracerProvider struct {
+	embedded.TracerProvider
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (p *recordingTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+// byName returns the recorded spans with the given name
+func (p *recordingTracerProvider) byName(name string) []*recordedSpan {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var spans []*recordedSpan
+	for _, span := range p.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	provider *recordingTracerProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{provider: t.provider, name: name, attributes: map[attribute.Key]attribute.Value{}}
+	if parent, ok := trace.SpanFromContext(ctx).(*recordedSpan); ok {
+		span.parent = parent
+	}
+	span.SetAttributes(cfg.Attributes()...)
+
+	t.provider.mutex.Lock()
+	t.provider.spans = append(t.provider.spans, span)
+	t.provider.mutex.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	noop.Span
+	provider   *recordingTracerProvider
+	name       string
+	parent     *recordedSpan
+	mutex      sync.Mutex
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = code
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ended = true
+}
+
+func (s *recordedSpan) IsRecording() bool                    { return true }
+func (s *recordedSpan) TracerProvider() trace.TracerProvider { return s.provider }
+
+func TestFactory_Tracing(t *testing.T) {
+	client := httpclient.NewMockClient(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body["amount"] == 999.0 {
+			return httpclient.NewMockResponse(http.StatusTooManyRequests, nil), nil
+		}
+		resp, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": "TXN-TRACE-1",
+			"status":         "APPROVED",
+			"amount":         body["amount"],
+			"currency":       "USD",
+			"timestamp":      "2024-01-15T10:30:00Z",
+		})
+		return httpclient.NewMockResponse(http.StatusOK, resp), nil
+	})
+	cfg := &config.Config{
+		Providers: map[string]config.PaymentProviderConfig{
+			"ProviderA": {Name: "ProviderA", Endpoint: "http://provider-a.test", MaxAmount: 10000},
+		},
+	}
+	tp := &recordingTracerProvider{}
+	factory := NewFactory(cfg, client, WithTracerProvider(tp))
+
+	factory.BatchProcessPayments(context.Background(), []repository.PaymentRequest{
+		{Provider: "ProviderA", Amount: 100.00, Currency: "USD"},
+		{Provider: "ProviderA", Amount: 999.00, Currency: "USD"},
+	})
+
+	batches := tp.byName("Factory.BatchProcessPayments")
+	if len(batches) != 1 {
+		t.Fatalf("expected 1 batch span, got %d", len(batches))
+	}
+	batch := batches[0]
+	if !batch.ended || batch.attributes[attrBatchSize].AsInt64() != 2 {
+		t.Errorf("expected ended batch span with size 2, got ended=%v attributes=%v", batch.ended, batch.attributes)
+	}
+
+	payments := tp.byName("Factory.ProcessPayment")
+	if len(payments) != 2 {
+		t.Fatalf("expected 2 payment spans, got %d", len(payments))
+	}
+	failed := 0
+	for _, span := range payments {
+		if span.parent != batch {
+			t.Errorf("expected payment span to be a child of the batch span")
+		}
+		if !span.ended {
+			t.Errorf("expected payment span to be ended")
+		}
+		if span.attributes[attrProvider].AsString() != "ProviderA" || span.attributes[attrCurrency].AsString() != "USD" {
+			t.Errorf("unexpected payment span attributes: %v", span.attributes)
+		}
+		if span.status == codes.Error {
+			failed++
+			if code := span.attributes[attrErrorCode].AsString(); code != domain.ErrRateLimitExceeded {
+				t.Errorf("expected error code %s on failed span, got %q", domain.ErrRateLimitExceeded, code)
+			}
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 failed payment span, got %d", failed)
+	}
+
+	calls := tp.byName("ProviderA.ProcessPayment")
+	if len(calls) == 0 {
+		t.Fatal("expected provider spans")
+	}
+	for _, span := range calls {
+		if span.parent == nil || span.parent.name != "Factory.ProcessPayment" {
+			t.Errorf("expected provider span to be a child of a payment span")
+		}
+	}
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitoring.Tracing.Enabled = false
+	if _, ok := newTracerProvider(cfg).(noop.TracerProvider); !ok {
+		t.Errorf("expected a no-op tracer provider when tracing is disabled")
+	}
+}
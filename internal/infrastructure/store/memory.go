@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"yuno_assesment/internal/domain"
+)
+
+// MemoryTransactionStore is an in-memory repository.TransactionStore. Its contents are
+// lost when the process exits, so it only detects duplicates within a single run.
+type MemoryTransactionStore struct {
+	mutex    sync.RWMutex
+	payments map[string]domain.Payment
+}
+
+// NewMemoryTransactionStore creates an empty in-memory transaction store
+func NewMemoryTransactionStore() *MemoryTransactionStore {
+	return &MemoryTransactionStore{payments: make(map[string]domain.Payment)}
+}
+
+// Save stores a copy of payment under its idempotency key, replacing any payment
+// already stored under it
+func (s *MemoryTransactionStore) Save(ctx context.Context, payment *domain.Payment) error {
+	if payment == nil || payment.IdempotencyKey == "" {
+		return errors.New("payment has no idempotency key")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.payments[payment.IdempotencyKey] = *payment
+	return nil
+}
+
+// Exists returns a copy of the payment stored under idempotencyKey, if any
+func (s *MemoryTransactionStore) Exists(ctx context.Context, idempotencyKey string) (bool, *domain.Payment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	payment, ok := s.payments[idempotencyKey]
+	if !ok {
+		return false, nil, nil
+	}
+	return true, &payment, nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"yuno_assesment/internal/domain"
+)
+
+func TestMemoryTransactionStore(t *testing.T) {
+	s := NewMemoryTransactionStore()
+	ctx := context.Background()
+
+	if exists, payment, err := s.Exists(ctx, "key-1"); err != nil || exists || payment != nil {
+		t.Fatalf("expected a miss on an empty store, got exists=%v payment=%v err=%v", exists, payment, err)
+	}
+
+	saved := &domain.Payment{ID: "TXN-1", Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-1"}
+	if err := s.Save(ctx, saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved.Status = domain.StatusRefunded
+
+	exists, payment, err := s.Exists(ctx, "key-1")
+	if err != nil || !exists {
+		t.Fatalf("expected a hit, got exists=%v err=%v", exists, err)
+	}
+	if payment.ID != "TXN-1" {
+		t.Errorf("expected payment TXN-1, got %s", payment.ID)
+	}
+	if payment.Status == domain.StatusRefunded {
+		t.Error("expected the store to keep its own copy of the payment")
+	}
+
+	if err := s.Save(ctx, &domain.Payment{ID: "TXN-2"}); err == nil {
+		t.Error("expected an error saving a payment without an idempotency key")
+	}
+}
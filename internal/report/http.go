@@ -0,0 +1,75 @@
+package report
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// BatchResponse is the JSON body describing the outcome of a batch
+type BatchResponse struct {
+	Status    string             `json:"status"`
+	Total     int                `json:"total"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []BatchResultEntry `json:"results"`
+}
+
+// BatchResultEntry is the outcome of a single request within a BatchResponse
+type BatchResultEntry struct {
+	Provider string               `json:"provider"`
+	Amount   float64              `json:"amount"`
+	Currency string               `json:"currency"`
+	Payment  *domain.Payment      `json:"payment,omitempty"`
+	Error    *domain.PaymentError `json:"error,omitempty"`
+}
+
+// Aggregate batch statuses reported in BatchResponse.Status
+const (
+	BatchStatusSuccess = "success"
+	BatchStatusPartial = "partial"
+	BatchStatusFailed  = "failed"
+)
+
+// HTTPResponse maps batch results to an aggregate HTTP status and JSON body:
+// 200 when every request succeeded, 207 Multi-Status for a mix, and 502 when all failed.
+// An empty batch is reported as 200.
+func HTTPResponse(results []repository.PaymentResult) (int, []byte, error) {
+	response := BatchResponse{
+		Total:   len(results),
+		Results: make([]BatchResultEntry, 0, len(results)),
+	}
+	for _, result := range results {
+		if result.Error == nil && result.Payment != nil {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		response.Results = append(response.Results, BatchResultEntry{
+			Provider: result.Request.Provider,
+			Amount:   result.Request.Amount,
+			Currency: result.Request.Currency,
+			Payment:  result.Payment,
+			Error:    result.Error,
+		})
+	}
+
+	status := http.StatusOK
+	response.Status = BatchStatusSuccess
+	switch {
+	case response.Failed > 0 && response.Succeeded > 0:
+		status = http.StatusMultiStatus
+		response.Status = BatchStatusPartial
+	case response.Failed > 0:
+		status = http.StatusBadGateway
+		response.Status = BatchStatusFailed
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	return status, body, nil
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestHTTPResponse(t *testing.T) {
+	success := repository.PaymentResult{
+		Request: repository.PaymentRequest{Provider: "ProviderA", Amount: 100, Currency: "USD"},
+		Payment: &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved},
+	}
+	failure := repository.PaymentResult{
+		Request: repository.PaymentRequest{Provider: "ProviderB", Amount: 999, Currency: "EUR"},
+		Error:   &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"},
+	}
+
+	tests := []struct {
+		name           string
+		results        []repository.PaymentResult
+		expectedStatus int
+		expectedBody   string
+		succeeded      int
+		failed         int
+	}{
+		{
+			name:           "all success",
+			results:        []repository.PaymentResult{success, success},
+			expectedStatus: http.StatusOK,
+			expectedBody:   BatchStatusSuccess,
+			succeeded:      2,
+		},
+		{
+			name:           "mixed",
+			results:        []repository.PaymentResult{success, failure},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   BatchStatusPartial,
+			succeeded:      1,
+			failed:         1,
+		},
+		{
+			name:           "all failed",
+			results:        []repository.PaymentResult{failure, failure},
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   BatchStatusFailed,
+			failed:         2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body, err := HTTPResponse(tt.results)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status != tt.expectedStatus {
+				t.Errorf("expected HTTP status %d, got %d", tt.expectedStatus, status)
+			}
+
+			var response BatchResponse
+			if err := json.Unmarshal(body, &response); err != nil {
+				t.Fatalf("invalid JSON body %s: %v", body, err)
+			}
+			if response.Status != tt.expectedBody {
+				t.Errorf("expected body status %q, got %q", tt.expectedBody, response.Status)
+			}
+			if response.Total != len(tt.results) || response.Succeeded != tt.succeeded || response.Failed != tt.failed {
+				t.Errorf("expected %d/%d/%d total/succeeded/failed, got %+v", len(tt.results), tt.succeeded, tt.failed, response)
+			}
+			if len(response.Results) != len(tt.results) {
+				t.Fatalf("expected %d result entries, got %d", len(tt.results), len(response.Results))
+			}
+			for i, entry := range response.Results {
+				if (entry.Error == nil) == (tt.results[i].Error != nil) {
+					t.Errorf("entry %d: expected error presence to match the result, got %+v", i, entry)
+				}
+				if entry.Provider != tt.results[i].Request.Provider {
+					t.Errorf("entry %d: expected provider %s, got %s", i, tt.results[i].Request.Provider, entry.Provider)
+				}
+			}
+		})
+	}
+}
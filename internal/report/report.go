@@ -0,0 +1,37 @@
+package report
+
+import (
+	"yuno_assesment/internal/domain/repository"
+)
+
+// ProviderSummary aggregates the results of a batch for a single provider
+type ProviderSummary struct {
+	Provider    string  `json:"provider"`
+	Total       int     `json:"total"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// GroupByProvider aggregates batch results per provider. A result counts as
+// succeeded when it carries a payment and no error.
+func GroupByProvider(results []repository.PaymentResult) map[string]ProviderSummary {
+	summaries := make(map[string]ProviderSummary)
+	for _, result := range results {
+		summary := summaries[result.Request.Provider]
+		summary.Provider = result.Request.Provider
+		summary.Total++
+		if result.Error == nil && result.Payment != nil {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		summaries[result.Request.Provider] = summary
+	}
+
+	for provider, summary := range summaries {
+		summary.SuccessRate = float64(summary.Succeeded) / float64(summary.Total)
+		summaries[provider] = summary
+	}
+	return summaries
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"testing"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestGroupByProvider(t *testing.T) {
+	approved := &domain.Payment{Status: domain.StatusApproved}
+	declined := &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"}
+
+	results := []repository.PaymentResult{
+		{Request: repository.PaymentRequest{Provider: "ProviderA", Amount: 10}, Payment: approved},
+		{Request: repository.PaymentRequest{Provider: "ProviderA", Amount: 20}, Payment: approved},
+		{Request: repository.PaymentRequest{Provider: "ProviderA", Amount: 30}, Error: declined},
+		{Request: repository.PaymentRequest{Provider: "ProviderA", Amount: 40}, Payment: approved},
+		{Request: repository.PaymentRequest{Provider: "ProviderB", Amount: 50}, Error: declined},
+		{Request: repository.PaymentRequest{Provider: "ProviderB", Amount: 60}, Payment: approved},
+	}
+
+	summaries := GroupByProvider(results)
+
+	tests := []struct {
+		provider    string
+		total       int
+		succeeded   int
+		failed      int
+		successRate float64
+	}{
+		{provider: "ProviderA", total: 4, succeeded: 3, failed: 1, successRate: 0.75},
+		{provider: "ProviderB", total: 2, succeeded: 1, failed: 1, successRate: 0.5},
+	}
+
+	if len(summaries) != len(tests) {
+		t.Fatalf("expected %d providers, got %d", len(tests), len(summaries))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			summary, exists := summaries[tt.provider]
+			if !exists {
+				t.Fatalf("missing summary for %s", tt.provider)
+			}
+			if summary.Total != tt.total || summary.Succeeded != tt.succeeded || summary.Failed != tt.failed {
+				t.Errorf("expected %d/%d/%d total/succeeded/failed, got %d/%d/%d",
+					tt.total, tt.succeeded, tt.failed, summary.Total, summary.Succeeded, summary.Failed)
+			}
+			if summary.SuccessRate != tt.successRate {
+				t.Errorf("expected success rate %v, got %v", tt.successRate, summary.SuccessRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"fmt"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// FailureSummary describes the failures of a processed batch
+type FailureSummary struct {
+	Total  int
+	Failed int
+	Rate   float64
+}
+
+// WithOnThresholdExceeded registers a callback invoked after a batch whose failures
+// exceed the limits in Global.FailureAlert, e.g. to page an operator
+func WithOnThresholdExceeded(fn func(FailureSummary)) Option {
+	return func(uc *PaymentUseCase) {
+		uc.onThresholdExceeded = fn
+	}
+}
+
+// summarizeFailures counts the failed payments in a batch
+func summarizeFailures(results []repository.PaymentResult) FailureSummary {
+	batch := SummarizeResults(results)
+	summary := FailureSummary{Total: batch.Total, Failed: batch.Failed}
+	if summary.Total > 0 {
+		summary.Rate = float64(summary.Failed) / float64(summary.Total)
+	}
+	return summary
+}
+
+// exceeds reports whether the summary is over either of the configured limits
+func (s FailureSummary) exceeds(cfg config.FailureAlertConfig) bool {
+	if cfg.MaxFailures > 0 && s.Failed > cfg.MaxFailures {
+		return true
+	}
+	return cfg.MaxFailureRate > 0 && s.Rate > cfg.MaxFailureRate
+}
+
+// checkFailureThreshold alerts when a batch failed beyond the configured limits. It
+// returns an error only when the config asks for the batch to fail as well.
+func (uc *PaymentUseCase) checkFailureThreshold(results []repository.PaymentResult) *domain.PaymentError {
+	cfg := uc.config.Global.FailureAlert
+	summary := summarizeFailures(results)
+	if !summary.exceeds(cfg) {
+		return nil
+	}
+
+	logger.Error("Failure threshold exceeded: %d of %d payments failed (%.1f%%)", summary.Failed, summary.Total, summary.Rate*100)
+	if uc.onThresholdExceeded != nil {
+		uc.onThresholdExceeded(summary)
+	}
+	if !cfg.FailBatch {
+		return nil
+	}
+	return &domain.PaymentError{
+		Code:    domain.ErrFailureThreshold,
+		Message: fmt.Sprintf("%d of %d payments failed", summary.Failed, summary.Total),
+	}
+}
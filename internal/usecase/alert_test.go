@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestPaymentUseCase_BatchProcessPayments_FailureThreshold(t *testing.T) {
+	// Two of the four requests fail
+	requests := []repository.PaymentRequest{
+		{Amount: 10, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 20, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 30, Currency: "USD", Provider: "ProviderB"},
+		{Amount: 40, Currency: "USD", Provider: "ProviderB"},
+	}
+
+	tests := []struct {
+		name          string
+		alert         config.FailureAlertConfig
+		expectAlert   bool
+		expectErrCode string
+	}{
+		{
+			name:  "Disabled",
+			alert: config.FailureAlertConfig{},
+		},
+		{
+			name:  "Count below threshold",
+			alert: config.FailureAlertConfig{MaxFailures: 2},
+		},
+		{
+			name:        "Count above threshold",
+			alert:       config.FailureAlertConfig{MaxFailures: 1},
+			expectAlert: true,
+		},
+		{
+			name:  "Rate below threshold",
+			alert: config.FailureAlertConfig{MaxFailureRate: 0.5},
+		},
+		{
+			name:        "Rate above threshold",
+			alert:       config.FailureAlertConfig{MaxFailureRate: 0.25},
+			expectAlert: true,
+		},
+		{
+			name:          "Fail batch",
+			alert:         config.FailureAlertConfig{MaxFailures: 1, FailBatch: true},
+			expectAlert:   true,
+			expectErrCode: domain.ErrFailureThreshold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+			mockRepo.errors["ProviderB"] = &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Card declined"}
+
+			cfg := config.DefaultConfig()
+			cfg.Global.FailureAlert = tt.alert
+
+			var alerts []FailureSummary
+			useCase := NewPaymentUseCase(mockRepo, cfg, WithOnThresholdExceeded(func(summary FailureSummary) {
+				alerts = append(alerts, summary)
+			}))
+
+			results, err := useCase.BatchProcessPayments(context.Background(), requests)
+			if len(results) != len(requests) {
+				t.Fatalf("expected %d results, got %d", len(requests), len(results))
+			}
+
+			if tt.expectErrCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if err == nil || err.Code != tt.expectErrCode {
+				t.Fatalf("expected error %s, got %v", tt.expectErrCode, err)
+			}
+
+			if !tt.expectAlert {
+				if len(alerts) != 0 {
+					t.Errorf("expected no alert, got %+v", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("expected exactly one alert, got %d", len(alerts))
+			}
+			if alerts[0].Total != 4 || alerts[0].Failed != 2 || alerts[0].Rate != 0.5 {
+				t.Errorf("unexpected failure summary: %+v", alerts[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// Column names recognised in payment and refund request CSV files
+const (
+	columnTransactionID  = "transaction_id"
+	columnAmount         = "amount"
+	columnCurrency       = "currency"
+	columnProvider       = "provider"
+	columnIdempotencyKey = "idempotency_key"
+	columnReference      = "reference"
+	columnReferenceID    = "reference_id"
+)
+
+// csvColumn describes a column of a CSV schema. A column may be
+// named by any of its aliases in the header instead of its name.
+type csvColumn struct {
+	name     string
+	aliases  []string
+	required bool
+}
+
+// paymentCSVSchema lists the columns read from payment request CSV files. Columns
+// outside the schema are ignored.
+var paymentCSVSchema = []csvColumn{
+	{name: columnAmount, required: true},
+	{name: columnCurrency, required: true},
+	{name: columnProvider, required: true},
+	{name: columnIdempotencyKey},
+	{name: columnReferenceID, aliases: []string{columnReference}},
+}
+
+// refundCSVSchema lists the columns read from refund request CSV files
+var refundCSVSchema = []csvColumn{
+	{name: columnTransactionID, required: true},
+	{name: columnAmount, required: true},
+	{name: columnProvider, required: true},
+}
+
+// requiredCSVColumns returns the names of the required columns of the schema. The
+// provider column is optional when a default provider is configured.
+func requiredCSVColumns(schema []csvColumn, defaultProvider string) []string {
+	var names []string
+	for _, column := range schema {
+		if column.required && !(column.name == columnProvider && defaultProvider != "") {
+			names = append(names, column.name)
+		}
+	}
+	return names
+}
+
+// parseCSVHeader maps each schema column found in header to its index. Header names
+// are matched case-insensitively, and a column's own name takes precedence over its
+// aliases. It fails naming every required column the header lacks.
+func parseCSVHeader(header []string, schema []csvColumn, defaultProvider string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int, len(schema))
+	for _, column := range schema {
+		for _, name := range append([]string{column.name}, column.aliases...) {
+			if idx, exists := positions[name]; exists {
+				columns[column.name] = idx
+				break
+			}
+		}
+	}
+	var missing []string
+	for _, name := range requiredCSVColumns(schema, defaultProvider) {
+		if _, found := columns[name]; !found {
+			missing = append(missing, strconv.Quote(name))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("CSV header is missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// CSVRowError describes a CSV row that could not be turned into a payment or refund
+// request.
+// Row is the 1-based line number in the file, the header being row 1.
+type CSVRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface for CSVRowError
+func (e CSVRowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// SkippedRowsError summarises the CSV rows that could not be parsed and were not
+// sent to any provider. The individual rows are reported as CSVRowErrors.
+type SkippedRowsError struct {
+	Skipped int
+	Total   int
+}
+
+// Error implements the error interface for SkippedRowsError
+func (e *SkippedRowsError) Error() string {
+	return fmt.Sprintf("skipped %d of %d CSV rows that could not be parsed", e.Skipped, e.Total)
+}
+
+// csvRow is a parsed CSV row; err is set when the row is invalid
+type csvRow struct {
+	request repository.PaymentRequest
+	err     *CSVRowError
+}
+
+// refundRow is a parsed refund CSV row; err is set when the row is invalid
+type refundRow struct {
+	request repository.RefundRequest
+	err     *CSVRowError
+}
+
+// csvRecord is a data row of a CSV file, with its columns located by the header
+type csvRecord struct {
+	line    int
+	fields  []string
+	columns map[string]int
+}
+
+// field returns the trimmed value of the named column, or "" when the row lacks it
+func (r csvRecord) field(name string) string {
+	idx, exists := r.columns[name]
+	if !exists || idx >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[idx])
+}
+
+// amount parses the amount column. Short rows are malformed; an empty amount in a
+// complete row is returned as zero and left to request validation so it gets the
+// matching domain error code.
+func (r csvRecord) amount(required []string, separator string) (float64, *CSVRowError) {
+	var missing []string
+	for _, name := range required {
+		if r.columns[name] >= len(r.fields) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return 0, &CSVRowError{Row: r.line, Message: "missing required columns: " + strings.Join(missing, ", ")}
+	}
+	value := r.field(columnAmount)
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := parseAmount(value, separator)
+	if err != nil {
+		return 0, &CSVRowError{Row: r.line, Message: fmt.Sprintf("invalid amount %q", value)}
+	}
+	return amount, nil
+}
+
+// readCSV reads CSV data against schema and calls visit for every data row. Records
+// the CSV reader cannot parse are passed as malformed instead of being dropped, so
+// visit is called exactly once per data row.
+func readCSV(r io.Reader, cfg config.CSVConfig, schema []csvColumn, defaultProvider string, visit func(record csvRecord, malformed *CSVRowError)) error {
+	reader := csv.NewReader(r)
+	// Rows may be short or carry a trailing comma; missing columns are reported per
+	// row and fields beyond the header are ignored
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = cfg.LazyQuotes
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns, err := parseCSVHeader(header, schema, defaultProvider)
+	if err != nil {
+		return err
+	}
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader resumes after the malformed record, so keep going
+			visit(csvRecord{line: parseErr.StartLine}, &CSVRowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV record: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		visit(csvRecord{line: line, fields: fields, columns: columns}, nil)
+	}
+}
+
+// readPaymentCSV parses payment requests from CSV. Columns are located by their header
+// name, following paymentCSVSchema, so their order does not matter. Rows without a
+// provider use defaultProvider, if set. Invalid rows, including records the CSV reader
+// cannot parse, are returned with an error instead of being dropped, so every data row
+// yields exactly one csvRow.
+func readPaymentCSV(r io.Reader, cfg config.CSVConfig, defaultProvider string) ([]csvRow, error) {
+	required := requiredCSVColumns(paymentCSVSchema, defaultProvider)
+
+	var rows []csvRow
+	err := readCSV(r, cfg, paymentCSVSchema, defaultProvider, func(record csvRecord, malformed *CSVRowError) {
+		if malformed != nil {
+			rows = append(rows, csvRow{request: repository.PaymentRequest{Line: record.line}, err: malformed})
+			return
+		}
+
+		row := csvRow{
+			request: repository.PaymentRequest{
+				Currency:       record.field(columnCurrency),
+				Provider:       record.field(columnProvider),
+				IdempotencyKey: record.field(columnIdempotencyKey),
+				ReferenceID:    record.field(columnReferenceID),
+				Line:           record.line,
+			},
+		}
+		if row.request.Provider == "" {
+			row.request.Provider = defaultProvider
+		}
+		row.request.Amount, row.err = record.amount(required, cfg.GroupingSeparator)
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// readRefundCSV parses refund requests from CSV following refundCSVSchema, like
+// readPaymentCSV: every data row yields exactly one refundRow, rows without a
+// provider use defaultProvider, and invalid rows carry an error.
+func readRefundCSV(r io.Reader, cfg config.CSVConfig, defaultProvider string) ([]refundRow, error) {
+	required := requiredCSVColumns(refundCSVSchema, defaultProvider)
+
+	var rows []refundRow
+	err := readCSV(r, cfg, refundCSVSchema, defaultProvider, func(record csvRecord, malformed *CSVRowError) {
+		if malformed != nil {
+			rows = append(rows, refundRow{err: malformed})
+			return
+		}
+
+		row := refundRow{
+			request: repository.RefundRequest{
+				Provider:      record.field(columnProvider),
+				TransactionID: record.field(columnTransactionID),
+			},
+		}
+		if row.request.Provider == "" {
+			row.request.Provider = defaultProvider
+		}
+		row.request.Amount, row.err = record.amount(required, cfg.GroupingSeparator)
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseAmount parses a CSV amount such as "1000.00" or, with a grouping separator,
+// a quoted "1,000.00". Separators must split the integer part into groups of three.
+func parseAmount(value, separator string) (float64, error) {
+	if separator != "" && strings.Contains(value, separator) {
+		integer, fraction := value, ""
+		if dot := strings.Index(value, "."); dot >= 0 {
+			integer, fraction = value[:dot], value[dot:]
+		}
+		groups := strings.Split(integer, separator)
+		for i, group := range groups {
+			if group == "" || len(group) > 3 || (i > 0 && len(group) != 3) {
+				return 0, fmt.Errorf("misplaced grouping separator in amount %q", value)
+			}
+		}
+		value = strings.Join(groups, "") + fraction
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// precheckRows returns, for every row, the error it fails with before reaching any
+// provider: unparsable rows get INVALID_REQUEST, and rows with a non-positive amount,
+// a missing or unsupported currency or an unknown provider get the matching domain
+// error. A nil entry means the row should be dispatched.
+func (uc *PaymentUseCase) precheckRows(rows []csvRow) []*domain.PaymentError {
+	known := make(map[string]bool)
+	for _, name := range uc.paymentRepo.ListProviders() {
+		known[name] = true
+	}
+
+	errs := make([]*domain.PaymentError, len(rows))
+	for i, row := range rows {
+		if row.err != nil {
+			logger.Error("Invalid payment request in input: %v", row.err)
+			errs[i] = invalidRequestError(row.err)
+			continue
+		}
+		if err := uc.validatePayment(row.request.Amount, row.request.Currency); err != nil {
+			errs[i] = err
+			continue
+		}
+		if !known[row.request.Provider] {
+			logger.Error("Unknown provider in input row %d: %q", row.request.Line, row.request.Provider)
+			errs[i] = &domain.PaymentError{
+				Code:     domain.ErrProviderNotFound,
+				Message:  fmt.Sprintf("Provider %q not found", row.request.Provider),
+				Provider: row.request.Provider,
+			}
+		}
+	}
+	return errs
+}
+
+// invalidRequestError converts a CSV row error into the error reported in its PaymentResult
+func invalidRequestError(rowErr *CSVRowError) *domain.PaymentError {
+	return &domain.PaymentError{
+		Code:    domain.ErrInvalidRequest,
+		Message: rowErr.Error(),
+		Details: *rowErr,
+	}
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"yuno_assesment/config"
+)
+
+func TestReadPaymentCSV(t *testing.T) {
+	defaultCSV := config.DefaultConfig().Global.CSV
+
+	tests := []struct {
+		name           string
+		input          string
+		cfg            config.CSVConfig
+		expectedAmount []float64 // zero marks an invalid row
+	}{
+		{
+			name:           "Quoted amount with grouping separator",
+			input:          "amount,currency,provider\n\"1,000.00\",USD,ProviderA\n\"12,345,678.9\",USD,ProviderA\n",
+			cfg:            defaultCSV,
+			expectedAmount: []float64{1000, 12345678.9},
+		},
+		{
+			name:           "Trailing comma",
+			input:          "amount,currency,provider\n100.50,USD,ProviderA,\n",
+			cfg:            defaultCSV,
+			expectedAmount: []float64{100.50},
+		},
+		{
+			name:           "Trailing comma on header and rows",
+			input:          "amount,currency,provider,\n100.50,USD,ProviderA,\n",
+			cfg:            defaultCSV,
+			expectedAmount: []float64{100.50},
+		},
+		{
+			name:           "Misplaced grouping separator",
+			input:          "amount,currency,provider\n\"1,00.00\",USD,ProviderA\n\"1000,000\",USD,ProviderA\n",
+			cfg:            defaultCSV,
+			expectedAmount: []float64{0, 0},
+		},
+		{
+			name:           "Grouping disabled",
+			input:          "amount,currency,provider\n\"1,000.00\",USD,ProviderA\n",
+			cfg:            config.CSVConfig{},
+			expectedAmount: []float64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readPaymentCSV(strings.NewReader(tt.input), tt.cfg, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rows) != len(tt.expectedAmount) {
+				t.Fatalf("expected %d rows, got %d", len(tt.expectedAmount), len(rows))
+			}
+			for i, expected := range tt.expectedAmount {
+				if expected == 0 {
+					if rows[i].err == nil {
+						t.Errorf("row %d: expected an invalid amount error", i)
+					}
+					continue
+				}
+				if rows[i].err != nil {
+					t.Errorf("row %d: unexpected error: %v", i, rows[i].err)
+					continue
+				}
+				if rows[i].request.Amount != expected {
+					t.Errorf("row %d: expected amount %v, got %v", i, expected, rows[i].request.Amount)
+				}
+				if rows[i].request.Provider != "ProviderA" {
+					t.Errorf("row %d: expected provider ProviderA, got %q", i, rows[i].request.Provider)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPaymentCSV_Schema(t *testing.T) {
+	defaultCSV := config.DefaultConfig().Global.CSV
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedErr    []string // column names the header error must mention
+		expectedAmount float64
+		expectedRef    string
+		expectedKey    string
+	}{
+		{
+			name:           "reordered columns",
+			input:          "provider,amount,currency\nProviderA,10.00,USD\n",
+			expectedAmount: 10,
+		},
+		{
+			name:           "extra columns are ignored",
+			input:          "note,amount,currency,customer,provider\nfirst,10.00,USD,C-1,ProviderA\n",
+			expectedAmount: 10,
+		},
+		{
+			name:           "optional columns",
+			input:          "Idempotency_Key,reference_id,amount,currency,provider\nKEY-1,ORD-1,10.00,USD,ProviderA\n",
+			expectedAmount: 10,
+			expectedRef:    "ORD-1",
+			expectedKey:    "KEY-1",
+		},
+		{
+			name:           "reference alias",
+			input:          "reference,amount,currency,provider\nORD-2,10.00,USD,ProviderA\n",
+			expectedAmount: 10,
+			expectedRef:    "ORD-2",
+		},
+		{
+			name:           "reference_id takes precedence over reference",
+			input:          "reference_id,reference,amount,currency,provider\nORD-1,ORD-2,10.00,USD,ProviderA\n",
+			expectedAmount: 10,
+			expectedRef:    "ORD-1",
+		},
+		{
+			name:        "missing required column",
+			input:       "amount,provider\n10.00,ProviderA\n",
+			expectedErr: []string{`"currency"`},
+		},
+		{
+			name:        "several missing required columns",
+			input:       "reference_id,currency\nORD-1,USD\n",
+			expectedErr: []string{`"amount"`, `"provider"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readPaymentCSV(strings.NewReader(tt.input), defaultCSV, "")
+			if len(tt.expectedErr) > 0 {
+				if err == nil {
+					t.Fatal("expected a header error")
+				}
+				for _, column := range tt.expectedErr {
+					if !strings.Contains(err.Error(), column) {
+						t.Errorf("expected the error to name %s, got %v", column, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rows) != 1 || rows[0].err != nil {
+				t.Fatalf("expected one valid row, got %+v", rows)
+			}
+			req := rows[0].request
+			if req.Amount != tt.expectedAmount || req.Currency != "USD" || req.Provider != "ProviderA" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			if req.ReferenceID != tt.expectedRef || req.IdempotencyKey != tt.expectedKey {
+				t.Errorf("expected reference %q and key %q, got %q and %q", tt.expectedRef, tt.expectedKey, req.ReferenceID, req.IdempotencyKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// WithDryRun runs every payment through validation only. Requests the repository would
+// accept get a WOULD_PROCESS payment marked DryRun, and no provider is ever called.
+func WithDryRun(enabled bool) Option {
+	return func(uc *PaymentUseCase) {
+		uc.dryRun = enabled
+	}
+}
+
+// dispatch sends a validated request to the repository, or only checks it in dry-run
+// mode. The request is assigned a request ID if it has none.
+func (uc *PaymentUseCase) dispatch(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	ctx, req = withRequestID(ctx, req)
+	if uc.dryRun {
+		return uc.dryRunPayment(req)
+	}
+	return uc.processStored(ctx, req)
+}
+
+// dryRunPayment checks the request against the provider's availability and limits and
+// returns the payment that would have been sent
+func (uc *PaymentUseCase) dryRunPayment(req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	if err := uc.paymentRepo.CanProcess(req); err != nil {
+		return nil, err
+	}
+	return wouldProcess(req.Provider, req.Amount, req.Currency), nil
+}
+
+// wouldProcess builds the synthetic payment reported for an accepted dry-run request
+func wouldProcess(provider string, amount float64, currency string) *domain.Payment {
+	logger.Debug("Dry run: would process %.2f %s with %s", amount, currency, provider)
+	return &domain.Payment{
+		Amount:    amount,
+		Currency:  domain.Currency(currency),
+		Status:    domain.StatusWouldProcess,
+		Provider:  provider,
+		Timestamp: time.Now(),
+		DryRun:    true,
+	}
+}
+
+// dryRunResults checks every request of a batch in dry-run mode
+func (uc *PaymentUseCase) dryRunResults(requests []repository.PaymentRequest) []repository.PaymentResult {
+	results := make([]repository.PaymentResult, len(requests))
+	for i, req := range requests {
+		results[i].Request = req
+		if err := uc.validatePayment(req.Amount, req.Currency); err != nil {
+			results[i].Error = err
+			continue
+		}
+		results[i].Payment, results[i].Error = uc.dryRunPayment(req)
+	}
+	return results
+}
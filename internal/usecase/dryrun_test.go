@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+func TestPaymentUseCase_DryRun(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+	mockRepo.errors["ProviderB"] = &domain.PaymentError{Code: domain.ErrInvalidAmount, Message: "Amount exceeds maximum limit of 10000"}
+
+	content := "amount,currency,provider\n" +
+		"100.00,USD,ProviderA\n" +
+		"20000.00,USD,ProviderB\n" +
+		"-5.00,USD,ProviderA\n" +
+		"10.00,USD,ProviderX\n"
+	expected := []struct {
+		status domain.PaymentStatus
+		code   string
+	}{
+		{status: domain.StatusWouldProcess},
+		{code: domain.ErrInvalidAmount},
+		{code: domain.ErrInvalidAmount},
+		{code: domain.ErrProviderNotFound},
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "payments.csv")
+	if err := os.WriteFile(csvPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write CSV file: %v", err)
+	}
+
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithDryRun(true))
+	results, _, err := useCase.ProcessPaymentRequestsFromCSV(context.Background(), csvPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if expected[i].code != "" {
+			if result.Error == nil || result.Error.Code != expected[i].code {
+				t.Errorf("result %d: expected %s, got %v", i, expected[i].code, result.Error)
+			}
+			continue
+		}
+		if result.Error != nil {
+			t.Errorf("result %d: unexpected error: %v", i, result.Error)
+			continue
+		}
+		if result.Payment.Status != expected[i].status || !result.Payment.DryRun {
+			t.Errorf("result %d: expected dry-run %s payment, got %+v", i, expected[i].status, result.Payment)
+		}
+	}
+	if dispatched := atomic.LoadInt32(&mockRepo.dispatched); dispatched != 0 {
+		t.Errorf("expected no requests dispatched in dry-run mode, got %d", dispatched)
+	}
+
+	payment, perr := useCase.ProcessPayment(context.Background(), "ProviderA", 50.00, "EUR")
+	if perr != nil {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	if payment.Status != domain.StatusWouldProcess || payment.ID != "" {
+		t.Errorf("expected a WOULD_PROCESS payment without ID, got %+v", payment)
+	}
+	if mockRepo.lastRequest.Provider != "" {
+		t.Errorf("expected no request to reach the repository, got %+v", mockRepo.lastRequest)
+	}
+
+	// The written results mark dry-run entries explicitly
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, results[:1], FormatJSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if entries[0]["dry_run"] != true || entries[0]["status"] != string(domain.StatusWouldProcess) {
+		t.Errorf("expected a dry-run WOULD_PROCESS entry, got %v", entries[0])
+	}
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// PaymentFilter selects payments from the payment history. Empty fields match every
+// payment; From and To bound the payment timestamp, From inclusive and To exclusive.
+type PaymentFilter struct {
+	Provider string
+	Status   domain.PaymentStatus
+	From     time.Time
+	To       time.Time
+}
+
+// matches reports whether payment is selected by the filter
+func (f PaymentFilter) matches(payment *domain.Payment) bool {
+	if f.Provider != "" && payment.Provider != f.Provider {
+		return false
+	}
+	if f.Status != "" && payment.Status != f.Status {
+		return false
+	}
+	if !f.From.IsZero() && payment.Timestamp.Before(f.From) {
+		return false
+	}
+	return f.To.IsZero() || payment.Timestamp.Before(f.To)
+}
+
+// paymentHistory keeps the most recent payments by ID, up to limit of them. The oldest
+// payment is evicted when a new one would exceed the limit.
+type paymentHistory struct {
+	mu       sync.RWMutex
+	limit    int
+	payments map[string]domain.Payment
+	order    []string
+}
+
+// WithPaymentHistory keeps the last limit payments processed by the use case in memory,
+// so they can be looked up with GetPayment and ListPayments. A limit of zero or less
+// keeps no history.
+func WithPaymentHistory(limit int) Option {
+	return func(uc *PaymentUseCase) {
+		if limit > 0 {
+			uc.history = &paymentHistory{limit: limit, payments: make(map[string]domain.Payment)}
+		}
+	}
+}
+
+// add records payment, replacing an earlier version with the same ID in place
+func (h *paymentHistory) add(payment *domain.Payment) {
+	if payment == nil || payment.ID == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.payments[payment.ID]; !exists {
+		if len(h.order) == h.limit {
+			delete(h.payments, h.order[0])
+			h.order = h.order[1:]
+		}
+		h.order = append(h.order, payment.ID)
+	}
+	h.payments[payment.ID] = *payment
+}
+
+// update replaces a payment already in the history with its latest version
+func (h *paymentHistory) update(payment *domain.Payment) {
+	if payment == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.payments[payment.ID]; exists {
+		h.payments[payment.ID] = *payment
+	}
+}
+
+// completed records the result of a payment sent to a provider in the history and
+// posts it to the webhook. Dry-run results are neither recorded nor posted.
+func (uc *PaymentUseCase) completed(result repository.PaymentResult) {
+	if uc.history != nil && !uc.dryRun {
+		uc.history.add(result.Payment)
+	}
+	uc.notify(result)
+}
+
+// GetPayment returns a payment from the payment history by ID. It reports false when
+// the payment is unknown, has been evicted, or no history is kept.
+func (uc *PaymentUseCase) GetPayment(id string) (*domain.Payment, bool) {
+	if uc.history == nil {
+		return nil, false
+	}
+	uc.history.mu.RLock()
+	defer uc.history.mu.RUnlock()
+
+	payment, exists := uc.history.payments[id]
+	if !exists {
+		return nil, false
+	}
+	return &payment, true
+}
+
+// ListPayments returns the payments in the history selected by filter, oldest first
+func (uc *PaymentUseCase) ListPayments(filter PaymentFilter) []*domain.Payment {
+	if uc.history == nil {
+		return nil
+	}
+	uc.history.mu.RLock()
+	defer uc.history.mu.RUnlock()
+
+	var payments []*domain.Payment
+	for _, id := range uc.history.order {
+		payment := uc.history.payments[id]
+		if filter.matches(&payment) {
+			payments = append(payments, &payment)
+		}
+	}
+	return payments
+}
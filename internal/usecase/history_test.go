@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestPaymentUseCase_ListPayments(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	processed := []*domain.Payment{
+		{ID: "TXN-1", Amount: 10, Currency: "USD", Status: domain.StatusApproved, Provider: "ProviderA", Timestamp: base},
+		{ID: "TXN-2", Amount: 20, Currency: "USD", Status: domain.StatusPending, Provider: "ProviderB", Timestamp: base.Add(time.Hour)},
+		{ID: "TXN-3", Amount: 30, Currency: "USD", Status: domain.StatusApproved, Provider: "ProviderA", Timestamp: base.Add(2 * time.Hour)},
+	}
+
+	mockRepo := newMockPaymentRepository()
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithPaymentHistory(10))
+	for _, payment := range processed {
+		mockRepo.payments[payment.Provider] = payment
+		req := repository.PaymentRequest{Amount: payment.Amount, Currency: string(payment.Currency), Provider: payment.Provider}
+		if _, err := useCase.ProcessPaymentRequest(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   PaymentFilter
+		expected []string
+	}{
+		{name: "all payments", expected: []string{"TXN-1", "TXN-2", "TXN-3"}},
+		{name: "by provider", filter: PaymentFilter{Provider: "ProviderA"}, expected: []string{"TXN-1", "TXN-3"}},
+		{name: "by status", filter: PaymentFilter{Status: domain.StatusPending}, expected: []string{"TXN-2"}},
+		{name: "from is inclusive", filter: PaymentFilter{From: base.Add(time.Hour)}, expected: []string{"TXN-2", "TXN-3"}},
+		{name: "to is exclusive", filter: PaymentFilter{To: base.Add(time.Hour)}, expected: []string{"TXN-1"}},
+		{name: "combined", filter: PaymentFilter{Provider: "ProviderA", From: base.Add(time.Minute)}, expected: []string{"TXN-3"}},
+		{name: "no match", filter: PaymentFilter{Provider: "ProviderC"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments := useCase.ListPayments(tt.filter)
+			if len(payments) != len(tt.expected) {
+				t.Fatalf("expected %d payments, got %d", len(tt.expected), len(payments))
+			}
+			for i, payment := range payments {
+				if payment.ID != tt.expected[i] {
+					t.Errorf("payment %d: expected %s, got %s", i, tt.expected[i], payment.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_GetPayment(t *testing.T) {
+	newPayment := func(id string) *domain.Payment {
+		return &domain.Payment{ID: id, Amount: 10, Currency: "USD", Status: domain.StatusPending, Provider: "ProviderA"}
+	}
+	requests := []repository.PaymentRequest{{Amount: 10, Currency: "USD", Provider: "ProviderA"}}
+
+	t.Run("oldest payment is evicted", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithPaymentHistory(2))
+		for _, id := range []string{"TXN-1", "TXN-2", "TXN-3"} {
+			mockRepo.payments["ProviderA"] = newPayment(id)
+			if _, err := useCase.BatchProcessPayments(context.Background(), requests); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if _, found := useCase.GetPayment("TXN-1"); found {
+			t.Error("expected the oldest payment to be evicted")
+		}
+		for _, id := range []string{"TXN-2", "TXN-3"} {
+			if payment, found := useCase.GetPayment(id); !found || payment.ID != id {
+				t.Errorf("expected payment %s, got %v", id, payment)
+			}
+		}
+	})
+
+	t.Run("returned payments are copies", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.payments["ProviderA"] = newPayment("TXN-1")
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithPaymentHistory(2))
+		if _, err := useCase.ProcessPaymentRequest(context.Background(), requests[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		payment, _ := useCase.GetPayment("TXN-1")
+		payment.Status = domain.StatusDeclined
+		if stored, _ := useCase.GetPayment("TXN-1"); stored.Status != domain.StatusPending {
+			t.Errorf("expected the stored payment to be unchanged, got %s", stored.Status)
+		}
+	})
+
+	t.Run("status queries update the history", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.payments["ProviderA"] = newPayment("TXN-1")
+		mockRepo.settled["TXN-1"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+		mockRepo.settled["TXN-OTHER"] = &domain.Payment{ID: "TXN-OTHER", Status: domain.StatusApproved, Provider: "ProviderA"}
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithPaymentHistory(2))
+		if _, err := useCase.ProcessPaymentRequest(context.Background(), requests[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, id := range []string{"TXN-1", "TXN-OTHER"} {
+			if _, err := useCase.GetPaymentStatus(context.Background(), "ProviderA", id); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if payment, _ := useCase.GetPayment("TXN-1"); payment == nil || payment.Status != domain.StatusApproved {
+			t.Errorf("expected the settled status to be recorded, got %v", payment)
+		}
+		if _, found := useCase.GetPayment("TXN-OTHER"); found {
+			t.Error("expected a status query not to add unknown payments")
+		}
+	})
+
+	t.Run("dry runs are not recorded", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.payments["ProviderA"] = newPayment("TXN-1")
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithPaymentHistory(2), WithDryRun(true))
+		if _, err := useCase.ProcessPaymentRequest(context.Background(), requests[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if payments := useCase.ListPayments(PaymentFilter{}); len(payments) != 0 {
+			t.Errorf("expected no recorded payments, got %d", len(payments))
+		}
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.payments["ProviderA"] = newPayment("TXN-1")
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+		if _, err := useCase.ProcessPaymentRequest(context.Background(), requests[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, found := useCase.GetPayment("TXN-1"); found {
+			t.Error("expected no payment without a history")
+		}
+		if payments := useCase.ListPayments(PaymentFilter{}); payments != nil {
+			t.Errorf("expected no payments without a history, got %v", payments)
+		}
+	})
+}
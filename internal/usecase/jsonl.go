@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"yuno_assesment/internal/domain/repository"
+)
+
+// maxJSONLLineSize is the longest line accepted in JSON Lines input
+const maxJSONLLineSize = 1 << 20
+
+// jsonlPaymentRequest is one line of JSON Lines payment request input
+type jsonlPaymentRequest struct {
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	Provider       string  `json:"provider"`
+	ReferenceID    string  `json:"reference_id"`
+	IdempotencyKey string  `json:"idempotency_key"`
+}
+
+// readPaymentJSONL parses payment requests from JSON Lines input, one JSON object per
+// line. Blank lines are skipped and unknown fields ignored. Lines that are not valid
+// JSON objects are returned with an error instead of being dropped, so every non-blank
+// line yields exactly one csvRow. Requests without a provider use defaultProvider.
+func readPaymentJSONL(r io.Reader, defaultProvider string) ([]csvRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+
+	var rows []csvRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var entry jsonlPaymentRequest
+		if err := json.Unmarshal(data, &entry); err != nil {
+			rows = append(rows, csvRow{
+				request: repository.PaymentRequest{Line: line},
+				err:     &CSVRowError{Row: line, Message: "invalid JSON: " + err.Error()},
+			})
+			continue
+		}
+
+		request := repository.PaymentRequest{
+			Amount:         entry.Amount,
+			Currency:       entry.Currency,
+			Provider:       entry.Provider,
+			IdempotencyKey: entry.IdempotencyKey,
+			ReferenceID:    entry.ReferenceID,
+			Line:           line,
+		}
+		if request.Provider == "" {
+			request.Provider = defaultProvider
+		}
+		rows = append(rows, csvRow{request: request})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON Lines input after line %d: %w", line, err)
+	}
+	return rows, nil
+}
+
+// ProcessPaymentRequestsFromJSONL reads payment requests from JSON Lines input, one
+// object per line with amount, currency, provider and optional reference_id and
+// idempotency_key fields, and processes them like ProcessPaymentRequestsFromCSV.
+// Malformed lines are not sent to any provider; they are reported in the returned row
+// errors, with Row set to the line number, and as INVALID_REQUEST results.
+func (uc *PaymentUseCase) ProcessPaymentRequestsFromJSONL(ctx context.Context, r io.Reader) ([]repository.PaymentResult, []CSVRowError, error) {
+	rows, err := readPaymentJSONL(r, uc.config.Global.DefaultProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return uc.processRows(ctx, rows)
+}
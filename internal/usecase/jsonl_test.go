@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+)
+
+func TestPaymentUseCase_ProcessPaymentRequestsFromJSONL(t *testing.T) {
+	input := `{"amount": 100.00, "currency": "USD", "provider": "ProviderA", "reference_id": "ORD-1"}
+
+{"amount": 50.75, "currency": "EUR", "provider": "ProviderB
+{"amount": "abc", "currency": "USD", "provider": "ProviderA"}
+{"amount": 25.00, "currency": "GBP", "provider": "ProviderB", "note": "ignored"}
+{"amount": 10.00, "currency": "USD", "provider": "ProviderC"}
+`
+
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+	mockRepo.payments["ProviderB"] = &domain.Payment{ID: "PAY-1", Status: domain.StatusApproved, Provider: "ProviderB"}
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+	results, rowErrors, err := useCase.ProcessPaymentRequestsFromJSONL(context.Background(), strings.NewReader(input))
+	var skipped *SkippedRowsError
+	if !errors.As(err, &skipped) || skipped.Skipped != 2 || skipped.Total != 5 {
+		t.Fatalf("expected 2 of 5 lines skipped, got %v", err)
+	}
+
+	expectedCodes := []string{"", domain.ErrInvalidRequest, domain.ErrInvalidRequest, "", domain.ErrProviderNotFound}
+	if len(results) != len(expectedCodes) {
+		t.Fatalf("expected %d results, got %d", len(expectedCodes), len(results))
+	}
+	for i, result := range results {
+		code := ""
+		if result.Error != nil {
+			code = result.Error.Code
+		}
+		if code != expectedCodes[i] {
+			t.Errorf("result %d: expected error code %q, got %q", i, expectedCodes[i], code)
+		}
+	}
+	if results[0].Request.ReferenceID != "ORD-1" {
+		t.Errorf("expected reference ORD-1, got %q", results[0].Request.ReferenceID)
+	}
+
+	// Blank lines are skipped but still counted in line numbers
+	expectedLines := []int{3, 4}
+	if len(rowErrors) != len(expectedLines) {
+		t.Fatalf("expected %d line errors, got %v", len(expectedLines), rowErrors)
+	}
+	for i, rowErr := range rowErrors {
+		if rowErr.Row != expectedLines[i] {
+			t.Errorf("line error %d: expected line %d, got %d", i, expectedLines[i], rowErr.Row)
+		}
+	}
+	if dispatched := atomic.LoadInt32(&mockRepo.dispatched); dispatched != 2 {
+		t.Errorf("expected only the 2 valid lines to be dispatched, got %d", dispatched)
+	}
+}
+
+func TestReadPaymentJSONL(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		defaultProvider  string
+		expectedErr      bool
+		expectedProvider string
+	}{
+		{name: "provider from the line", input: `{"amount": 1, "currency": "USD", "provider": "ProviderA"}`, defaultProvider: "ProviderB", expectedProvider: "ProviderA"},
+		{name: "default provider", input: `{"amount": 1, "currency": "USD"}`, defaultProvider: "ProviderB", expectedProvider: "ProviderB"},
+		{name: "line too long", input: `{"note": "` + strings.Repeat("x", maxJSONLLineSize) + `"}`, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readPaymentJSONL(strings.NewReader(tt.input), tt.defaultProvider)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rows) != 1 || rows[0].err != nil {
+				t.Fatalf("expected one valid row, got %+v", rows)
+			}
+			if rows[0].request.Provider != tt.expectedProvider {
+				t.Errorf("expected provider %s, got %s", tt.expectedProvider, rows[0].request.Provider)
+			}
+		})
+	}
+}
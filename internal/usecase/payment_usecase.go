@@ -2,12 +2,13 @@ package usecase
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"strconv"
+	"sort"
+	"time"
 
+	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
 	"yuno_assesment/internal/domain/repository"
 	"yuno_assesment/pkg/logger"
@@ -15,23 +16,158 @@ import (
 
 // PaymentUseCase implements payment business logic
 type PaymentUseCase struct {
-	paymentRepo repository.PaymentRepository
+	paymentRepo  repository.PaymentRepository
+	config       *config.Config
+	batchSlots   chan struct{}
+	pollInterval time.Duration
+	dryRun       bool
+	transactions repository.TransactionStore
+	webhook      *WebhookNotifier
+	history      *paymentHistory
+
+	onThresholdExceeded func(FailureSummary)
+}
+
+// defaultPollInterval is how often WaitForSettlement polls unless configured otherwise
+const defaultPollInterval = 2 * time.Second
+
+// Option configures optional behaviour of a PaymentUseCase
+type Option func(*PaymentUseCase)
+
+// WithMaxConcurrentBatches limits how many batches may be processed at the same time.
+// Batches beyond the limit are rejected with ErrServiceBusy. Zero means unlimited.
+func WithMaxConcurrentBatches(n int) Option {
+	return func(uc *PaymentUseCase) {
+		if n > 0 {
+			uc.batchSlots = make(chan struct{}, n)
+		}
+	}
 }
 
-// NewPaymentUseCase creates a new payment use case
-func NewPaymentUseCase(repo repository.PaymentRepository) *PaymentUseCase {
-	return &PaymentUseCase{
-		paymentRepo: repo,
+// WithSettlementPollInterval sets how often WaitForSettlement polls the provider
+func WithSettlementPollInterval(interval time.Duration) Option {
+	return func(uc *PaymentUseCase) {
+		if interval > 0 {
+			uc.pollInterval = interval
+		}
 	}
 }
 
+// NewPaymentUseCase creates a new payment use case. The config supplies the
+// currency policy applied to every payment.
+func NewPaymentUseCase(repo repository.PaymentRepository, cfg *config.Config, opts ...Option) *PaymentUseCase {
+	uc := &PaymentUseCase{
+		paymentRepo:  repo,
+		config:       cfg,
+		pollInterval: defaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
 // ProcessPayment processes a payment through the specified provider
 func (uc *PaymentUseCase) ProcessPayment(ctx context.Context, provider string, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
-	logger.Debug("Processing payment request: provider=%s, amount=%.2f, currency=%s", provider, amount, currency)
+	return uc.ProcessPaymentRequest(ctx, repository.PaymentRequest{
+		Provider: provider,
+		Amount:   amount,
+		Currency: currency,
+	})
+}
+
+// ProcessPaymentRequest processes a single payment request, forwarding its idempotency
+// key, reference and metadata to the provider. The request is assigned a request ID,
+// unless it has one, that tags every log line written while processing it.
+func (uc *PaymentUseCase) ProcessPaymentRequest(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	ctx, req = withRequestID(ctx, req)
+	log := logger.WithContext(ctx)
+	log.Debug("Processing payment request: provider=%s, amount=%.2f, currency=%s, reference_id=%s",
+		req.Provider, req.Amount, req.Currency, req.ReferenceID)
+
+	if err := contextError(ctx, req.Provider); err != nil {
+		return nil, err
+	}
+
+	if err := uc.validatePayment(req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+
+	if req.Provider == "" {
+		log.Error("Missing provider in payment request")
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderNotFound,
+			Message: "Provider is required",
+		}
+	}
 
+	payment, err := uc.dispatch(ctx, req)
+	return uc.finishPayment(ctx, req, payment, err)
+}
+
+// ProcessPaymentWith processes a payment through the given provider instance directly,
+// bypassing the repository lookup. The same request validation, history and webhook
+// notification as ProcessPaymentRequest apply.
+func (uc *PaymentUseCase) ProcessPaymentWith(ctx context.Context, provider repository.PaymentProvider, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	if provider == nil {
+		logger.WithContext(ctx).Error("Missing provider in payment request")
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrProviderNotFound,
+			Message: "Provider is required",
+		}
+	}
+	ctx, req := withRequestID(ctx, repository.PaymentRequest{Amount: amount, Currency: currency, Provider: provider.Name()})
+	logger.WithContext(ctx).Debug("Processing payment request with provider instance: provider=%s, amount=%.2f, currency=%s", provider.Name(), amount, currency)
+
+	if err := contextError(ctx, provider.Name()); err != nil {
+		return nil, err
+	}
+
+	if err := uc.validatePayment(amount, currency); err != nil {
+		return nil, err
+	}
+
+	if uc.dryRun {
+		return uc.finishPayment(ctx, req, wouldProcess(provider.Name(), amount, currency), nil)
+	}
+
+	payment, err := provider.ProcessPayment(ctx, amount, currency)
+	return uc.finishPayment(ctx, req, payment, err)
+}
+
+// finishPayment records the outcome of a payment request in the history, posts it to
+// the webhook and logs it
+func (uc *PaymentUseCase) finishPayment(ctx context.Context, req repository.PaymentRequest, payment *domain.Payment, err *domain.PaymentError) (*domain.Payment, *domain.PaymentError) {
+	uc.completed(repository.PaymentResult{Request: req, Payment: payment, Error: err})
+	log := logger.WithContext(ctx)
+	if err != nil {
+		log.Error("Payment processing failed: %v", err)
+		return nil, err
+	}
+
+	log.Info("Payment processed successfully: ID=%s, Status=%s", payment.ID, payment.Status)
+	return payment, nil
+}
+
+// contextError returns an ErrCancelled error when ctx is already done, so no work is
+// started on behalf of a caller that has given up
+func contextError(ctx context.Context, provider string) *domain.PaymentError {
+	if ctx.Err() == nil {
+		return nil
+	}
+	logger.Error("Payment request cancelled before processing: %v", ctx.Err())
+	return &domain.PaymentError{
+		Code:     domain.ErrCancelled,
+		Message:  "Request cancelled: " + ctx.Err().Error(),
+		Provider: provider,
+	}
+}
+
+// validatePayment checks the amount and currency of a payment request
+func (uc *PaymentUseCase) validatePayment(amount float64, currency string) *domain.PaymentError {
 	if amount <= 0 {
 		logger.Error("Invalid payment amount: %.2f", amount)
-		return nil, &domain.PaymentError{
+		return &domain.PaymentError{
 			Code:    domain.ErrInvalidAmount,
 			Message: "Amount must be greater than zero",
 		}
@@ -39,30 +175,123 @@ func (uc *PaymentUseCase) ProcessPayment(ctx context.Context, provider string, a
 
 	if currency == "" {
 		logger.Error("Missing currency in payment request")
-		return nil, &domain.PaymentError{
+		return &domain.PaymentError{
 			Code:    domain.ErrInvalidCurrency,
 			Message: "Currency is required",
 		}
 	}
 
-	if provider == "" {
-		logger.Error("Missing provider in payment request")
+	if !uc.isSupportedCurrency(currency) {
+		logger.Error("Unsupported currency in payment request: %s", currency)
+		return &domain.PaymentError{
+			Code:    domain.ErrInvalidCurrency,
+			Message: fmt.Sprintf("Currency %s is not supported", currency),
+		}
+	}
+	return nil
+}
+
+// isSupportedCurrency checks the currency against Global.SupportedCurrencies.
+// An empty list places no restriction on currencies.
+func (uc *PaymentUseCase) isSupportedCurrency(currency string) bool {
+	supported := uc.config.Global.SupportedCurrencies
+	if len(supported) == 0 {
+		return true
+	}
+	return domain.IsSupportedCurrency(domain.Currency(currency), supported)
+}
+
+// GetPaymentStatus returns the current status of a payment as reported by its provider
+func (uc *PaymentUseCase) GetPaymentStatus(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	if transactionID == "" {
 		return nil, &domain.PaymentError{
-			Code:    domain.ErrProviderNotFound,
-			Message: "Provider is required",
+			Code:    domain.ErrTransactionNotFound,
+			Message: "Transaction ID is required",
 		}
 	}
 
-	payment, err := uc.paymentRepo.ProcessPayment(ctx, provider, amount, currency)
+	logger.Debug("Querying payment status: provider=%s, transaction=%s", provider, transactionID)
+	payment, err := uc.paymentRepo.GetPaymentStatus(ctx, provider, transactionID)
+	if err == nil && uc.history != nil {
+		uc.history.update(payment)
+	}
+	return payment, err
+}
+
+// CancelPayment cancels a payment that has not settled yet. Payments that already
+// reached a terminal status, such as approved or refunded ones, cannot be cancelled.
+func (uc *PaymentUseCase) CancelPayment(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	current, err := uc.GetPaymentStatus(ctx, provider, transactionID)
 	if err != nil {
-		logger.Error("Payment processing failed: %v", err)
 		return nil, err
 	}
+	if current.Status.IsTerminal() {
+		logger.WithContext(ctx).Error("Refusing to cancel payment %s in status %s", transactionID, current.Status)
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrInvalidRequest,
+			Message:  fmt.Sprintf("Payment %s is %s and can no longer be cancelled", transactionID, current.Status),
+			Provider: provider,
+		}
+	}
 
-	logger.Info("Payment processed successfully: ID=%s, Status=%s", payment.ID, payment.Status)
+	logger.WithContext(ctx).Debug("Cancelling payment: provider=%s, transaction=%s", provider, transactionID)
+	cancelled, err := uc.paymentRepo.CancelPayment(ctx, provider, transactionID)
+	if err == nil && uc.history != nil {
+		uc.history.update(cancelled)
+	}
+	return cancelled, err
+}
+
+// WaitForSettlement polls the provider until a pending payment reaches a terminal status
+// or ctx is done. Payments that are already terminal are returned as is. A payment
+// accepted asynchronously without an ID is polled at its PollURL.
+func (uc *PaymentUseCase) WaitForSettlement(ctx context.Context, payment *domain.Payment) (*domain.Payment, *domain.PaymentError) {
+	ticker := time.NewTicker(uc.pollInterval)
+	defer ticker.Stop()
+
+	for !payment.Status.IsTerminal() {
+		select {
+		case <-ctx.Done():
+			logger.Error("Gave up waiting for settlement of payment %s: %v", payment.ID, ctx.Err())
+			return payment, &domain.PaymentError{
+				Code:     domain.ErrCancelled,
+				Message:  fmt.Sprintf("Payment %s did not settle: %v", payment.ID, ctx.Err()),
+				Provider: payment.Provider,
+			}
+		case <-ticker.C:
+		}
+
+		current, err := uc.settlementStatus(ctx, payment)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("Payment %s status: %s", current.ID, current.Status)
+		payment = current
+	}
 	return payment, nil
 }
 
+// settlementStatus queries the current status of a pending payment: by its ID, or at
+// its poll URL while the provider has not reported an ID for it yet
+func (uc *PaymentUseCase) settlementStatus(ctx context.Context, payment *domain.Payment) (*domain.Payment, *domain.PaymentError) {
+	if payment.ID != "" || payment.PollURL == "" {
+		return uc.GetPaymentStatus(ctx, payment.Provider, payment.ID)
+	}
+
+	logger.Debug("Polling payment status: provider=%s, url=%s", payment.Provider, payment.PollURL)
+	current, err := uc.paymentRepo.GetPaymentStatus(domain.WithPollURL(ctx, payment.PollURL), payment.Provider, "")
+	if err != nil {
+		return nil, err
+	}
+	if current.ID == "" {
+		current.PollURL = payment.PollURL
+	}
+	if uc.history != nil {
+		uc.history.update(current)
+	}
+	return current, nil
+}
+
 // GetProviderMetadata returns metadata for a specific provider
 func (uc *PaymentUseCase) GetProviderMetadata(providerName string) map[string]interface{} {
 	return uc.paymentRepo.GetProviderMetadata(providerName)
@@ -73,51 +302,216 @@ func (uc *PaymentUseCase) ListProviders() []string {
 	return uc.paymentRepo.ListProviders()
 }
 
-// BatchProcessPayments processes multiple payments in batch
-func (uc *PaymentUseCase) BatchProcessPayments(ctx context.Context, requests []repository.PaymentRequest) []repository.PaymentResult {
+// ListProvidersDetailed returns the configuration and availability of every provider
+func (uc *PaymentUseCase) ListProvidersDetailed() []repository.ProviderInfo {
+	return uc.paymentRepo.ListProvidersDetailed()
+}
+
+// ListAvailableProviders returns the providers payments may currently be sent to, sorted by name
+func (uc *PaymentUseCase) ListAvailableProviders() []string {
+	available := make([]string, 0)
+	for _, name := range uc.paymentRepo.ListProviders() {
+		if uc.paymentRepo.IsProviderAvailable(name) {
+			available = append(available, name)
+		}
+	}
+	sort.Strings(available)
+	return available
+}
+
+// BatchProcessPayments processes multiple payments in batch. It fails with ErrServiceBusy
+// when the maximum number of concurrent batches is already being processed. When the
+// failures exceed Global.FailureAlert the results are returned together with an
+// ErrFailureThreshold error if FailBatch is set.
+func (uc *PaymentUseCase) BatchProcessPayments(ctx context.Context, requests []repository.PaymentRequest) ([]repository.PaymentResult, *domain.PaymentError) {
+	if err := uc.checkBatchCurrencies(requests); err != nil {
+		return nil, err
+	}
+
+	if uc.dryRun {
+		logger.Info("Dry run: validating %d payment requests without sending them", len(requests))
+		return uc.dryRunResults(requests), nil
+	}
+
+	release, err := uc.acquireBatchSlot(len(requests))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	logger.Info("Starting batch processing of %d payment requests", len(requests))
-	return uc.paymentRepo.BatchProcessPayments(ctx, requests)
+	results := uc.batchProcessStored(ctx, withRequestIDs(requests))
+	for _, result := range results {
+		uc.completed(result)
+	}
+	return results, uc.checkFailureThreshold(results)
+}
+
+// checkBatchCurrencies rejects a batch with more distinct currencies than
+// Global.MaxCurrenciesPerBatch allows
+func (uc *PaymentUseCase) checkBatchCurrencies(requests []repository.PaymentRequest) *domain.PaymentError {
+	limit := uc.config.Global.MaxCurrenciesPerBatch
+	if limit <= 0 {
+		return nil
+	}
+
+	currencies := make(map[string]struct{})
+	for _, req := range requests {
+		currencies[req.Currency] = struct{}{}
+	}
+	if len(currencies) <= limit {
+		return nil
+	}
+
+	logger.Error("Rejecting batch with %d distinct currencies (limit %d)", len(currencies), limit)
+	return &domain.PaymentError{
+		Code:    domain.ErrInvalidRequest,
+		Message: fmt.Sprintf("Batch contains %d distinct currencies, more than the limit of %d; check the input for shifted columns", len(currencies), limit),
+	}
+}
+
+// acquireBatchSlot reserves one of the concurrent batch slots, failing with ErrServiceBusy
+// when none is free. The returned function releases the slot.
+func (uc *PaymentUseCase) acquireBatchSlot(size int) (func(), *domain.PaymentError) {
+	if uc.batchSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case uc.batchSlots <- struct{}{}:
+		return func() { <-uc.batchSlots }, nil
+	default:
+		logger.Error("Rejecting batch of %d payment requests: too many concurrent batches", size)
+		return nil, &domain.PaymentError{
+			Code:      domain.ErrServiceBusy,
+			Message:   fmt.Sprintf("Too busy: %d batches are already being processed", cap(uc.batchSlots)),
+			Retryable: true,
+		}
+	}
 }
 
-// ProcessPaymentRequestsFromCSV reads payment requests from a CSV file and processes them
-func (uc *PaymentUseCase) ProcessPaymentRequestsFromCSV(ctx context.Context, filePath string) ([]repository.PaymentResult, error) {
+// ProcessPaymentRequestsFromCSV reads payment requests from a CSV file and processes them.
+// Columns are matched by header name. Rows that cannot be parsed are not sent to any
+// provider; they are reported in the returned row errors and as INVALID_REQUEST results.
+// Rows failing request validation are not sent either and get the matching domain error,
+// so the results keep one entry per data row in file order. When rows had to be skipped
+// the results are still returned, along with a *SkippedRowsError counting them.
+func (uc *PaymentUseCase) ProcessPaymentRequestsFromCSV(ctx context.Context, filePath string) ([]repository.PaymentResult, []CSVRowError, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	// Skip header row
-	_, err = reader.Read()
+	rows, err := readPaymentCSV(file, uc.config.Global.CSV, uc.config.Global.DefaultProvider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		return nil, nil, err
 	}
+	return uc.processRows(ctx, rows)
+}
+
+// processRows sends the valid parsed input rows as one batch and returns one result
+// per row in input order, with the errors of the rows that could not be parsed
+func (uc *PaymentUseCase) processRows(ctx context.Context, rows []csvRow) ([]repository.PaymentResult, []CSVRowError, error) {
+	prechecked := uc.precheckRows(rows)
 
 	var requests []repository.PaymentRequest
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
+	var rowErrors []CSVRowError
+	for i, row := range rows {
+		if row.err != nil {
+			rowErrors = append(rowErrors, *row.err)
 		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read CSV record: %w", err)
+		if prechecked[i] == nil {
+			requests = append(requests, row.request)
 		}
+	}
 
-		amount, err := strconv.ParseFloat(record[0], 64)
-		if err != nil {
-			logger.Error("Invalid amount in CSV: %s", record[0])
+	processed, batchErr := uc.BatchProcessPayments(ctx, requests)
+	if processed == nil && batchErr != nil {
+		return nil, rowErrors, batchErr
+	}
+
+	// Merge processed results back with the rejected rows in file order
+	results := make([]repository.PaymentResult, 0, len(rows))
+	next := 0
+	for i, row := range rows {
+		if prechecked[i] != nil {
+			results = append(results, repository.PaymentResult{
+				Request: row.request,
+				Error:   prechecked[i],
+			})
 			continue
 		}
+		results = append(results, processed[next])
+		next++
+	}
 
-		request := repository.PaymentRequest{
-			Amount:   amount,
-			Currency: record[1],
-			Provider: record[2],
+	var skippedErr error
+	if len(rowErrors) > 0 {
+		skippedErr = &SkippedRowsError{Skipped: len(rowErrors), Total: len(rows)}
+	}
+	switch {
+	case batchErr != nil && skippedErr != nil:
+		return results, rowErrors, errors.Join(batchErr, skippedErr)
+	case batchErr != nil:
+		return results, rowErrors, batchErr
+	default:
+		return results, rowErrors, skippedErr
+	}
+}
+
+// BatchProcessRefunds processes multiple refunds in batch
+func (uc *PaymentUseCase) BatchProcessRefunds(ctx context.Context, requests []repository.RefundRequest) []repository.RefundResult {
+	logger.Info("Starting batch processing of %d refund requests", len(requests))
+	return uc.paymentRepo.BatchProcessRefunds(ctx, requests)
+}
+
+// ProcessRefundsFromCSV reads refund requests from a CSV file and processes them.
+// Columns are matched by header name: transaction_id, amount and provider, which
+// may be left out when a default provider is configured. Like payment CSV files,
+// every data row gets a result in file order; rows that cannot be parsed are not
+// sent to any provider, get an INVALID_REQUEST result and are reported in the
+// returned row errors, along with a *SkippedRowsError counting them.
+func (uc *PaymentUseCase) ProcessRefundsFromCSV(ctx context.Context, filePath string) ([]repository.RefundResult, []CSVRowError, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	rows, err := readRefundCSV(file, uc.config.Global.CSV, uc.config.Global.DefaultProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var requests []repository.RefundRequest
+	var rowErrors []CSVRowError
+	for _, row := range rows {
+		if row.err != nil {
+			logger.Error("Invalid refund request in input: %v", row.err)
+			rowErrors = append(rowErrors, *row.err)
+			continue
 		}
-		requests = append(requests, request)
+		requests = append(requests, row.request)
 	}
+	processed := uc.BatchProcessRefunds(ctx, requests)
 
-	results := uc.BatchProcessPayments(ctx, requests)
-	return results, nil
+	// Merge processed results back with the rejected rows in file order
+	results := make([]repository.RefundResult, 0, len(rows))
+	next := 0
+	for _, row := range rows {
+		if row.err != nil {
+			results = append(results, repository.RefundResult{
+				Request: row.request,
+				Error:   invalidRequestError(row.err),
+			})
+			continue
+		}
+		results = append(results, processed[next])
+		next++
+	}
+
+	if len(rowErrors) > 0 {
+		return results, rowErrors, &SkippedRowsError{Skipped: len(rowErrors), Total: len(rows)}
+	}
+	return results, nil, nil
 }
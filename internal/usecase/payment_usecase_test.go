@@ -2,22 +2,53 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"yuno_assesment/config"
 	"yuno_assesment/internal/domain"
 	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/internal/infrastructure/providers"
 )
 
 type mockPaymentRepository struct {
 	payments map[string]*domain.Payment
 	errors   map[string]*domain.PaymentError
+	settled  map[string]*domain.Payment
+
+	// GetPaymentStatus reports settled payments as pending for this many calls
+	pendingPolls int
+
+	// Number of requests received through BatchProcessPayments, updated atomically
+	dispatched int32
+
+	// The last request received through ProcessPaymentRequest, guarded by requestMutex
+	lastRequest  repository.PaymentRequest
+	requestMutex sync.Mutex
+
+	// Providers reported as unavailable by IsProviderAvailable
+	unavailable map[string]bool
+
+	// When set, batches signal batchStarted and block until releaseBatches is closed
+	batchStarted   chan struct{}
+	releaseBatches chan struct{}
 }
 
 func newMockPaymentRepository() *mockPaymentRepository {
 	return &mockPaymentRepository{
 		payments: make(map[string]*domain.Payment),
 		errors:   make(map[string]*domain.PaymentError),
+		settled:  make(map[string]*domain.Payment),
 	}
 }
 
@@ -36,6 +67,23 @@ func (m *mockPaymentRepository) ProcessPayment(ctx context.Context, provider str
 	}
 }
 
+func (m *mockPaymentRepository) ProcessPaymentRequest(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	m.requestMutex.Lock()
+	m.lastRequest = req
+	m.requestMutex.Unlock()
+	return m.ProcessPayment(ctx, req.Provider, req.Amount, req.Currency)
+}
+
+func (m *mockPaymentRepository) CanProcess(req repository.PaymentRequest) *domain.PaymentError {
+	if err, exists := m.errors[req.Provider]; exists {
+		return err
+	}
+	if _, exists := m.payments[req.Provider]; !exists {
+		return &domain.PaymentError{Code: domain.ErrProviderNotFound, Message: "Provider not found"}
+	}
+	return nil
+}
+
 func (m *mockPaymentRepository) GetProviderMetadata(providerName string) map[string]interface{} {
 	return map[string]interface{}{
 		"name":    providerName,
@@ -44,14 +92,39 @@ func (m *mockPaymentRepository) GetProviderMetadata(providerName string) map[str
 }
 
 func (m *mockPaymentRepository) ListProviders() []string {
-	providers := make([]string, 0, len(m.payments))
+	providers := make([]string, 0, len(m.payments)+len(m.errors))
 	for provider := range m.payments {
 		providers = append(providers, provider)
 	}
+	for provider := range m.errors {
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+func (m *mockPaymentRepository) ListProvidersDetailed() []repository.ProviderInfo {
+	names := m.ListProviders()
+	sort.Strings(names)
+	providers := make([]repository.ProviderInfo, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, repository.ProviderInfo{Name: name, Available: m.IsProviderAvailable(name)})
+	}
 	return providers
 }
 
+func (m *mockPaymentRepository) IsProviderAvailable(name string) bool {
+	_, withPayment := m.payments[name]
+	_, withError := m.errors[name]
+	return (withPayment || withError) && !m.unavailable[name]
+}
+
 func (m *mockPaymentRepository) BatchProcessPayments(ctx context.Context, requests []repository.PaymentRequest) []repository.PaymentResult {
+	if m.releaseBatches != nil {
+		m.batchStarted <- struct{}{}
+		<-m.releaseBatches
+	}
+
+	atomic.AddInt32(&m.dispatched, int32(len(requests)))
 	results := make([]repository.PaymentResult, len(requests))
 	for i, req := range requests {
 		payment, err := m.ProcessPayment(ctx, req.Provider, req.Amount, req.Currency)
@@ -64,6 +137,72 @@ func (m *mockPaymentRepository) BatchProcessPayments(ctx context.Context, reques
 	return results
 }
 
+func (m *mockPaymentRepository) RefundPayment(ctx context.Context, provider string, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	original, exists := m.settled[transactionID]
+	if !exists {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrTransactionNotFound,
+			Message: "Transaction not found",
+		}
+	}
+	if amount > original.Amount {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrInvalidAmount,
+			Message: "Refund amount exceeds refundable amount",
+		}
+	}
+	return &domain.Payment{
+		ID:            "REF-" + transactionID,
+		Amount:        amount,
+		Currency:      original.Currency,
+		Status:        domain.StatusRefunded,
+		Provider:      original.Provider,
+		TransactionID: transactionID,
+	}, nil
+}
+
+func (m *mockPaymentRepository) BatchProcessRefunds(ctx context.Context, requests []repository.RefundRequest) []repository.RefundResult {
+	results := make([]repository.RefundResult, len(requests))
+	for i, req := range requests {
+		refund, err := m.RefundPayment(ctx, req.Provider, req.TransactionID, req.Amount)
+		results[i] = repository.RefundResult{
+			Request: req,
+			Refund:  refund,
+			Error:   err,
+		}
+	}
+	return results
+}
+
+func (m *mockPaymentRepository) GetPaymentStatus(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	if payment, exists := m.settled[transactionID]; exists && payment.Provider == provider {
+		if m.pendingPolls > 0 {
+			m.pendingPolls--
+			pending := *payment
+			pending.Status = domain.StatusPending
+			return &pending, nil
+		}
+		return payment, nil
+	}
+	return nil, &domain.PaymentError{
+		Code:    domain.ErrTransactionNotFound,
+		Message: "Transaction not found",
+	}
+}
+
+func (m *mockPaymentRepository) CancelPayment(ctx context.Context, provider string, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	payment, exists := m.settled[transactionID]
+	if !exists || payment.Provider != provider {
+		return nil, &domain.PaymentError{
+			Code:    domain.ErrTransactionNotFound,
+			Message: "Transaction not found",
+		}
+	}
+	cancelled := *payment
+	cancelled.Status = domain.StatusCancelled
+	return &cancelled, nil
+}
+
 func TestPaymentUseCase_ProcessPayment(t *testing.T) {
 	// Setup test data
 	now := time.Now()
@@ -124,6 +263,16 @@ func TestPaymentUseCase_ProcessPayment(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name:     "unsupported currency",
+			provider: "ProviderA",
+			amount:   100.00,
+			currency: "JPY",
+			setupMock: func(m *mockPaymentRepository) {
+				m.payments["ProviderA"] = successfulPayment
+			},
+			expectedError: true,
+		},
 		{
 			name:          "provider not found",
 			provider:      "NonExistentProvider",
@@ -141,7 +290,7 @@ func TestPaymentUseCase_ProcessPayment(t *testing.T) {
 			tt.setupMock(mockRepo)
 
 			// Create use case
-			useCase := NewPaymentUseCase(mockRepo)
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
 
 			// Process payment
 			payment, err := useCase.ProcessPayment(context.Background(), tt.provider, tt.amount, tt.currency)
@@ -178,3 +327,861 @@ func TestPaymentUseCase_ProcessPayment(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentUseCase_ProcessRefundsFromCSV(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.settled["TXN-100"] = &domain.Payment{ID: "TXN-100", Amount: 100.00, Currency: domain.USD, Provider: "ProviderA"}
+	mockRepo.settled["PAY-50"] = &domain.Payment{ID: "PAY-50", Amount: 50.00, Currency: domain.EUR, Provider: "ProviderB"}
+
+	tests := []struct {
+		name            string
+		content         string
+		defaultProvider string
+		expectedIDs     []string
+		expectedErrors  []string
+		invalidRows     map[int]int
+		expectedErr     bool
+	}{
+		{
+			name:           "refunds in file order",
+			content:        "transaction_id,amount,provider\nTXN-100,40.00,ProviderA\nPAY-50,75.00,ProviderB\n",
+			expectedIDs:    []string{"TXN-100", "PAY-50"},
+			expectedErrors: []string{"", domain.ErrInvalidAmount},
+		},
+		{
+			name:            "columns in any order with a default provider",
+			content:         "amount,transaction_id\n40.00,TXN-100\n",
+			defaultProvider: "ProviderA",
+			expectedIDs:     []string{"TXN-100"},
+			expectedErrors:  []string{""},
+		},
+		{
+			name:           "invalid amount gets a result",
+			content:        "transaction_id,amount,provider\nTXN-100,abc,ProviderA\nPAY-50,10.00,ProviderB\n",
+			expectedIDs:    []string{"TXN-100", "PAY-50"},
+			expectedErrors: []string{domain.ErrInvalidRequest, ""},
+			invalidRows:    map[int]int{0: 2},
+		},
+		{
+			name:           "short row gets a result",
+			content:        "transaction_id,amount,provider\nTXN-100\nPAY-50,10.00,ProviderB\n",
+			expectedIDs:    []string{"TXN-100", "PAY-50"},
+			expectedErrors: []string{domain.ErrInvalidRequest, ""},
+			invalidRows:    map[int]int{0: 2},
+		},
+		{
+			name:           "malformed records are skipped and reading continues",
+			content:        "transaction_id,amount,provider\nTXN-1\"00,40.00,ProviderA\nPAY-50,10.00,ProviderB\n",
+			expectedIDs:    []string{"", "PAY-50"},
+			expectedErrors: []string{domain.ErrInvalidRequest, ""},
+			invalidRows:    map[int]int{0: 2},
+		},
+		{
+			name:        "missing provider column without a default provider",
+			content:     "transaction_id,amount\nTXN-100,40.00\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), "refunds.csv")
+			if err := os.WriteFile(csvPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write CSV file: %v", err)
+			}
+
+			cfg := config.DefaultConfig()
+			cfg.Global.DefaultProvider = tt.defaultProvider
+			useCase := NewPaymentUseCase(mockRepo, cfg)
+			results, rowErrors, err := useCase.ProcessRefundsFromCSV(context.Background(), csvPath)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if len(tt.invalidRows) > 0 {
+				var skipped *SkippedRowsError
+				if !errors.As(err, &skipped) || skipped.Skipped != len(tt.invalidRows) || skipped.Total != len(tt.expectedIDs) {
+					t.Errorf("expected %d of %d rows skipped, got %v", len(tt.invalidRows), len(tt.expectedIDs), err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(results) != len(tt.expectedIDs) {
+				t.Fatalf("expected %d results, got %d", len(tt.expectedIDs), len(results))
+			}
+			if len(rowErrors) != len(tt.invalidRows) {
+				t.Errorf("expected %d row errors, got %v", len(tt.invalidRows), rowErrors)
+			}
+
+			for i, result := range results {
+				if result.Request.TransactionID != tt.expectedIDs[i] {
+					t.Errorf("result %d: expected transaction %q, got %q", i, tt.expectedIDs[i], result.Request.TransactionID)
+				}
+				if row, invalid := tt.invalidRows[i]; invalid {
+					if details, ok := result.Error.Details.(CSVRowError); !ok || details.Row != row {
+						t.Errorf("result %d: expected row error for row %d, got %+v", i, row, result.Error)
+					}
+				}
+				switch {
+				case tt.expectedErrors[i] == "" && result.Error != nil:
+					t.Errorf("result %d: unexpected error %v", i, result.Error)
+				case tt.expectedErrors[i] == "" && result.Refund.Status != domain.StatusRefunded:
+					t.Errorf("result %d: expected status %s, got %+v", i, domain.StatusRefunded, result.Refund)
+				case tt.expectedErrors[i] != "" && (result.Error == nil || result.Error.Code != tt.expectedErrors[i]):
+					t.Errorf("result %d: expected error code %s, got %v", i, tt.expectedErrors[i], result.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_GetPaymentStatus(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.settled["TXN-100"] = &domain.Payment{ID: "TXN-100", Status: domain.StatusApproved, Provider: "ProviderA"}
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+	payment, err := useCase.GetPaymentStatus(context.Background(), "ProviderA", "TXN-100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.Status != domain.StatusApproved {
+		t.Errorf("expected status %s, got %s", domain.StatusApproved, payment.Status)
+	}
+
+	if _, err := useCase.GetPaymentStatus(context.Background(), "ProviderA", ""); err == nil {
+		t.Error("expected error for empty transaction ID")
+	}
+}
+
+func TestPaymentUseCase_CancelPayment(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        domain.PaymentStatus
+		expectedError string
+	}{
+		{
+			name:   "Pending payment is cancelled",
+			status: domain.StatusPending,
+		},
+		{
+			name:          "Declined payment cannot be cancelled",
+			status:        domain.StatusDeclined,
+			expectedError: domain.ErrInvalidRequest,
+		},
+		{
+			name:          "Approved payment cannot be cancelled",
+			status:        domain.StatusApproved,
+			expectedError: domain.ErrInvalidRequest,
+		},
+		{
+			name:          "Refunded payment cannot be cancelled",
+			status:        domain.StatusRefunded,
+			expectedError: domain.ErrInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newMockPaymentRepository()
+			mockRepo.settled["TXN-300"] = &domain.Payment{ID: "TXN-300", Status: tt.status, Provider: "ProviderB"}
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+			payment, err := useCase.CancelPayment(context.Background(), "ProviderB", "TXN-300")
+
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error %s, got payment %+v", tt.expectedError, payment)
+				}
+				if err.Code != tt.expectedError {
+					t.Errorf("expected error code %s, got %s", tt.expectedError, err.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Status != domain.StatusCancelled {
+				t.Errorf("expected status %s, got %s", domain.StatusCancelled, payment.Status)
+			}
+		})
+	}
+
+	t.Run("Unknown transaction", func(t *testing.T) {
+		useCase := NewPaymentUseCase(newMockPaymentRepository(), config.DefaultConfig())
+		if _, err := useCase.CancelPayment(context.Background(), "ProviderB", "TXN-404"); err == nil || err.Code != domain.ErrTransactionNotFound {
+			t.Errorf("expected %s, got %v", domain.ErrTransactionNotFound, err)
+		}
+	})
+}
+
+// fakeProvider is a PaymentProvider that records the payments sent to it
+type fakeProvider struct {
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return "FakeProvider" }
+
+func (p *fakeProvider) ProcessPayment(ctx context.Context, amount float64, currency string) (*domain.Payment, *domain.PaymentError) {
+	p.calls++
+	return &domain.Payment{ID: "FAKE-1", Amount: amount, Currency: domain.Currency(currency), Status: domain.StatusApproved, Provider: p.Name()}, nil
+}
+
+func (p *fakeProvider) RefundPayment(ctx context.Context, transactionID string, amount float64) (*domain.Payment, *domain.PaymentError) {
+	return nil, &domain.PaymentError{Code: domain.ErrInternalError, Message: "not implemented"}
+}
+
+func (p *fakeProvider) GetPaymentStatus(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	return nil, &domain.PaymentError{Code: domain.ErrInternalError, Message: "not implemented"}
+}
+
+func (p *fakeProvider) CancelPayment(ctx context.Context, transactionID string) (*domain.Payment, *domain.PaymentError) {
+	return nil, &domain.PaymentError{Code: domain.ErrInternalError, Message: "not implemented"}
+}
+
+func (p *fakeProvider) GetMetadata() map[string]interface{} {
+	return map[string]interface{}{"name": p.Name()}
+}
+
+func TestPaymentUseCase_ProcessPaymentWith(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        float64
+		currency      string
+		expectedError string
+		expectedCalls int
+	}{
+		{
+			name:          "Uses supplied provider",
+			amount:        100,
+			currency:      "USD",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Invalid amount",
+			amount:        0,
+			currency:      "USD",
+			expectedError: domain.ErrInvalidAmount,
+		},
+		{
+			name:          "Unsupported currency",
+			amount:        100,
+			currency:      "JPY",
+			expectedError: domain.ErrInvalidCurrency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{}
+			// A nil repository makes sure the lookup is bypassed entirely
+			useCase := NewPaymentUseCase(nil, config.DefaultConfig())
+
+			payment, err := useCase.ProcessPaymentWith(context.Background(), provider, tt.amount, tt.currency)
+			if tt.expectedError != "" {
+				if err == nil || err.Code != tt.expectedError {
+					t.Errorf("expected error %s, got %v", tt.expectedError, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if payment.ID != "FAKE-1" || payment.Provider != "FakeProvider" {
+					t.Errorf("expected payment from the fake provider, got %+v", payment)
+				}
+			}
+			if provider.calls != tt.expectedCalls {
+				t.Errorf("expected %d provider calls, got %d", tt.expectedCalls, provider.calls)
+			}
+		})
+	}
+
+	t.Run("Records history and notifies webhook", func(t *testing.T) {
+		receiver := &webhookReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+		notifier := NewWebhookNotifier(config.WebhookConfig{URL: server.URL}, server.Client())
+		useCase := NewPaymentUseCase(nil, config.DefaultConfig(), WithPaymentHistory(10), WithWebhookNotifier(notifier))
+
+		if _, err := useCase.ProcessPaymentWith(context.Background(), &fakeProvider{}, 100, "USD"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		notifier.Wait()
+
+		if _, exists := useCase.GetPayment("FAKE-1"); !exists {
+			t.Error("expected the payment to be recorded in the history")
+		}
+		if len(receiver.bodies) != 1 {
+			t.Errorf("expected 1 webhook delivery, got %d", len(receiver.bodies))
+		}
+	})
+
+	t.Run("Nil provider", func(t *testing.T) {
+		useCase := NewPaymentUseCase(nil, config.DefaultConfig())
+		if _, err := useCase.ProcessPaymentWith(context.Background(), nil, 100, "USD"); err == nil || err.Code != domain.ErrProviderNotFound {
+			t.Errorf("expected error %s, got %v", domain.ErrProviderNotFound, err)
+		}
+	})
+}
+
+func TestPaymentUseCase_ProcessPayment_CancelledContext(t *testing.T) {
+	// A nil repository makes any attempt to process the payment panic
+	useCase := NewPaymentUseCase(nil, config.DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := useCase.ProcessPayment(ctx, "ProviderA", 100, "USD"); err == nil || err.Code != domain.ErrCancelled {
+		t.Errorf("expected %s, got %v", domain.ErrCancelled, err)
+	}
+
+	provider := &fakeProvider{}
+	if _, err := useCase.ProcessPaymentWith(ctx, provider, 100, "USD"); err == nil || err.Code != domain.ErrCancelled {
+		t.Errorf("expected %s, got %v", domain.ErrCancelled, err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("expected no provider calls, got %d", provider.calls)
+	}
+}
+
+func TestPaymentUseCase_ProcessPaymentRequest(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-REQ-1", Status: domain.StatusApproved}
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+	req := repository.PaymentRequest{
+		Provider:       "ProviderA",
+		Amount:         100.00,
+		Currency:       "USD",
+		IdempotencyKey: "order-7",
+		ReferenceID:    "INV-7",
+		Metadata:       map[string]string{"customer": "C-1"},
+	}
+	payment, err := useCase.ProcessPaymentRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.ID != "TXN-REQ-1" {
+		t.Errorf("expected payment TXN-REQ-1, got %s", payment.ID)
+	}
+	got := mockRepo.lastRequest
+	if got.IdempotencyKey != req.IdempotencyKey || got.ReferenceID != req.ReferenceID || got.Metadata["customer"] != "C-1" {
+		t.Errorf("expected request %+v to reach the repository, got %+v", req, got)
+	}
+
+	// Invalid requests are rejected before reaching the repository
+	mockRepo.lastRequest = repository.PaymentRequest{}
+	req.Amount = 0
+	if _, err := useCase.ProcessPaymentRequest(context.Background(), req); err == nil || err.Code != domain.ErrInvalidAmount {
+		t.Errorf("expected %s, got %v", domain.ErrInvalidAmount, err)
+	}
+	if mockRepo.lastRequest.Provider != "" {
+		t.Errorf("expected invalid request not to reach the repository, got %+v", mockRepo.lastRequest)
+	}
+}
+
+func TestPaymentUseCase_WaitForSettlement(t *testing.T) {
+	pending := &domain.Payment{ID: "TXN-200", Status: domain.StatusPending, Provider: "ProviderB"}
+
+	t.Run("polls until terminal", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.settled["TXN-200"] = &domain.Payment{ID: "TXN-200", Status: domain.StatusApproved, Provider: "ProviderB"}
+		mockRepo.pendingPolls = 2
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithSettlementPollInterval(time.Millisecond))
+
+		payment, err := useCase.WaitForSettlement(context.Background(), pending)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if payment.Status != domain.StatusApproved {
+			t.Errorf("expected status %s, got %s", domain.StatusApproved, payment.Status)
+		}
+		if mockRepo.pendingPolls != 0 {
+			t.Errorf("expected all pending polls to be consumed, %d left", mockRepo.pendingPolls)
+		}
+	})
+
+	t.Run("terminal payment is returned without polling", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithSettlementPollInterval(time.Millisecond))
+		approved := &domain.Payment{ID: "TXN-201", Status: domain.StatusApproved, Provider: "ProviderB"}
+
+		payment, err := useCase.WaitForSettlement(context.Background(), approved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if payment != approved {
+			t.Errorf("expected the same payment back, got %+v", payment)
+		}
+	})
+
+	t.Run("context expiry", func(t *testing.T) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.settled["TXN-200"] = &domain.Payment{ID: "TXN-200", Status: domain.StatusApproved, Provider: "ProviderB"}
+		mockRepo.pendingPolls = 1 << 30
+		useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithSettlementPollInterval(time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		payment, err := useCase.WaitForSettlement(ctx, pending)
+		if err == nil || err.Code != domain.ErrCancelled {
+			t.Fatalf("expected %s, got %v", domain.ErrCancelled, err)
+		}
+		if payment.Status != domain.StatusPending {
+			t.Errorf("expected last known status %s, got %s", domain.StatusPending, payment.Status)
+		}
+	})
+}
+
+func TestPaymentUseCase_WaitForSettlement_AcceptedPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		path     string
+		id       string
+		pending  string
+		approved string
+		body     func(id, state string) map[string]interface{}
+	}{
+		{
+			name:     "ProviderA",
+			provider: "ProviderA",
+			path:     "/process",
+			id:       "TXN-ASYNC-A-1",
+			pending:  "PENDING",
+			approved: "APPROVED",
+			body: func(id, state string) map[string]interface{} {
+				return map[string]interface{}{
+					"transaction_id": id,
+					"status":         state,
+					"amount":         100.00,
+					"currency":       "USD",
+					"timestamp":      "2024-01-15T10:30:00Z",
+				}
+			},
+		},
+		{
+			name:     "ProviderB",
+			provider: "ProviderB",
+			path:     "/payments",
+			id:       "PAY-ASYNC-1",
+			pending:  "PENDING",
+			approved: "SUCCESS",
+			body: func(id, state string) map[string]interface{} {
+				return map[string]interface{}{
+					"paymentId":   id,
+					"state":       state,
+					"value":       map[string]string{"amount": "100.00", "currencyCode": "USD"},
+					"processedAt": time.Now().UnixMilli(),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The provider accepts the payment with 202 and a Location, reports it pending
+			// with an ID at that location, and approved when queried by ID
+			var polls []string
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.path, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Location", "/async/"+tt.id)
+				w.WriteHeader(http.StatusAccepted)
+			})
+			status := func(state string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					polls = append(polls, r.Method+" "+r.URL.Path)
+					json.NewEncoder(w).Encode(tt.body(tt.id, state))
+				}
+			}
+			mux.HandleFunc("/async/"+tt.id, status(tt.pending))
+			mux.HandleFunc(tt.path+"/"+tt.id, status(tt.approved))
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			cfg := config.DefaultConfig()
+			providerCfg := cfg.Providers[tt.provider]
+			providerCfg.Endpoint = server.URL + tt.path
+			cfg.Providers[tt.provider] = providerCfg
+			useCase := NewPaymentUseCase(providers.NewFactory(cfg, server.Client()), cfg, WithSettlementPollInterval(time.Millisecond))
+
+			payment, err := useCase.ProcessPayment(context.Background(), tt.provider, 100.00, "USD")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if payment.Status != domain.StatusPending || payment.ID != "" || payment.PollURL == "" {
+				t.Fatalf("expected a pending payment with only a poll URL, got %+v", payment)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			settled, err := useCase.WaitForSettlement(ctx, payment)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if settled.Status != domain.StatusApproved || settled.ID != tt.id {
+				t.Errorf("expected approved payment %s, got %+v", tt.id, settled)
+			}
+			expected := "GET /async/" + tt.id + ",GET " + tt.path + "/" + tt.id
+			if got := strings.Join(polls, ","); got != expected {
+				t.Errorf("expected polls %s, got %s", expected, got)
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_ListAvailableProviders(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderB"] = &domain.Payment{ID: "PAY-B"}
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "PAY-A"}
+	mockRepo.payments["ProviderC"] = &domain.Payment{ID: "PAY-C"}
+	mockRepo.unavailable = map[string]bool{"ProviderC": true}
+
+	uc := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+	available := uc.ListAvailableProviders()
+	expected := []string{"ProviderA", "ProviderB"}
+	if len(available) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, available)
+	}
+	for i := range expected {
+		if available[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, available)
+			break
+		}
+	}
+}
+
+func TestPaymentUseCase_BatchProcessPayments_MaxCurrencies(t *testing.T) {
+	requests := []repository.PaymentRequest{
+		{Amount: 10, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 20, Currency: "EUR", Provider: "ProviderA"},
+		{Amount: 30, Currency: "GBP", Provider: "ProviderA"},
+		{Amount: 40, Currency: "USD", Provider: "ProviderA"},
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		expectedError string
+	}{
+		{name: "Unlimited", limit: 0},
+		{name: "Under limit", limit: 5},
+		{name: "Over limit", limit: 2, expectedError: domain.ErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+			cfg := config.DefaultConfig()
+			cfg.Global.MaxCurrenciesPerBatch = tt.limit
+			useCase := NewPaymentUseCase(mockRepo, cfg)
+
+			results, err := useCase.BatchProcessPayments(context.Background(), requests)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(results) != len(requests) {
+					t.Errorf("expected %d results, got %d", len(requests), len(results))
+				}
+				return
+			}
+			if err == nil || err.Code != tt.expectedError {
+				t.Fatalf("expected error %s, got %v", tt.expectedError, err)
+			}
+			if !strings.Contains(err.Message, "3 distinct currencies") {
+				t.Errorf("expected the error to name the currency count, got %q", err.Message)
+			}
+			if results != nil {
+				t.Errorf("expected no results for a rejected batch, got %d", len(results))
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_BatchProcessPayments_MaxConcurrentBatches(t *testing.T) {
+	const limit = 2
+
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+	mockRepo.batchStarted = make(chan struct{}, limit)
+	mockRepo.releaseBatches = make(chan struct{})
+
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithMaxConcurrentBatches(limit))
+	requests := []repository.PaymentRequest{{Provider: "ProviderA", Amount: 10, Currency: "USD"}}
+
+	// Occupy every slot with a batch that blocks inside the repository
+	var wg sync.WaitGroup
+	errs := make(chan *domain.PaymentError, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := useCase.BatchProcessPayments(context.Background(), requests)
+			errs <- err
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		<-mockRepo.batchStarted
+	}
+
+	// Any further batch is rejected immediately
+	for i := 0; i < 3; i++ {
+		results, err := useCase.BatchProcessPayments(context.Background(), requests)
+		if err == nil || err.Code != domain.ErrServiceBusy {
+			t.Errorf("expected %s, got %v", domain.ErrServiceBusy, err)
+		}
+		if results != nil {
+			t.Errorf("expected no results for a rejected batch, got %d", len(results))
+		}
+	}
+
+	close(mockRepo.releaseBatches)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error for admitted batch: %v", err)
+		}
+	}
+
+	// Slots are released once the running batches complete
+	if _, err := useCase.BatchProcessPayments(context.Background(), requests); err != nil {
+		t.Errorf("expected batch to be accepted after slots were released, got %v", err)
+	}
+}
+
+func TestPaymentUseCase_ProcessPaymentRequestsFromCSV_Prevalidation(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+
+	content := "amount,currency,provider\n" +
+		"100.00,USD,ProviderA\n" +
+		"-50.00,USD,ProviderA\n" +
+		",USD,ProviderA\n" +
+		"10.00,,ProviderA\n" +
+		"10.00,XXX,ProviderA\n" +
+		"10.00,USD,InvalidProvider\n" +
+		"10.00,USD,\n"
+	expectedCodes := []string{
+		"",
+		domain.ErrInvalidAmount,
+		domain.ErrInvalidAmount,
+		domain.ErrInvalidCurrency,
+		domain.ErrInvalidCurrency,
+		domain.ErrProviderNotFound,
+		domain.ErrProviderNotFound,
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "payments.csv")
+	if err := os.WriteFile(csvPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write CSV file: %v", err)
+	}
+
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+	results, rowErrors, err := useCase.ProcessPaymentRequestsFromCSV(context.Background(), csvPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rowErrors) != 0 {
+		t.Errorf("expected no CSV row errors for well-formed rows, got %v", rowErrors)
+	}
+	if len(results) != len(expectedCodes) {
+		t.Fatalf("expected %d results, got %d", len(expectedCodes), len(results))
+	}
+	for i, result := range results {
+		code := ""
+		if result.Error != nil {
+			code = result.Error.Code
+		}
+		if code != expectedCodes[i] {
+			t.Errorf("result %d: expected error code %q, got %q", i, expectedCodes[i], code)
+		}
+	}
+	if dispatched := atomic.LoadInt32(&mockRepo.dispatched); dispatched != 1 {
+		t.Errorf("expected only the valid row to be dispatched, got %d", dispatched)
+	}
+}
+
+func TestPaymentUseCase_ProcessPaymentRequestsFromCSV_DefaultProvider(t *testing.T) {
+	tests := []struct {
+		name              string
+		content           string
+		defaultProvider   string
+		expectedErr       bool
+		expectedProviders []string
+		expectedCodes     []string
+	}{
+		{
+			name:              "empty provider uses the default",
+			content:           "amount,currency,provider\n10.00,USD,\n20.00,USD,ProviderA\n",
+			defaultProvider:   "ProviderB",
+			expectedProviders: []string{"ProviderB", "ProviderA"},
+			expectedCodes:     []string{"", ""},
+		},
+		{
+			name:              "provider column may be omitted",
+			content:           "amount,currency\n10.00,USD\n",
+			defaultProvider:   "ProviderB",
+			expectedProviders: []string{"ProviderB"},
+			expectedCodes:     []string{""},
+		},
+		{
+			name:              "empty provider without a default",
+			content:           "amount,currency,provider\n10.00,USD,\n",
+			expectedProviders: []string{""},
+			expectedCodes:     []string{domain.ErrProviderNotFound},
+		},
+		{
+			name:        "provider column required without a default",
+			content:     "amount,currency\n10.00,USD\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), "payments.csv")
+			if err := os.WriteFile(csvPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write CSV file: %v", err)
+			}
+
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+			mockRepo.payments["ProviderB"] = &domain.Payment{ID: "PAY-1", Status: domain.StatusApproved, Provider: "ProviderB"}
+			cfg := config.DefaultConfig()
+			cfg.Global.DefaultProvider = tt.defaultProvider
+			useCase := NewPaymentUseCase(mockRepo, cfg)
+
+			results, _, err := useCase.ProcessPaymentRequestsFromCSV(context.Background(), csvPath)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(tt.expectedProviders) {
+				t.Fatalf("expected %d results, got %d", len(tt.expectedProviders), len(results))
+			}
+			for i, result := range results {
+				if result.Request.Provider != tt.expectedProviders[i] {
+					t.Errorf("result %d: expected provider %q, got %q", i, tt.expectedProviders[i], result.Request.Provider)
+				}
+				code := ""
+				if result.Error != nil {
+					code = result.Error.Code
+				}
+				if code != tt.expectedCodes[i] {
+					t.Errorf("result %d: expected error code %q, got %q", i, tt.expectedCodes[i], code)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_ProcessPaymentRequestsFromCSV(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, Provider: "ProviderA"}
+	mockRepo.payments["ProviderB"] = &domain.Payment{ID: "PAY-1", Status: domain.StatusApproved, Provider: "ProviderB"}
+
+	tests := []struct {
+		name              string
+		content           string
+		expectedErr       bool
+		expectedProviders []string
+		invalidRows       map[int]int // result index -> CSV row
+	}{
+		{
+			name:              "columns in any order",
+			content:           "provider,amount,currency\nProviderB,50.00,EUR\nProviderA,100.00,USD\n",
+			expectedProviders: []string{"ProviderB", "ProviderA"},
+		},
+		{
+			name:              "unknown columns are ignored",
+			content:           "note,provider,amount,reference_id,currency,customer\nfirst,ProviderA,100.00,ORD-1,USD,C-1\n",
+			expectedProviders: []string{"ProviderA"},
+		},
+		{
+			name:              "invalid rows are reported and kept in order",
+			content:           "currency,provider,amount\nUSD,ProviderA,100.00\nUSD,ProviderB\nEUR,ProviderB,abc\nGBP,ProviderB,25.00\n",
+			expectedProviders: []string{"ProviderA", "ProviderB", "ProviderB", "ProviderB"},
+			invalidRows:       map[int]int{1: 3, 2: 4},
+		},
+		{
+			name:              "malformed records are skipped and reading continues",
+			content:           "amount,currency,provider\n100.00,USD,ProviderA\n1\"0,USD,ProviderB\n25.00,GBP,ProviderB\n",
+			expectedProviders: []string{"ProviderA", "", "ProviderB"},
+			invalidRows:       map[int]int{1: 3},
+		},
+		{
+			name:        "missing required header",
+			content:     "amount,currency\n100.00,USD\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), "payments.csv")
+			if err := os.WriteFile(csvPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write CSV file: %v", err)
+			}
+
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+			results, rowErrors, err := useCase.ProcessPaymentRequestsFromCSV(context.Background(), csvPath)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if len(tt.invalidRows) > 0 {
+				var skipped *SkippedRowsError
+				if !errors.As(err, &skipped) || skipped.Skipped != len(tt.invalidRows) || skipped.Total != len(tt.expectedProviders) {
+					t.Errorf("expected %d of %d rows skipped, got %v", len(tt.invalidRows), len(tt.expectedProviders), err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(results) != len(tt.expectedProviders) {
+				t.Fatalf("expected %d results, got %d", len(tt.expectedProviders), len(results))
+			}
+			if len(rowErrors) != len(tt.invalidRows) {
+				t.Errorf("expected %d row errors, got %v", len(tt.invalidRows), rowErrors)
+			}
+
+			for i, result := range results {
+				if result.Request.Provider != tt.expectedProviders[i] {
+					t.Errorf("result %d: expected provider %s, got %s", i, tt.expectedProviders[i], result.Request.Provider)
+				}
+				row, invalid := tt.invalidRows[i]
+				if !invalid {
+					if result.Error != nil {
+						t.Errorf("result %d: unexpected error: %v", i, result.Error)
+					}
+					continue
+				}
+				if result.Error == nil || result.Error.Code != domain.ErrInvalidRequest {
+					t.Errorf("result %d: expected %s, got %v", i, domain.ErrInvalidRequest, result.Error)
+				}
+				found := false
+				for _, rowErr := range rowErrors {
+					found = found || rowErr.Row == row
+				}
+				if !found {
+					t.Errorf("expected a row error for CSV row %d, got %v", row, rowErrors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// newRequestID returns a short random ID used to correlate the log lines of a request
+func newRequestID() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// withRequestID assigns req a request ID unless it already has one and returns a
+// context carrying it for logging
+func withRequestID(ctx context.Context, req repository.PaymentRequest) (context.Context, repository.PaymentRequest) {
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	}
+	return logger.ContextWithRequestID(ctx, req.RequestID), req
+}
+
+// withRequestIDs returns a copy of requests in which every request has a request ID
+func withRequestIDs(requests []repository.PaymentRequest) []repository.PaymentRequest {
+	tagged := make([]repository.PaymentRequest, len(requests))
+	for i, req := range requests {
+		if req.RequestID == "" {
+			req.RequestID = newRequestID()
+		}
+		tagged[i] = req
+	}
+	return tagged
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestPaymentUseCase_RequestIDs(t *testing.T) {
+	newUseCase := func() (*PaymentUseCase, *mockPaymentRepository) {
+		mockRepo := newMockPaymentRepository()
+		mockRepo.payments["ProviderA"] = &domain.Payment{ID: "PAY-1", Status: domain.StatusApproved}
+		return NewPaymentUseCase(mockRepo, config.DefaultConfig()), mockRepo
+	}
+
+	t.Run("single request", func(t *testing.T) {
+		uc, mockRepo := newUseCase()
+		req := repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"}
+		if _, err := uc.ProcessPaymentRequest(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(mockRepo.lastRequest.RequestID) != 8 {
+			t.Errorf("expected an 8 character request ID, got %q", mockRepo.lastRequest.RequestID)
+		}
+	})
+
+	t.Run("existing ID is kept", func(t *testing.T) {
+		uc, mockRepo := newUseCase()
+		req := repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA", RequestID: "req-42"}
+		if _, err := uc.ProcessPaymentRequest(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mockRepo.lastRequest.RequestID != "req-42" {
+			t.Errorf("expected request ID req-42, got %q", mockRepo.lastRequest.RequestID)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		uc, _ := newUseCase()
+		requests := make([]repository.PaymentRequest, 10)
+		for i := range requests {
+			requests[i] = repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"}
+		}
+
+		results, err := uc.BatchProcessPayments(context.Background(), requests)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen := make(map[string]bool)
+		for i, result := range results {
+			id := result.Request.RequestID
+			if id == "" || seen[id] {
+				t.Errorf("result %d: expected a unique request ID, got %q", i, id)
+			}
+			seen[id] = true
+		}
+		if requests[0].RequestID != "" {
+			t.Error("expected the caller's requests not to be modified")
+		}
+	})
+}
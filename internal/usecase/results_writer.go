@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// Supported result output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Result statuses used for results without an approved or pending payment
+const (
+	resultStatusInvalid = "INVALID"
+	resultStatusFailed  = "FAILED"
+)
+
+// resultCSVHeader lists the columns written by WriteResults in CSV format
+var resultCSVHeader = []string{"amount", "currency", "provider", "status", "payment_id", "error_code", "reference_id"}
+
+// resultRequest is the JSON form of a PaymentRequest
+type resultRequest struct {
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	Provider       string  `json:"provider"`
+	IdempotencyKey string  `json:"idempotency_key,omitempty"`
+	ReferenceID    string  `json:"reference_id,omitempty"`
+}
+
+// resultEntry is the JSON form of a PaymentResult
+type resultEntry struct {
+	Status  string               `json:"status"`
+	DryRun  bool                 `json:"dry_run,omitempty"`
+	Request resultRequest        `json:"request"`
+	Payment *domain.Payment      `json:"payment,omitempty"`
+	Error   *domain.PaymentError `json:"error,omitempty"`
+}
+
+// newResultEntry converts a PaymentResult to its JSON form
+func newResultEntry(result repository.PaymentResult) resultEntry {
+	return resultEntry{
+		Status: resultStatus(result),
+		DryRun: result.Payment != nil && result.Payment.DryRun,
+		Request: resultRequest{
+			Amount:         result.Request.Amount,
+			Currency:       result.Request.Currency,
+			Provider:       result.Request.Provider,
+			IdempotencyKey: result.Request.IdempotencyKey,
+			ReferenceID:    result.Request.ReferenceID,
+		},
+		Payment: result.Payment,
+		Error:   result.Error,
+	}
+}
+
+// WriteResults serializes payment results to w in the given format ("text", "json"
+// or "csv"). Requests with a zero amount are reported with status INVALID.
+func WriteResults(w io.Writer, results []repository.PaymentResult, format string) error {
+	switch format {
+	case FormatText:
+		return writeResultsText(w, results)
+	case FormatJSON:
+		return writeResultsJSON(w, results)
+	case FormatCSV:
+		return writeResultsCSV(w, results)
+	default:
+		return fmt.Errorf("unsupported result format %q", format)
+	}
+}
+
+// WriteResultsFile writes payment results to the file at path in the given format,
+// creating its parent directories as needed. An existing file is overwritten.
+func WriteResultsFile(path string, results []repository.PaymentResult, format string) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return WriteResults(file, results, format)
+}
+
+// resultStatus summarizes a result as INVALID, FAILED or the payment status
+func resultStatus(result repository.PaymentResult) string {
+	switch {
+	case result.Request.Amount == 0:
+		return resultStatusInvalid
+	case result.Error != nil || result.Payment == nil:
+		return resultStatusFailed
+	default:
+		return string(result.Payment.Status)
+	}
+}
+
+// writeResultsText writes results in a human-readable report layout
+func writeResultsText(w io.Writer, results []repository.PaymentResult) error {
+	var buf bytes.Buffer
+	writeResultTextHeader(&buf)
+
+	for i, result := range results {
+		writeResultText(&buf, i+1, result)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeResultTextHeader writes the title of the text report
+func writeResultTextHeader(buf *bytes.Buffer) {
+	fmt.Fprintln(buf, "Payment Processing Results")
+	fmt.Fprintln(buf, "------------------------")
+	fmt.Fprintln(buf)
+}
+
+// writeResultText writes the text report entry of the n-th result
+func writeResultText(buf *bytes.Buffer, n int, result repository.PaymentResult) {
+	fmt.Fprintf(buf, "Payment Request #%d:\n", n)
+
+	if result.Request.Amount != 0 {
+		fmt.Fprintf(buf, "  Amount: %.2f %s\n", result.Request.Amount, result.Request.Currency)
+		fmt.Fprintf(buf, "  Provider: %s\n", result.Request.Provider)
+		if result.Request.ReferenceID != "" {
+			fmt.Fprintf(buf, "  Reference ID: %s\n", result.Request.ReferenceID)
+		}
+
+		if result.Error != nil {
+			fmt.Fprintf(buf, "  Status: Failed\n")
+			fmt.Fprintf(buf, "  Error: %s (%s)\n", result.Error.Message, result.Error.Code)
+		} else if result.Payment != nil {
+			fmt.Fprintf(buf, "  Status: Success\n")
+			fmt.Fprintf(buf, "  Payment ID: %s\n", result.Payment.ID)
+			fmt.Fprintf(buf, "  Payment Status: %s\n", result.Payment.Status)
+		} else {
+			fmt.Fprintf(buf, "  Status: Unknown\n")
+		}
+	} else {
+		fmt.Fprintf(buf, "  Status: Invalid Request\n")
+	}
+	fmt.Fprintln(buf)
+}
+
+func writeResultsJSON(w io.Writer, results []repository.PaymentResult) error {
+	entries := make([]resultEntry, 0, len(results))
+	for _, result := range results {
+		entries = append(entries, newResultEntry(result))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+func writeResultsCSV(w io.Writer, results []repository.PaymentResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultCSVHeader); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		var paymentID, errorCode string
+		if result.Payment != nil {
+			paymentID = result.Payment.ID
+		}
+		if result.Error != nil {
+			errorCode = result.Error.Code
+		}
+		record := []string{
+			strconv.FormatFloat(result.Request.Amount, 'f', 2, 64),
+			result.Request.Currency,
+			result.Request.Provider,
+			resultStatus(result),
+			paymentID,
+			errorCode,
+			result.Request.ReferenceID,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
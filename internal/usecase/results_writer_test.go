@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestWriteResults(t *testing.T) {
+	results := []repository.PaymentResult{
+		{
+			Request: repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA", ReferenceID: "ORD-1"},
+			Payment: &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved, ProviderRawData: map[string]interface{}{"status": "APPROVED"}},
+		},
+		{
+			Request: repository.PaymentRequest{Amount: 999, Currency: "USD", Provider: "ProviderB"},
+			Error:   &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"},
+		},
+		{
+			Request: repository.PaymentRequest{Provider: "ProviderA"},
+			Error:   &domain.PaymentError{Code: domain.ErrInvalidRequest, Message: "row 4: invalid amount"},
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, results, FormatCSV); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV output: %v", err)
+		}
+		expected := [][]string{
+			{"amount", "currency", "provider", "status", "payment_id", "error_code", "reference_id"},
+			{"100.00", "USD", "ProviderA", "APPROVED", "TXN-1", "", "ORD-1"},
+			{"999.00", "USD", "ProviderB", "FAILED", "", domain.ErrCardDeclined, ""},
+			{"0.00", "", "ProviderA", "INVALID", "", domain.ErrInvalidRequest, ""},
+		}
+		if len(records) != len(expected) {
+			t.Fatalf("expected %d records, got %d: %v", len(expected), len(records), records)
+		}
+		for i := range expected {
+			for j := range expected[i] {
+				if records[i][j] != expected[i][j] {
+					t.Errorf("record %d column %d: expected %q, got %q", i, j, expected[i][j], records[i][j])
+				}
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, results, FormatJSON); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var entries []map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		if len(entries) != len(results) {
+			t.Fatalf("expected %d entries, got %d", len(results), len(entries))
+		}
+		if _, ok := entries[0]["payment"]; !ok {
+			t.Errorf("expected payment field in successful entry, got %v", entries[0])
+		}
+		if payment, ok := entries[0]["payment"].(map[string]interface{}); !ok || payment["provider_raw_data"] == nil {
+			t.Errorf("expected provider raw data in payment, got %v", entries[0]["payment"])
+		}
+		if _, ok := entries[1]["error"]; !ok {
+			t.Errorf("expected error field in failed entry, got %v", entries[1])
+		}
+		if request, ok := entries[0]["request"].(map[string]interface{}); !ok || request["provider"] != "ProviderA" || request["reference_id"] != "ORD-1" {
+			t.Errorf("expected request field with provider and reference, got %v", entries[0]["request"])
+		}
+		if request, ok := entries[1]["request"].(map[string]interface{}); ok {
+			if _, hasReference := request["reference_id"]; hasReference {
+				t.Errorf("expected no reference field without a reference, got %v", request)
+			}
+		}
+		if entries[2]["status"] != "INVALID" {
+			t.Errorf("expected invalid entry, got %v", entries[2])
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		if err := WriteResults(&bytes.Buffer{}, results, "xml"); err == nil {
+			t.Error("expected error for unsupported format")
+		}
+	})
+}
+
+func TestWriteResultsFile(t *testing.T) {
+	results := []repository.PaymentResult{
+		{
+			Request: repository.PaymentRequest{Amount: 100.00, Currency: "USD", Provider: "ProviderA", ReferenceID: "ORD-1"},
+			Payment: &domain.Payment{ID: "PAY-001", Status: domain.StatusApproved},
+		},
+		{
+			Request: repository.PaymentRequest{Amount: 999.00, Currency: "USD", Provider: "ProviderB"},
+			Error:   &domain.PaymentError{Code: "DECLINED", Message: "Payment declined"},
+		},
+		{
+			Request: repository.PaymentRequest{Provider: "ProviderA"},
+		},
+	}
+
+	// The parent directories do not exist yet
+	path := filepath.Join(t.TempDir(), "nested", "out", "payment_results.txt")
+	if err := WriteResultsFile(path, results, FormatText); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected results file to be created: %v", err)
+	}
+	for _, expected := range []string{
+		"Payment Request #1:\n  Amount: 100.00 USD\n  Provider: ProviderA\n  Reference ID: ORD-1\n  Status: Success\n  Payment ID: PAY-001\n",
+		"  Status: Failed\n  Error: Payment declined (DECLINED)\n",
+		"Payment Request #3:\n  Status: Invalid Request\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected results to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		if err := WriteResultsFile(filepath.Join(t.TempDir(), "results.xml"), results, "xml"); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+	})
+}
+
+func TestPaymentUseCase_ReferenceInResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "reference column", input: "amount,currency,provider,reference\n10.00,USD,ProviderA,ORD-1\n20.00,USD,ProviderA,\n"},
+		{name: "reference_id column", input: "reference_id,provider,currency,amount\nORD-1,ProviderA,USD,10.00\n,ProviderA,USD,20.00\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "payments.csv")
+			if err := os.WriteFile(path, []byte(tt.input), 0644); err != nil {
+				t.Fatalf("failed to write CSV: %v", err)
+			}
+
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-A", Status: domain.StatusApproved, Provider: "ProviderA"}
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+			results, _, err := useCase.ProcessPaymentRequestsFromCSV(context.Background(), path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("expected 2 results, got %d", len(results))
+			}
+
+			var jsonOut bytes.Buffer
+			if err := WriteResults(&jsonOut, results, FormatJSON); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var entries []resultEntry
+			if err := json.Unmarshal(jsonOut.Bytes(), &entries); err != nil {
+				t.Fatalf("invalid JSON output: %v", err)
+			}
+			if entries[0].Request.ReferenceID != "ORD-1" || entries[1].Request.ReferenceID != "" {
+				t.Errorf("expected references [ORD-1, \"\"], got [%q, %q]", entries[0].Request.ReferenceID, entries[1].Request.ReferenceID)
+			}
+
+			var csvOut bytes.Buffer
+			if err := WriteResults(&csvOut, results, FormatCSV); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			records, err := csv.NewReader(&csvOut).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV output: %v", err)
+			}
+			last := len(resultCSVHeader) - 1
+			if records[1][last] != "ORD-1" || records[2][last] != "" {
+				t.Errorf("expected reference column [ORD-1, \"\"], got [%q, %q]", records[1][last], records[2][last])
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"yuno_assesment/internal/domain/repository"
+)
+
+// ResultSink receives payment results one at a time as a batch is processed, e.g. to
+// write them to a file or push them to a database or message queue. Write is never
+// called concurrently, and Close is called once after the last result.
+type ResultSink interface {
+	Write(ctx context.Context, result repository.PaymentResult) error
+	Close() error
+}
+
+// FileSink writes results to a file in the text report format of WriteResults
+type FileSink struct {
+	file  *os.File
+	count int
+}
+
+// NewFileSink creates the file at path, and its parent directories as needed, and
+// writes the report header. An existing file is overwritten.
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	writeResultTextHeader(&buf)
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends the report entry of a result to the file
+func (s *FileSink) Write(ctx context.Context, result repository.PaymentResult) error {
+	s.count++
+	var buf bytes.Buffer
+	writeResultText(&buf, s.count, result)
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// JSONLinesSink writes each result as one JSON object per line, in the same form as
+// the entries of the JSON results format
+type JSONLinesSink struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLinesSink creates a sink writing to w, such as os.Stdout. The caller owns w;
+// Close does not close it.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{encoder: json.NewEncoder(w)}
+}
+
+// Write writes a result as a single line of JSON
+func (s *JSONLinesSink) Write(ctx context.Context, result repository.PaymentResult) error {
+	return s.encoder.Encode(newResultEntry(result))
+}
+
+// Close is a no-op; the underlying writer is left open
+func (s *JSONLinesSink) Close() error {
+	return nil
+}
+
+// ProcessCSVToSink processes payment requests read from CSV like ProcessCSVStreaming and
+// writes each result to sink as soon as it completes. The sink is closed once every
+// result has been written. After a write fails the remaining results are still
+// processed but not written, and the first write error is returned.
+func (uc *PaymentUseCase) ProcessCSVToSink(ctx context.Context, r io.Reader, sink ResultSink) (err error) {
+	defer func() {
+		if closeErr := sink.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close result sink: %w", closeErr)
+		}
+	}()
+
+	var writeErr error
+	processErr := uc.ProcessCSVStreaming(ctx, r, func(result repository.PaymentResult) {
+		if writeErr != nil {
+			return
+		}
+		if err := sink.Write(ctx, result); err != nil {
+			writeErr = fmt.Errorf("failed to write result: %w", err)
+		}
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return processErr
+}
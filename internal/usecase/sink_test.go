@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// recordingSink is a ResultSink that keeps the results written to it
+type recordingSink struct {
+	results  []repository.PaymentResult
+	closed   int
+	writeErr error
+}
+
+func (s *recordingSink) Write(ctx context.Context, result repository.PaymentResult) error {
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	s.results = append(s.results, result)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestPaymentUseCase_ProcessCSVToSink(t *testing.T) {
+	input := "amount,currency,provider\n" +
+		"10.00,USD,ProviderA\n" +
+		"abc,USD,ProviderA\n" +
+		"30.00,USD,ProviderA\n"
+
+	tests := []struct {
+		name            string
+		writeErr        error
+		expectedResults int
+		expectError     bool
+	}{
+		{name: "every result is written", expectedResults: 3},
+		{name: "write failure is reported", writeErr: errors.New("disk full"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-A", Status: domain.StatusApproved, Provider: "ProviderA"}
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+			sink := &recordingSink{writeErr: tt.writeErr}
+
+			err := useCase.ProcessCSVToSink(context.Background(), strings.NewReader(input), sink)
+			if tt.expectError {
+				if err == nil || !errors.Is(err, tt.writeErr) {
+					t.Errorf("expected write error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(sink.results) != tt.expectedResults {
+				t.Errorf("expected %d written results, got %d", tt.expectedResults, len(sink.results))
+			}
+			if sink.closed != 1 {
+				t.Errorf("expected the sink to be closed once, got %d", sink.closed)
+			}
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	results := []repository.PaymentResult{
+		{
+			Request: repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"},
+			Payment: &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved},
+		},
+		{
+			Request: repository.PaymentRequest{Amount: 999, Currency: "USD", Provider: "ProviderB"},
+			Error:   &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "results.txt")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, result := range results {
+		if err := sink.Write(context.Background(), result); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	var expected bytes.Buffer
+	if err := WriteResults(&expected, results, FormatText); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read sink output: %v", err)
+	}
+	if string(written) != expected.String() {
+		t.Errorf("expected the text report\n%s\ngot\n%s", expected.String(), written)
+	}
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesSink(&buf)
+
+	results := []repository.PaymentResult{
+		{
+			Request: repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"},
+			Payment: &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved},
+		},
+		{
+			Request: repository.PaymentRequest{Amount: 999, Currency: "USD", Provider: "ProviderB"},
+			Error:   &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"},
+		},
+	}
+	for _, result := range results {
+		if err := sink.Write(context.Background(), result); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(results) {
+		t.Fatalf("expected %d lines, got %d: %q", len(results), len(lines), buf.String())
+	}
+	expectedStatuses := []string{string(domain.StatusApproved), resultStatusFailed}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", i+1, err)
+		}
+		if entry["status"] != expectedStatuses[i] {
+			t.Errorf("line %d: expected status %s, got %v", i+1, expectedStatuses[i], entry["status"])
+		}
+	}
+}
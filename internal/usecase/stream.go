@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// streamWorkers is the number of payments processed concurrently by ProcessCSVStreaming
+const streamWorkers = 5
+
+// ProcessCSVStreaming reads payment requests from CSV and calls emit with each result as
+// soon as it completes, e.g. to push live updates over a websocket. Results arrive in
+// completion order, not file order; rows rejected before dispatch are emitted first.
+// emit is always called from the calling goroutine, never concurrently.
+func (uc *PaymentUseCase) ProcessCSVStreaming(ctx context.Context, r io.Reader, emit func(repository.PaymentResult)) error {
+	rows, err := readPaymentCSV(r, uc.config.Global.CSV, uc.config.Global.DefaultProvider)
+	if err != nil {
+		return err
+	}
+
+	prechecked := uc.precheckRows(rows)
+
+	var requests []repository.PaymentRequest
+	for i, row := range rows {
+		if prechecked[i] == nil {
+			requests = append(requests, row.request)
+		}
+	}
+	requests = withRequestIDs(requests)
+	if err := uc.checkBatchCurrencies(requests); err != nil {
+		return err
+	}
+
+	release, busyErr := uc.acquireBatchSlot(len(rows))
+	if busyErr != nil {
+		return busyErr
+	}
+	defer release()
+
+	logger.Info("Starting streaming processing of %d payment requests", len(rows))
+
+	requestCh := make(chan repository.PaymentRequest)
+	resultCh := make(chan repository.PaymentResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < streamWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range requestCh {
+				payment, err := uc.dispatch(ctx, req)
+				result := repository.PaymentResult{Request: req, Payment: payment, Error: err}
+				uc.completed(result)
+				resultCh <- result
+			}
+		}()
+	}
+
+	go func() {
+		for _, req := range requests {
+			requestCh <- req
+		}
+		close(requestCh)
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	for i, row := range rows {
+		if prechecked[i] != nil {
+			emit(repository.PaymentResult{Request: row.request, Error: prechecked[i]})
+		}
+	}
+
+	var processed []repository.PaymentResult
+	for result := range resultCh {
+		processed = append(processed, result)
+		emit(result)
+	}
+
+	if err := uc.checkFailureThreshold(processed); err != nil {
+		return err
+	}
+	return nil
+}
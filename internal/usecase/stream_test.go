@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestPaymentUseCase_ProcessCSVStreaming(t *testing.T) {
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-A", Status: domain.StatusApproved, Provider: "ProviderA"}
+	mockRepo.errors["ProviderB"] = &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Card declined"}
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig())
+
+	input := "amount,currency,provider\n" +
+		"10.00,USD,ProviderA\n" +
+		"20.00,USD,ProviderB\n" +
+		"abc,USD,ProviderA\n" +
+		"30.00,USD,ProviderA\n" +
+		"40.00,EUR,ProviderA\n" +
+		"50.00,USD,ProviderB\n"
+
+	// emit must never be called concurrently; appending without a lock lets -race catch it
+	var emitted []repository.PaymentResult
+	err := useCase.ProcessCSVStreaming(context.Background(), strings.NewReader(input), func(result repository.PaymentResult) {
+		emitted = append(emitted, result)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(emitted) != 6 {
+		t.Fatalf("expected 6 emitted results, got %d", len(emitted))
+	}
+
+	// Completion order is not deterministic, so compare the results sorted by line
+	sort.Slice(emitted, func(i, j int) bool {
+		return emitted[i].Request.Line < emitted[j].Request.Line
+	})
+	expected := []string{"", domain.ErrCardDeclined, domain.ErrInvalidRequest, "", "", domain.ErrCardDeclined}
+	for i, result := range emitted {
+		code := ""
+		if result.Error != nil {
+			code = result.Error.Code
+		}
+		if code != expected[i] {
+			t.Errorf("line %d: expected error code %q, got %q", result.Request.Line, expected[i], code)
+		}
+		if code == "" && result.Payment == nil {
+			t.Errorf("line %d: expected a payment", result.Request.Line)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// BatchSummary aggregates the outcome of a processed batch. ByErrorCode counts the
+// failed payments per error code; TotalAmountApproved sums the approved payments.
+type BatchSummary struct {
+	Total               int            `json:"total"`
+	Succeeded           int            `json:"succeeded"`
+	Failed              int            `json:"failed"`
+	ByErrorCode         map[string]int `json:"by_error_code"`
+	TotalAmountApproved float64        `json:"total_amount_approved"`
+}
+
+// SummarizeResults computes the aggregate statistics of a batch
+func SummarizeResults(results []repository.PaymentResult) BatchSummary {
+	summary := BatchSummary{
+		Total:       len(results),
+		ByErrorCode: make(map[string]int),
+	}
+
+	// Sum in cents so that many small amounts do not accumulate float error
+	var approvedCents int64
+	for _, result := range results {
+		if result.Error != nil {
+			summary.Failed++
+			summary.ByErrorCode[result.Error.Code]++
+			continue
+		}
+		summary.Succeeded++
+		if result.Payment != nil && result.Payment.Status == domain.StatusApproved {
+			approvedCents += domain.ToMinorUnits(result.Payment.Amount)
+		}
+	}
+	summary.TotalAmountApproved = domain.FromMinorUnits(approvedCents)
+	return summary
+}
@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"testing"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+func TestSummarizeResults(t *testing.T) {
+	results := []repository.PaymentResult{
+		{Payment: &domain.Payment{ID: "TXN-1", Amount: 0.1, Status: domain.StatusApproved}},
+		{Payment: &domain.Payment{ID: "TXN-2", Amount: 0.2, Status: domain.StatusApproved}},
+		{Payment: &domain.Payment{ID: "TXN-3", Amount: 50, Status: domain.StatusPending}},
+		{Error: &domain.PaymentError{Code: domain.ErrCardDeclined}},
+		{Error: &domain.PaymentError{Code: domain.ErrCardDeclined}},
+		{Error: &domain.PaymentError{Code: domain.ErrInvalidRequest}},
+	}
+
+	summary := SummarizeResults(results)
+
+	if summary.Total != 6 || summary.Succeeded != 3 || summary.Failed != 3 {
+		t.Errorf("expected 6 total, 3 succeeded, 3 failed, got %+v", summary)
+	}
+	if summary.ByErrorCode[domain.ErrCardDeclined] != 2 || summary.ByErrorCode[domain.ErrInvalidRequest] != 1 {
+		t.Errorf("unexpected error code counts: %v", summary.ByErrorCode)
+	}
+	if summary.TotalAmountApproved != 0.3 {
+		t.Errorf("expected 0.3 approved, got %v", summary.TotalAmountApproved)
+	}
+
+	empty := SummarizeResults(nil)
+	if empty.Total != 0 || empty.ByErrorCode == nil {
+		t.Errorf("expected an empty summary with a usable map, got %+v", empty)
+	}
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// WithTransactionStore checks payment requests with an idempotency key against store
+// before sending them. A request whose key is already stored returns the stored payment
+// instead of charging again, and successful payments are saved to the store. Requests
+// without an idempotency key are not deduplicated.
+func WithTransactionStore(store repository.TransactionStore) Option {
+	return func(uc *PaymentUseCase) {
+		uc.transactions = store
+	}
+}
+
+// requestIdempotencyKey returns the idempotency key of req, or the one carried by ctx
+func requestIdempotencyKey(ctx context.Context, req repository.PaymentRequest) string {
+	if req.IdempotencyKey != "" {
+		return req.IdempotencyKey
+	}
+	return domain.IdempotencyKeyFromContext(ctx)
+}
+
+// storedPayment returns the payment already made under the idempotency key of req, or
+// nil when there is none. A stored payment for a different amount or currency is
+// reported as ErrDuplicateTransaction. The provider is not compared: a retry may name
+// none and leave it to the default provider or routing, which can pick another one.
+func (uc *PaymentUseCase) storedPayment(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	key := requestIdempotencyKey(ctx, req)
+	if uc.transactions == nil || key == "" {
+		return nil, nil
+	}
+
+	log := logger.WithContext(ctx)
+	exists, payment, err := uc.transactions.Exists(ctx, key)
+	if err != nil {
+		log.Error("Failed to look up idempotency key %s: %v", key, err)
+		return nil, (&domain.PaymentError{
+			Code:      domain.ErrInternalError,
+			Message:   "Failed to look up idempotency key: " + err.Error(),
+			Provider:  req.Provider,
+			Retryable: true,
+		}).WithCause(err)
+	}
+	if !exists || payment == nil {
+		return nil, nil
+	}
+
+	if domain.ToMinorUnits(payment.Amount) != domain.ToMinorUnits(req.Amount) ||
+		!strings.EqualFold(string(payment.Currency), req.Currency) {
+		log.Error("Idempotency key %s reused for a different payment", key)
+		return nil, &domain.PaymentError{
+			Code:     domain.ErrDuplicateTransaction,
+			Message:  fmt.Sprintf("Idempotency key %s was already used for %.2f %s", key, payment.Amount, payment.Currency),
+			Provider: req.Provider,
+		}
+	}
+
+	log.Info("Returning stored payment %s for idempotency key %s", payment.ID, key)
+	return payment, nil
+}
+
+// savePayment records a successful payment under the idempotency key of req. The
+// payment has already been made, so a failure to save it is logged rather than returned.
+func (uc *PaymentUseCase) savePayment(ctx context.Context, req repository.PaymentRequest, payment *domain.Payment) {
+	key := requestIdempotencyKey(ctx, req)
+	if uc.transactions == nil || key == "" || payment == nil {
+		return
+	}
+
+	payment.IdempotencyKey = key
+	if err := uc.transactions.Save(ctx, payment); err != nil {
+		logger.WithContext(ctx).Error("Failed to save payment %s under idempotency key %s: %v", payment.ID, key, err)
+	}
+}
+
+// processStored processes req unless a payment was already stored under its
+// idempotency key, and saves the payment it makes
+func (uc *PaymentUseCase) processStored(ctx context.Context, req repository.PaymentRequest) (*domain.Payment, *domain.PaymentError) {
+	if payment, err := uc.storedPayment(ctx, req); payment != nil || err != nil {
+		return payment, err
+	}
+
+	payment, err := uc.paymentRepo.ProcessPaymentRequest(ctx, req)
+	if err == nil {
+		uc.savePayment(ctx, req, payment)
+	}
+	return payment, err
+}
+
+// batchProcessStored processes a batch through the repository, answering requests whose
+// idempotency key is already stored from the store and saving the payments made. A key
+// repeated within the batch is sent once; the later requests get the first one's result.
+func (uc *PaymentUseCase) batchProcessStored(ctx context.Context, requests []repository.PaymentRequest) []repository.PaymentResult {
+	if uc.transactions == nil {
+		return uc.paymentRepo.BatchProcessPayments(ctx, requests)
+	}
+
+	results := make([]repository.PaymentResult, len(requests))
+	var pending []repository.PaymentRequest
+	var positions []int
+	// first maps an idempotency key to the position of its first request, and repeats
+	// lists the positions of the later requests sharing it
+	first := make(map[string]int)
+	repeats := make(map[int][]int)
+	for i, req := range requests {
+		payment, err := uc.storedPayment(ctx, req)
+		if payment != nil || err != nil {
+			results[i] = repository.PaymentResult{Request: req, Payment: payment, Error: err}
+			continue
+		}
+		if key := requestIdempotencyKey(ctx, req); key != "" {
+			if leader, seen := first[key]; seen {
+				repeats[leader] = append(repeats[leader], i)
+				continue
+			}
+			first[key] = i
+		}
+		pending = append(pending, req)
+		positions = append(positions, i)
+	}
+
+	if len(pending) > 0 {
+		for i, result := range uc.paymentRepo.BatchProcessPayments(ctx, pending) {
+			if result.Error == nil {
+				uc.savePayment(ctx, result.Request, result.Payment)
+			}
+			results[positions[i]] = result
+		}
+	}
+
+	for leader, positions := range repeats {
+		for _, i := range positions {
+			results[i] = repeatedResult(requestIdempotencyKey(ctx, requests[i]), requests[i], results[leader])
+		}
+	}
+	return results
+}
+
+// repeatedResult is the result of req, which repeats the idempotency key of the request
+// that produced first in the same batch
+func repeatedResult(key string, req repository.PaymentRequest, first repository.PaymentResult) repository.PaymentResult {
+	if domain.ToMinorUnits(first.Request.Amount) != domain.ToMinorUnits(req.Amount) ||
+		!strings.EqualFold(first.Request.Currency, req.Currency) {
+		return repository.PaymentResult{Request: req, Error: &domain.PaymentError{
+			Code:     domain.ErrDuplicateTransaction,
+			Message:  fmt.Sprintf("Idempotency key %s was already used for %.2f %s", key, first.Request.Amount, first.Request.Currency),
+			Provider: req.Provider,
+		}}
+	}
+	return repository.PaymentResult{Request: req, Payment: first.Payment, Error: first.Error}
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/internal/infrastructure/store"
+)
+
+func TestPaymentUseCase_TransactionStore(t *testing.T) {
+	stored := &domain.Payment{
+		ID:             "TXN-STORED",
+		Amount:         100,
+		Currency:       "USD",
+		Status:         domain.StatusApproved,
+		Provider:       "ProviderA",
+		IdempotencyKey: "key-1",
+	}
+
+	tests := []struct {
+		name           string
+		request        repository.PaymentRequest
+		expectedID     string
+		expectedError  string
+		expectDispatch bool
+	}{
+		{
+			name:           "miss is processed and saved",
+			request:        repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-2"},
+			expectedID:     "TXN-NEW",
+			expectDispatch: true,
+		},
+		{
+			name:       "hit returns the stored payment",
+			request:    repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-1"},
+			expectedID: "TXN-STORED",
+		},
+		{
+			name:          "hit for a different payment is a duplicate",
+			request:       repository.PaymentRequest{Amount: 250, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-1"},
+			expectedError: domain.ErrDuplicateTransaction,
+		},
+		{
+			name:       "hit routed to another provider returns the stored payment",
+			request:    repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderB", IdempotencyKey: "key-1"},
+			expectedID: "TXN-STORED",
+		},
+		{
+			name:           "request without a key is not deduplicated",
+			request:        repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA"},
+			expectedID:     "TXN-NEW",
+			expectDispatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions := store.NewMemoryTransactionStore()
+			if err := transactions.Save(context.Background(), stored); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-NEW", Amount: 100, Currency: "USD", Status: domain.StatusApproved, Provider: "ProviderA"}
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithTransactionStore(transactions))
+
+			payment, err := useCase.ProcessPaymentRequest(context.Background(), tt.request)
+			if tt.expectedError != "" {
+				if err == nil || err.Code != tt.expectedError {
+					t.Fatalf("expected error %s, got %v", tt.expectedError, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if payment.ID != tt.expectedID {
+					t.Errorf("expected payment %s, got %s", tt.expectedID, payment.ID)
+				}
+			}
+
+			dispatched := mockRepo.lastRequest.Provider != ""
+			if dispatched != tt.expectDispatch {
+				t.Errorf("expected dispatch=%v, got %v", tt.expectDispatch, dispatched)
+			}
+
+			if tt.request.IdempotencyKey != "" && tt.expectDispatch {
+				exists, saved, err := transactions.Exists(context.Background(), tt.request.IdempotencyKey)
+				if err != nil || !exists || saved.ID != tt.expectedID {
+					t.Errorf("expected payment %s to be saved, got exists=%v payment=%v err=%v", tt.expectedID, exists, saved, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentUseCase_BatchProcessPayments_TransactionStore(t *testing.T) {
+	transactions := store.NewMemoryTransactionStore()
+	stored := &domain.Payment{ID: "TXN-STORED", Amount: 100, Currency: "USD", Status: domain.StatusApproved, Provider: "ProviderA", IdempotencyKey: "key-1"}
+	if err := transactions.Save(context.Background(), stored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mockRepo := newMockPaymentRepository()
+	mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-NEW", Amount: 100, Currency: "USD", Status: domain.StatusApproved, Provider: "ProviderA"}
+	useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithTransactionStore(transactions))
+
+	// key-2 is repeated within the batch, once for a different amount
+	requests := []repository.PaymentRequest{
+		{Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-1"},
+		{Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-2"},
+		{Amount: 100, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-2"},
+		{Amount: 250, Currency: "USD", Provider: "ProviderA", IdempotencyKey: "key-2"},
+	}
+	results, _ := useCase.BatchProcessPayments(context.Background(), requests)
+
+	if got := atomic.LoadInt32(&mockRepo.dispatched); got != 1 {
+		t.Errorf("expected 1 request sent to the repository, got %d", got)
+	}
+	if err := results[3].Error; err == nil || err.Code != domain.ErrDuplicateTransaction {
+		t.Errorf("expected %s for a repeated key with another amount, got %v", domain.ErrDuplicateTransaction, err)
+	}
+	expectedIDs := []string{"TXN-STORED", "TXN-NEW", "TXN-NEW"}
+	for i, result := range results[:3] {
+		if result.Error != nil {
+			t.Fatalf("result %d: unexpected error: %v", i, result.Error)
+		}
+		if result.Payment.ID != expectedIDs[i] {
+			t.Errorf("result %d: expected payment %s, got %s", i, expectedIDs[i], result.Payment.ID)
+		}
+	}
+	if exists, _, _ := transactions.Exists(context.Background(), "key-2"); !exists {
+		t.Error("expected the new payment to be saved under key-2")
+	}
+}
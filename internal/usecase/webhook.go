@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain/repository"
+	"yuno_assesment/pkg/logger"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the body, as "sha256=<hex>",
+// when a webhook secret is configured
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+const (
+	// defaultWebhookTimeout bounds each delivery attempt unless configured otherwise
+	defaultWebhookTimeout = 5 * time.Second
+	// defaultWebhookRetryDelay is the pause between delivery attempts
+	defaultWebhookRetryDelay = 500 * time.Millisecond
+	// webhookWorkers is the number of deliveries sent concurrently
+	webhookWorkers = 10
+)
+
+// WebhookNotifier posts payment results to a webhook URL in the background. Each
+// result is sent as a JSON object in the same form as the entries of the JSON
+// results format. Deliveries that still fail after the configured retries are logged
+// and dropped.
+type WebhookNotifier struct {
+	config     config.WebhookConfig
+	client     *http.Client
+	retryDelay time.Duration
+	slots      chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewWebhookNotifier creates a notifier posting to cfg.URL with client, or with a
+// default client when client is nil
+func NewWebhookNotifier(cfg config.WebhookConfig, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	return &WebhookNotifier{
+		config:     cfg,
+		client:     client,
+		retryDelay: defaultWebhookRetryDelay,
+		slots:      make(chan struct{}, webhookWorkers),
+	}
+}
+
+// WithWebhookNotifier posts the result of every payment sent to a provider to the
+// notifier's webhook as soon as it completes. Dry-run results are not posted.
+func WithWebhookNotifier(notifier *WebhookNotifier) Option {
+	return func(uc *PaymentUseCase) {
+		uc.webhook = notifier
+	}
+}
+
+// notify hands result to the webhook notifier, if one is configured
+func (uc *PaymentUseCase) notify(result repository.PaymentResult) {
+	if uc.webhook == nil || uc.dryRun {
+		return
+	}
+	uc.webhook.Notify(result)
+}
+
+// Notify posts result to the webhook without waiting for the delivery
+func (n *WebhookNotifier) Notify(result repository.PaymentResult) {
+	// Encode now so the delivery does not race with later changes to the result
+	body, err := json.Marshal(newResultEntry(result))
+	if err != nil {
+		logger.Error("Failed to encode webhook payload for request %s: %v", result.Request.RequestID, err)
+		return
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.slots <- struct{}{}
+		defer func() { <-n.slots }()
+
+		if err := n.deliver(body); err != nil {
+			logger.Error("Failed to deliver webhook for request %s: %v", result.Request.RequestID, err)
+		}
+	}()
+}
+
+// Wait blocks until every pending delivery has completed or failed
+func (n *WebhookNotifier) Wait() {
+	n.wg.Wait()
+}
+
+// deliver posts body to the webhook, retrying network errors and 5xx/429 responses
+func (n *WebhookNotifier) deliver(body []byte) error {
+	var err error
+	for attempt := 0; attempt <= n.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(n.retryDelay)
+		}
+		var retryable bool
+		if retryable, err = n.post(body); err == nil || !retryable {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", n.config.MaxRetries+1, err)
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(body []byte) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.config.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhookBody(n.config.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+	return retryable, fmt.Errorf("webhook responded with HTTP %d", resp.StatusCode)
+}
+
+// signWebhookBody returns the hex HMAC-SHA256 of body keyed with secret
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"yuno_assesment/config"
+	"yuno_assesment/internal/domain"
+	"yuno_assesment/internal/domain/repository"
+)
+
+// webhookReceiver is a test webhook endpoint answering with the given statuses in turn
+// and recording the deliveries it accepts
+type webhookReceiver struct {
+	statuses []int
+	calls    int32
+
+	mu         sync.Mutex
+	bodies     [][]byte
+	signatures []string
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := int(atomic.AddInt32(&rcv.calls, 1)) - 1
+	status := http.StatusOK
+	if call < len(rcv.statuses) {
+		status = rcv.statuses[call]
+	}
+	if status == http.StatusOK {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		rcv.bodies = append(rcv.bodies, body)
+		rcv.signatures = append(rcv.signatures, r.Header.Get(WebhookSignatureHeader))
+		rcv.mu.Unlock()
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	result := repository.PaymentResult{
+		Request: repository.PaymentRequest{Amount: 100, Currency: "USD", Provider: "ProviderA", ReferenceID: "ORD-1"},
+		Payment: &domain.Payment{ID: "TXN-1", Status: domain.StatusApproved},
+	}
+
+	tests := []struct {
+		name              string
+		statuses          []int
+		maxRetries        int
+		secret            string
+		expectedCalls     int32
+		expectedDelivered int
+	}{
+		{name: "delivered", expectedCalls: 1, expectedDelivered: 1},
+		{name: "signed", secret: "s3cret", expectedCalls: 1, expectedDelivered: 1},
+		{name: "server error retried", statuses: []int{http.StatusInternalServerError}, maxRetries: 1, expectedCalls: 2, expectedDelivered: 1},
+		{name: "rate limit retried", statuses: []int{http.StatusTooManyRequests}, maxRetries: 2, expectedCalls: 2, expectedDelivered: 1},
+		{name: "client error not retried", statuses: []int{http.StatusBadRequest}, maxRetries: 2, expectedCalls: 1},
+		{name: "retries exhausted", statuses: []int{http.StatusBadGateway, http.StatusBadGateway}, maxRetries: 1, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &webhookReceiver{statuses: tt.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			notifier := NewWebhookNotifier(config.WebhookConfig{URL: server.URL, MaxRetries: tt.maxRetries, Secret: tt.secret}, server.Client())
+			notifier.retryDelay = 0
+			notifier.Notify(result)
+			notifier.Wait()
+
+			if calls := atomic.LoadInt32(&receiver.calls); calls != tt.expectedCalls {
+				t.Errorf("expected %d delivery attempts, got %d", tt.expectedCalls, calls)
+			}
+			if len(receiver.bodies) != tt.expectedDelivered {
+				t.Fatalf("expected %d deliveries, got %d", tt.expectedDelivered, len(receiver.bodies))
+			}
+			if tt.expectedDelivered == 0 {
+				return
+			}
+
+			var entry resultEntry
+			if err := json.Unmarshal(receiver.bodies[0], &entry); err != nil {
+				t.Fatalf("invalid webhook payload: %v", err)
+			}
+			if entry.Status != string(domain.StatusApproved) || entry.Request.ReferenceID != "ORD-1" || entry.Payment == nil || entry.Payment.ID != "TXN-1" {
+				t.Errorf("unexpected webhook payload: %s", receiver.bodies[0])
+			}
+
+			expectedSignature := ""
+			if tt.secret != "" {
+				expectedSignature = "sha256=" + signWebhookBody(tt.secret, receiver.bodies[0])
+			}
+			if receiver.signatures[0] != expectedSignature {
+				t.Errorf("expected signature %q, got %q", expectedSignature, receiver.signatures[0])
+			}
+		})
+	}
+}
+
+func TestSignWebhookBody(t *testing.T) {
+	// HMAC-SHA256 test vector from RFC 4231, test case 2
+	got := signWebhookBody("Jefe", []byte("what do ya want for nothing?"))
+	expected := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestPaymentUseCase_WebhookNotifications(t *testing.T) {
+	requests := []repository.PaymentRequest{
+		{Amount: 10, Currency: "USD", Provider: "ProviderA"},
+		{Amount: 20, Currency: "USD", Provider: "ProviderB"},
+	}
+
+	tests := []struct {
+		name              string
+		dryRun            bool
+		expectedDelivered int
+	}{
+		{name: "every result is posted", expectedDelivered: len(requests)},
+		{name: "dry run results are not posted", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &webhookReceiver{}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			mockRepo := newMockPaymentRepository()
+			mockRepo.payments["ProviderA"] = &domain.Payment{ID: "TXN-A", Status: domain.StatusApproved, Provider: "ProviderA"}
+			mockRepo.errors["ProviderB"] = &domain.PaymentError{Code: domain.ErrCardDeclined, Message: "Payment was declined"}
+			notifier := NewWebhookNotifier(config.WebhookConfig{URL: server.URL}, server.Client())
+			useCase := NewPaymentUseCase(mockRepo, config.DefaultConfig(), WithWebhookNotifier(notifier), WithDryRun(tt.dryRun))
+
+			if _, err := useCase.BatchProcessPayments(context.Background(), requests); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			notifier.Wait()
+
+			if len(receiver.bodies) != tt.expectedDelivered {
+				t.Fatalf("expected %d deliveries, got %d", tt.expectedDelivered, len(receiver.bodies))
+			}
+			statuses := make(map[string]bool)
+			for _, body := range receiver.bodies {
+				var entry resultEntry
+				if err := json.Unmarshal(body, &entry); err != nil {
+					t.Fatalf("invalid webhook payload: %v", err)
+				}
+				statuses[entry.Status] = true
+			}
+			if tt.expectedDelivered > 0 && (!statuses[string(domain.StatusApproved)] || !statuses[resultStatusFailed]) {
+				t.Errorf("expected approved and failed results to be posted, got %v", statuses)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces the value of sensitive headers and body fields
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are never written to a HAR file
+var sensitiveHeaders = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"x-api-key":     true,
+}
+
+// sensitiveFields are JSON body fields whose values are never written to a HAR file or logged
+var sensitiveFields = map[string]bool{
+	"card_number": true,
+	"cardnumber":  true,
+	"pan":         true,
+	"cvv":         true,
+	"cvc":         true,
+	"password":    true,
+	"secret":      true,
+	"token":       true,
+	"api_key":     true,
+	"apikey":      true,
+}
+
+// HAREntry is a recorded request/response pair
+type HAREntry struct {
+	StartedDateTime time.Time    `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         HARRequest   `json:"request"`
+	Response        *HARResponse `json:"response,omitempty"`
+	Error           string       `json:"error,omitempty"`
+}
+
+// HARRequest is the recorded request of a HAREntry
+type HARRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// HARResponse is the recorded response of a HAREntry
+type HARResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// HARRecorder is an http.RoundTripper that records every request/response pair passing
+// through it, with sensitive headers and body fields redacted, so a run can be attached
+// to a provider support ticket
+type HARRecorder struct {
+	transport http.RoundTripper
+	mutex     sync.Mutex
+	entries   []HAREntry
+}
+
+// NewHARRecorder wraps transport, falling back to http.DefaultTransport when nil
+func NewHARRecorder(transport http.RoundTripper) *HARRecorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &HARRecorder{transport: transport}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := HAREntry{
+		StartedDateTime: time.Now(),
+		Request: HARRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: redactHeaders(req.Header),
+		},
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		entry.Request.Body = redactBody(body, sensitiveFields)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	entry.Time = float64(time.Since(entry.StartedDateTime).Microseconds()) / 1000
+	if err != nil {
+		entry.Error = err.Error()
+		r.record(entry)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		entry.Error = err.Error()
+		r.record(entry)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Response = &HARResponse{
+		Status:  resp.StatusCode,
+		Headers: redactHeaders(resp.Header),
+		Body:    redactBody(body, sensitiveFields),
+	}
+	r.record(entry)
+	return resp, nil
+}
+
+// Entries returns the entries recorded so far
+func (r *HARRecorder) Entries() []HAREntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]HAREntry(nil), r.entries...)
+}
+
+// WriteHAR writes the recorded entries as a HAR-like JSON document
+func (r *HARRecorder) WriteHAR(w io.Writer) error {
+	doc := map[string]interface{}{
+		"log": map[string]interface{}{
+			"version": "1.2",
+			"creator": map[string]string{"name": "yuno_assesment"},
+			"entries": r.Entries(),
+		},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteFile writes the recorded entries to a HAR-like file at path
+func (r *HARRecorder) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.WriteHAR(file)
+}
+
+func (r *HARRecorder) record(entry HAREntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// redactHeaders flattens headers, replacing the values of sensitive ones
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			headers[name] = redacted
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// redactBody replaces the given fields, matched case-insensitively by lower-case name,
+// in JSON bodies. Other bodies are kept as is.
+func redactBody(body []byte, fields map[string]bool) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(redactValue(value, fields))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if fields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(field, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, fields)
+		}
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHARRecorder(t *testing.T) {
+	transport := &MockTransport{RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+		resp := NewMockResponse(http.StatusOK, []byte(`{"status":"APPROVED","token":"tok_live_123"}`))
+		resp.Header.Set("Set-Cookie", "session=abc")
+		return resp, nil
+	}}
+	recorder := NewHARRecorder(transport)
+	client := &http.Client{Transport: recorder}
+
+	bodies := []string{
+		`{"amount":100,"currency":"USD","card_number":"4111111111111111","billing":{"cvv":"123"}}`,
+		`{"amount":50,"currency":"EUR"}`,
+	}
+	for _, body := range bodies {
+		req, _ := http.NewRequest(http.MethodPost, "http://provider.test/process", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret-key")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The response body is still readable by the caller
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(data), "tok_live_123") {
+			t.Errorf("expected caller to receive the unredacted body, got %s", data)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := recorder.WriteHAR(&buf); err != nil {
+		t.Fatalf("failed to write HAR: %v", err)
+	}
+
+	var doc struct {
+		Log struct {
+			Entries []HAREntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid HAR document: %v", err)
+	}
+	if len(doc.Log.Entries) != len(bodies) {
+		t.Fatalf("expected %d entries, got %d", len(bodies), len(doc.Log.Entries))
+	}
+
+	dump := buf.String()
+	for _, secret := range []string{"4111111111111111", "secret-key", "tok_live_123", "session=abc", `"123"`} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("expected %q to be redacted from the dump", secret)
+		}
+	}
+
+	first := doc.Log.Entries[0]
+	if first.Request.Method != http.MethodPost || first.Response == nil || first.Response.Status != http.StatusOK {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if first.Request.Headers["Authorization"] != redacted || first.Request.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected only sensitive headers to be redacted, got %v", first.Request.Headers)
+	}
+	if !strings.Contains(first.Request.Body, `"amount":100`) {
+		t.Errorf("expected non-sensitive fields to be kept, got %s", first.Request.Body)
+	}
+}
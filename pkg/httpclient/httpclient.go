@@ -1,13 +1,94 @@
 package httpclient
 
 import (
+	"net"
 	"net/http"
 	"time"
 )
 
+// ClientOptions configures the client built by NewWithOptions. Zero fields fall back
+// to the values of DefaultClientOptions.
+type ClientOptions struct {
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+}
+
+// DefaultClientOptions returns the options used by New
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:               60 * time.Second,
+		DialTimeout:           10 * time.Second,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+	}
+}
+
+// withDefaults fills every zero field from DefaultClientOptions
+func (o ClientOptions) withDefaults() ClientOptions {
+	defaults := DefaultClientOptions()
+	if o.Timeout <= 0 {
+		o.Timeout = defaults.Timeout
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = defaults.DialTimeout
+	}
+	if o.KeepAlive <= 0 {
+		o.KeepAlive = defaults.KeepAlive
+	}
+	if o.TLSHandshakeTimeout <= 0 {
+		o.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+	}
+	if o.ResponseHeaderTimeout <= 0 {
+		o.ResponseHeaderTimeout = defaults.ResponseHeaderTimeout
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+	return o
+}
+
 // New returns a configured http.Client with sensible timeouts
 func New() *http.Client {
+	return NewWithOptions(DefaultClientOptions())
+}
+
+// NewWithOptions returns an http.Client whose transport pools connections and bounds
+// each phase of a request according to opts
+func NewWithOptions(opts ClientOptions) *http.Client {
+	opts = opts.withDefaults()
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: opts.KeepAlive,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
 	return &http.Client{
-		Timeout: 60 * time.Second,
+		Timeout:   opts.Timeout,
+		Transport: transport,
 	}
 }
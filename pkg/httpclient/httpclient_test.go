@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	defaults := DefaultClientOptions()
+
+	tests := []struct {
+		name     string
+		opts     ClientOptions
+		expected ClientOptions
+	}{
+		{
+			name:     "zero options use defaults",
+			opts:     ClientOptions{},
+			expected: defaults,
+		},
+		{
+			name: "explicit options are kept",
+			opts: ClientOptions{
+				Timeout:               5 * time.Second,
+				DialTimeout:           time.Second,
+				KeepAlive:             15 * time.Second,
+				TLSHandshakeTimeout:   2 * time.Second,
+				ResponseHeaderTimeout: 3 * time.Second,
+				IdleConnTimeout:       45 * time.Second,
+				MaxIdleConns:          200,
+				MaxIdleConnsPerHost:   50,
+			},
+			expected: ClientOptions{
+				Timeout:               5 * time.Second,
+				DialTimeout:           time.Second,
+				KeepAlive:             15 * time.Second,
+				TLSHandshakeTimeout:   2 * time.Second,
+				ResponseHeaderTimeout: 3 * time.Second,
+				IdleConnTimeout:       45 * time.Second,
+				MaxIdleConns:          200,
+				MaxIdleConnsPerHost:   50,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewWithOptions(tt.opts)
+			if client.Timeout != tt.expected.Timeout {
+				t.Errorf("expected timeout %v, got %v", tt.expected.Timeout, client.Timeout)
+			}
+			transport, ok := client.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("expected *http.Transport, got %T", client.Transport)
+			}
+			if transport.TLSHandshakeTimeout != tt.expected.TLSHandshakeTimeout {
+				t.Errorf("expected TLS handshake timeout %v, got %v", tt.expected.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+			}
+			if transport.ResponseHeaderTimeout != tt.expected.ResponseHeaderTimeout {
+				t.Errorf("expected response header timeout %v, got %v", tt.expected.ResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+			}
+			if transport.IdleConnTimeout != tt.expected.IdleConnTimeout {
+				t.Errorf("expected idle timeout %v, got %v", tt.expected.IdleConnTimeout, transport.IdleConnTimeout)
+			}
+			if transport.MaxIdleConns != tt.expected.MaxIdleConns || transport.MaxIdleConnsPerHost != tt.expected.MaxIdleConnsPerHost {
+				t.Errorf("expected pool %d/%d, got %d/%d", tt.expected.MaxIdleConns, tt.expected.MaxIdleConnsPerHost,
+					transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	client := New()
+	if client.Timeout != 60*time.Second {
+		t.Errorf("expected 60s timeout, got %v", client.Timeout)
+	}
+	if _, ok := client.Transport.(*http.Transport); !ok {
+		t.Errorf("expected *http.Transport, got %T", client.Transport)
+	}
+}
@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"yuno_assesment/pkg/logger"
+)
+
+// LoggingTransport is an http.RoundTripper that logs every request and response,
+// including their bodies with sensitive fields redacted, at debug level. Nothing is
+// read or logged while debug logging is disabled.
+type LoggingTransport struct {
+	transport http.RoundTripper
+	fields    map[string]bool
+}
+
+// NewLoggingTransport wraps transport, falling back to http.DefaultTransport when nil.
+// redactFields names JSON body fields to redact in addition to the built-in sensitive ones.
+func NewLoggingTransport(transport http.RoundTripper, redactFields ...string) *LoggingTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	fields := make(map[string]bool, len(sensitiveFields)+len(redactFields))
+	for field := range sensitiveFields {
+		fields[field] = true
+	}
+	for _, field := range redactFields {
+		fields[strings.ToLower(field)] = true
+	}
+	return &LoggingTransport{transport: transport, fields: fields}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !logger.DebugEnabled() {
+		return t.transport.RoundTrip(req)
+	}
+
+	var reqBody string
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		reqBody = redactBody(body, t.fields)
+	}
+	logger.Debug("HTTP request: %s %s %s", req.Method, req.URL, reqBody)
+
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		logger.Debug("HTTP request %s %s failed after %v: %v", req.Method, req.URL, time.Since(start), err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		logger.Debug("HTTP response from %s %s could not be read: %v", req.Method, req.URL, err)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	logger.Debug("HTTP response: %d from %s %s in %v %s", resp.StatusCode, req.Method, req.URL, time.Since(start), redactBody(body, t.fields))
+	return resp, nil
+}
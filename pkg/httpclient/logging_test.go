@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"yuno_assesment/pkg/logger"
+)
+
+// captureDebugLog redirects debug output to a buffer at the given level for the test
+func captureDebugLog(t *testing.T, level string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := logger.DebugLogger.Writer()
+	logger.DebugLogger.SetOutput(&buf)
+	logger.SetLevel(level)
+	t.Cleanup(func() {
+		logger.DebugLogger.SetOutput(previous)
+		logger.SetLevel("debug")
+	})
+	return &buf
+}
+
+func TestLoggingTransport(t *testing.T) {
+	transport := &MockTransport{RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if !strings.Contains(string(body), "4111111111111111") {
+			t.Errorf("expected the provider to receive the unredacted body, got %s", body)
+		}
+		return NewMockResponse(http.StatusOK, []byte(`{"status":"APPROVED","token":"tok_live_123","iban":"DE89370400440532013000"}`)), nil
+	}}
+	client := &http.Client{Transport: NewLoggingTransport(transport, "IBAN")}
+
+	t.Run("debug enabled", func(t *testing.T) {
+		output := captureDebugLog(t, "debug")
+
+		body := `{"amount":100,"currency":"USD","card_number":"4111111111111111"}`
+		resp, err := client.Post("http://provider.test/process", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(data), "tok_live_123") {
+			t.Errorf("expected caller to receive the unredacted body, got %s", data)
+		}
+
+		logged := output.String()
+		for _, expected := range []string{"POST http://provider.test/process", `"amount":100`, "HTTP response: 200", `"status":"APPROVED"`} {
+			if !strings.Contains(logged, expected) {
+				t.Errorf("expected log to contain %q, got:\n%s", expected, logged)
+			}
+		}
+		for _, secret := range []string{"4111111111111111", "tok_live_123", "DE89370400440532013000"} {
+			if strings.Contains(logged, secret) {
+				t.Errorf("expected %q to be redacted from the log", secret)
+			}
+		}
+	})
+
+	t.Run("debug disabled", func(t *testing.T) {
+		output := captureDebugLog(t, "info")
+
+		resp, err := client.Post("http://provider.test/process", "application/json", strings.NewReader(`{"card_number":"4111111111111111"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if output.Len() != 0 {
+			t.Errorf("expected nothing to be logged, got %s", output.String())
+		}
+	})
+
+	t.Run("transport error", func(t *testing.T) {
+		output := captureDebugLog(t, "debug")
+		failing := &http.Client{Transport: NewLoggingTransport(&MockTransport{RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}})}
+
+		if _, err := failing.Get("http://provider.test/status"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(output.String(), "connection refused") {
+			t.Errorf("expected the failure to be logged, got %s", output.String())
+		}
+	})
+}
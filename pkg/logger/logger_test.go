@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// captureOutput redirects all loggers to a buffer for the duration of a test
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	infoOut, warnOut, errorOut, debugOut := InfoLogger.Writer(), WarnLogger.Writer(), ErrorLogger.Writer(), DebugLogger.Writer()
+	InfoLogger.SetOutput(&buf)
+	WarnLogger.SetOutput(&buf)
+	ErrorLogger.SetOutput(&buf)
+	DebugLogger.SetOutput(&buf)
+	t.Cleanup(func() {
+		InfoLogger.SetOutput(infoOut)
+		WarnLogger.SetOutput(warnOut)
+		ErrorLogger.SetOutput(errorOut)
+		DebugLogger.SetOutput(debugOut)
+		SetLevel("debug")
+		SetFormat("text")
+	})
+	return &buf
+}
+
+func TestSetLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected []string
+		dropped  []string
+	}{
+		{level: "debug", expected: []string{"debug message", "info message", "warn message", "error message"}},
+		{level: "info", expected: []string{"info message", "warn message", "error message"}, dropped: []string{"debug message"}},
+		{level: "warn", expected: []string{"warn message", "error message"}, dropped: []string{"debug message", "info message"}},
+		{level: "error", expected: []string{"error message"}, dropped: []string{"debug message", "info message", "warn message"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			buf := captureOutput(t)
+			SetLevel(tt.level)
+
+			Debug("debug message")
+			Info("info message")
+			Warn("warn message")
+			Error("error message")
+
+			out := buf.String()
+			for _, msg := range tt.expected {
+				if !strings.Contains(out, msg) {
+					t.Errorf("expected %q in output, got %q", msg, out)
+				}
+			}
+			for _, msg := range tt.dropped {
+				if strings.Contains(out, msg) {
+					t.Errorf("expected %q to be suppressed, got %q", msg, out)
+				}
+			}
+		})
+	}
+}
+
+func TestSetFormat_JSON(t *testing.T) {
+	buf := captureOutput(t)
+	SetLevel("info")
+	SetFormat("json")
+
+	Info("payment %s processed", "TXN-1")
+
+	var entry map[string]string
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "info" || entry["msg"] != "payment TXN-1 processed" || entry["time"] == "" {
+		t.Errorf("unexpected JSON entry: %+v", entry)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		buf := captureOutput(t)
+
+		ctx := ContextWithRequestID(context.Background(), "a1b2c3d4")
+		WithContext(ctx).Error("payment %s failed", "TXN-1")
+		WithContext(context.Background()).Info("untagged message")
+
+		out := buf.String()
+		if !strings.Contains(out, "[req=a1b2c3d4] payment TXN-1 failed") {
+			t.Errorf("expected the request ID prefix, got %q", out)
+		}
+		if !strings.Contains(out, "logger_test.go") {
+			t.Errorf("expected the caller's file to be reported, got %q", out)
+		}
+		if strings.Contains(out, "[req=] ") {
+			t.Errorf("expected no prefix without a request ID, got %q", out)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := captureOutput(t)
+		SetFormat("json")
+
+		ctx := ContextWithRequestID(context.Background(), "a1b2c3d4")
+		WithContext(ctx).Info("payment processed")
+
+		var entry map[string]string
+		if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+			t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+		}
+		if entry["request_id"] != "a1b2c3d4" || entry["msg"] != "payment processed" {
+			t.Errorf("unexpected JSON entry: %+v", entry)
+		}
+	})
+}
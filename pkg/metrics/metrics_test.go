@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("requests_total", "Requests.", "provider", "code")
+	counter.Inc("ProviderB", "OK")
+	counter.Inc("ProviderA", "OK")
+	counter.Add(2, "ProviderA", "OK")
+
+	gauge := registry.Gauge("budget_remaining", "Budget.", "provider")
+	gauge.Set(5, "ProviderA")
+	gauge.Set(2.5, "ProviderA")
+
+	histogram := registry.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "provider")
+	histogram.Observe(0.05, "ProviderA")
+	histogram.Observe(0.5, "ProviderA")
+	histogram.Observe(3, "ProviderA")
+
+	if got := counter.Value("ProviderA", "OK"); got != 3 {
+		t.Errorf("expected counter value 3, got %v", got)
+	}
+	if got := gauge.Value("ProviderA"); got != 2.5 {
+		t.Errorf("expected gauge value 2.5, got %v", got)
+	}
+	if got := histogram.Count("ProviderA"); got != 3 {
+		t.Errorf("expected 3 observations, got %d", got)
+	}
+
+	var buf bytes.Buffer
+	if err := registry.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE requests_total counter",
+		`requests_total{provider="ProviderA",code="OK"} 3`,
+		`requests_total{provider="ProviderB",code="OK"} 1`,
+		"# TYPE budget_remaining gauge",
+		`budget_remaining{provider="ProviderA"} 2.5`,
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{provider="ProviderA",le="0.1"} 1`,
+		`latency_seconds_bucket{provider="ProviderA",le="1"} 2`,
+		`latency_seconds_bucket{provider="ProviderA",le="+Inf"} 3`,
+		`latency_seconds_sum{provider="ProviderA"} 3.55`,
+		`latency_seconds_count{provider="ProviderA"} 3`,
+	}
+	out := buf.String()
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, out)
+		}
+	}
+
+	// Series are sorted so the output is stable
+	if strings.Index(out, `provider="ProviderA",code`) > strings.Index(out, `provider="ProviderB",code`) {
+		t.Errorf("expected series sorted by label values:\n%s", out)
+	}
+}